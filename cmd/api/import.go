@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"device-api/internal/importer"
+	"device-api/internal/service"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+// runImport implements the import command and returns the exit code: 0 when
+// every row was accepted, 1 when rows were rejected and 2 when the import
+// could not run at all.
+func runImport(svc *service.DeviceService, args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", "", "csv or ndjson (default: from the file extension)")
+	dryRun := flags.Bool("dry-run", false, "check every row without changing anything")
+	report := flags.String("errors", "", "write rejected rows as CSV to this file")
+	actor := flags.String("actor", "import", "who the changes are attributed to")
+	columns := map[string]string{}
+	flags.Func("map", "map a column to a device field, e.g. 'Asset Tag:id' (repeatable)", func(mapping string) error {
+		i := strings.LastIndex(mapping, ":")
+		if i < 0 {
+			return fmt.Errorf("want column:field, got %q", mapping)
+		}
+		columns[mapping[:i]] = mapping[i+1:]
+		return nil
+	})
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: api import [flags] FILE")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = path
+	}
+	parsedFormat, err := importer.ParseFormat(*format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer file.Close()
+
+	// An interrupt stops the import; rows already written stay imported.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	result, err := importer.New(svc.As(*actor)).Import(ctx, file, importer.Options{
+		Format:  parsedFormat,
+		Columns: columns,
+		DryRun:  *dryRun,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	prefix := ""
+	if result.DryRun {
+		prefix = "dry run: "
+	}
+	fmt.Printf("%s%d rows, %d created, %d updated, %d rejected\n",
+		prefix, result.Total, result.Created, result.Updated, len(result.Rejected))
+	for _, rejection := range result.Rejected {
+		fmt.Fprintf(os.Stderr, "line %d: %v\n", rejection.Row.Line, rejection.Err)
+	}
+
+	if *report != "" && len(result.Rejected) > 0 {
+		out, err := os.Create(*report)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		defer out.Close()
+		if err := importer.WriteRejections(out, result.Rejected); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	if len(result.Rejected) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -1,16 +1,27 @@
 package main
 
 import (
+	"context"
 	_ "device-api/docs" // Import generated docs
 	"device-api/internal/domain"
+	"device-api/internal/events"
+	"device-api/internal/graphqlapi"
+	"device-api/internal/grpcapi"
 	"device-api/internal/handler"
+	"device-api/internal/idgen"
+	"device-api/internal/migrations"
+	"device-api/internal/outbox"
 	"device-api/internal/repository"
 	"device-api/internal/service"
+	"device-api/internal/webhook"
 	"log"
+	"net"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -26,6 +37,106 @@ func main() {
         log.Println("No .env file found")
     }
 
+    broker := events.NewBroker(events.DefaultBacklog)
+    serviceOptions := []service.Option{
+        service.WithDeletedRetention(deletedRetention()),
+        service.WithClientIDs(os.Getenv("ALLOW_CLIENT_IDS") != "false"),
+    }
+
+    var svc *service.DeviceService
+    var reservationSvc *service.ReservationService
+    var db *gorm.DB
+    switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+    case "", "postgres":
+        db = openDatabase()
+        devices := repository.NewPostgresRepository(db)
+        reservations := repository.NewPostgresReservationRepository(db)
+        uow := repository.NewUnitOfWork(db)
+        svc = service.NewDeviceService(
+            devices,
+            append(serviceOptions,
+                service.WithReservations(reservations),
+                service.WithHistory(repository.NewPostgresDeviceHistoryRepository(db)),
+                service.WithIDGenerator(idGenerator(db)),
+                service.WithUnitOfWork(uow),
+                service.WithOutbox(repository.NewPostgresOutboxRepository(db)),
+            )...,
+        )
+        reservationSvc = service.NewReservationService(reservations, devices, service.WithReservationUnitOfWork(uow))
+    case "memory":
+        // There are no webhooks and no outbox: events go straight to the
+        // broker once their unit of work is done.
+        store := repository.NewMemoryStore()
+        devices := repository.NewMemoryRepository(store)
+        reservations := repository.NewMemoryReservationRepository(store)
+        uow := repository.NewMemoryUnitOfWork(store)
+        svc = service.NewDeviceService(
+            devices,
+            append(serviceOptions,
+                service.WithReservations(reservations),
+                service.WithHistory(repository.NewMemoryDeviceHistoryRepository(store)),
+                service.WithIDGenerator(idGenerator(nil)),
+                service.WithUnitOfWork(uow),
+                service.WithEvents(broker),
+            )...,
+        )
+        reservationSvc = service.NewReservationService(reservations, devices, service.WithReservationUnitOfWork(uow))
+    default:
+        log.Fatalf("Unknown STORAGE_DRIVER %q", driver)
+    }
+
+    // "api migrate up|down|status" manages the schema instead of serving.
+    if len(os.Args) > 1 && os.Args[1] == "migrate" {
+        os.Exit(runMigrate(db, os.Args[2:]))
+    }
+    if db != nil {
+        requireCurrentSchema(db)
+    }
+
+    // "api import [flags] FILE" loads an inventory instead of serving.
+    if len(os.Args) > 1 && os.Args[1] == "import" {
+        os.Exit(runImport(svc, os.Args[2:]))
+    }
+
+    r := gin.Default()
+    r.Use(handler.Deadlines(requestTimeouts()))
+    handler.RegisterRoutes(r, handler.NewDeviceHandler(svc))
+    handler.RegisterEventRoutes(r, handler.NewEventHandler(broker))
+    handler.RegisterReservationRoutes(r, handler.NewReservationHandler(reservationSvc))
+
+    if db != nil {
+        webhookRepo := repository.NewPostgresWebhookRepository(db)
+        deliveryRepo := repository.NewPostgresWebhookDeliveryRepository(db)
+        targets := webhookTargets()
+        dispatcher := webhook.NewDispatcher(webhookRepo, deliveryRepo, webhook.WithTargetPolicy(targets))
+        handler.RegisterWebhookRoutes(r, handler.NewWebhookHandler(service.NewWebhookService(webhookRepo, deliveryRepo, service.WithWebhookTargetPolicy(targets))))
+
+        relay := outbox.NewRelay(
+            repository.NewPostgresOutboxRepository(db),
+            outboxSinks(broker, dispatcher),
+            outbox.WithUnitOfWork(repository.NewUnitOfWork(db)),
+        )
+        go relay.Run(context.Background())
+        go dispatcher.Run(context.Background())
+    }
+
+    schema, err := graphqlapi.NewSchema(svc, reservationSvc, broker)
+    if err != nil {
+        log.Fatalf("Failed to build GraphQL schema: %v", err)
+    }
+    graphqlapi.RegisterRoutes(r, graphqlapi.NewHandler(schema))
+
+    go serveGRPC(grpcapi.NewServer(svc, broker))
+
+    port := os.Getenv("PORT")
+    if port == "" {
+        port = "8080"
+    }
+    r.Run(":" + port)
+}
+
+// openDatabase connects to DATABASE_URL.
+func openDatabase() *gorm.DB {
 	dsn := os.Getenv("DATABASE_URL")
     if dsn == "" {
         dsn = "host=db user=postgres password=postgres dbname=devices port=5432 sslmode=disable"
@@ -35,20 +146,123 @@ func main() {
     if err != nil {
         log.Fatalf("Failed to connect to database: %v", err)
     }
+    return db
+}
+
+// requireCurrentSchema stops the process when migrations of the binary have
+// not been applied to the database; "api migrate up" applies them.
+func requireCurrentSchema(db *gorm.DB) {
+    migrator, err := migrations.New(db, migrations.Postgres())
+    if err != nil {
+        log.Fatalf("Failed to load migrations: %v", err)
+    }
+    if err := migrator.Check(context.Background()); err != nil {
+        log.Fatalf("Refusing to start: %v; run \"api migrate up\"", err)
+    }
+}
 
-    // Migrate the schema
-    db.AutoMigrate(&domain.Device{})
+// serveGRPC serves the gRPC API on GRPC_PORT (default 9090).
+func serveGRPC(server *grpcapi.Server) {
+    port := os.Getenv("GRPC_PORT")
+    if port == "" {
+        port = "9090"
+    }
+    lis, err := net.Listen("tcp", ":"+port)
+    if err != nil {
+        log.Fatalf("Failed to listen for gRPC: %v", err)
+    }
+    g := grpc.NewServer()
+    server.Register(g)
+    if err := g.Serve(lis); err != nil {
+        log.Fatalf("gRPC server stopped: %v", err)
+    }
+}
 
-    repo := repository.NewPostgresRepository(db)
-    svc := service.NewDeviceService(repo)
-    h := handler.NewDeviceHandler(svc)
+// deletedRetention reads how long soft deleted devices are kept from
+// DELETED_DEVICE_RETENTION (a Go duration such as "720h").
+func deletedRetention() time.Duration {
+    raw := os.Getenv("DELETED_DEVICE_RETENTION")
+    if raw == "" {
+        return service.DefaultDeletedRetention
+    }
+    retention, err := time.ParseDuration(raw)
+    if err != nil {
+        log.Fatalf("Invalid DELETED_DEVICE_RETENTION %q: %v", raw, err)
+    }
+    return retention
+}
 
-    r := gin.Default()
-    handler.RegisterRoutes(r, h)
+// requestTimeouts reads how long requests may take from REQUEST_TIMEOUT (a
+// Go duration, "0" for no limit) and ROUTE_TIMEOUTS, which overrides single
+// routes, e.g. "GET /api/v1/devices/export=10m,GET /api/v1/devices/:id=2s".
+func requestTimeouts() handler.Timeouts {
+    timeouts := handler.DefaultTimeouts()
+    if raw := os.Getenv("REQUEST_TIMEOUT"); raw != "" {
+        timeout, err := time.ParseDuration(raw)
+        if err != nil {
+            log.Fatalf("Invalid REQUEST_TIMEOUT %q: %v", raw, err)
+        }
+        timeouts.Default = timeout
+    }
+    if err := timeouts.ParseRouteTimeouts(os.Getenv("ROUTE_TIMEOUTS")); err != nil {
+        log.Fatalf("Invalid ROUTE_TIMEOUTS: %v", err)
+    }
+    return timeouts
+}
 
-    port := os.Getenv("PORT")
-    if port == "" {
-        port = "8080"
+// webhookTargets reads the networks webhooks may target despite being
+// private, loopback or link-local from WEBHOOK_ALLOWED_NETWORKS, e.g.
+// "10.0.0.0/8,192.168.1.20".
+func webhookTargets() webhook.TargetPolicy {
+    allowed, err := webhook.ParseAllowedNetworks(os.Getenv("WEBHOOK_ALLOWED_NETWORKS"))
+    if err != nil {
+        log.Fatalf("Invalid WEBHOOK_ALLOWED_NETWORKS: %v", err)
+    }
+    return webhook.TargetPolicy{Allowed: allowed}
+}
+
+// outboxSinks returns the sinks the outbox relay feeds: the live event
+// stream, the webhooks and, when EVENT_LOG is set, a log of every event as
+// JSON lines, written to stdout for "stdout" or appended to the named file.
+func outboxSinks(broker *events.Broker, dispatcher *webhook.Dispatcher) []outbox.Sink {
+    sinks := []outbox.Sink{outbox.PublisherSink(broker), outbox.SinkFunc(dispatcher.Enqueue)}
+    switch path := os.Getenv("EVENT_LOG"); path {
+    case "":
+    case "stdout":
+        sinks = append(sinks, outbox.NewWriterSink(os.Stdout))
+    default:
+        file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+        if err != nil {
+            log.Fatalf("Failed to open EVENT_LOG: %v", err)
+        }
+        sinks = append(sinks, outbox.NewWriterSink(file))
+    }
+    return sinks
+}
+
+// idGenerator builds the generator for server assigned device IDs from
+// DEVICE_ID_STRATEGY: uuidv4 (default), uuidv7, ulid or sequence. The sequence
+// strategy produces IDs such as DEV-000123, using DEVICE_ID_PREFIX, and needs
+// the database.
+func idGenerator(db *gorm.DB) domain.IDGenerator {
+    switch strategy := os.Getenv("DEVICE_ID_STRATEGY"); strategy {
+    case "", "uuidv4":
+        return idgen.UUIDv4{}
+    case "uuidv7":
+        return idgen.UUIDv7{}
+    case "ulid":
+        return idgen.ULID{}
+    case "sequence":
+        if db == nil {
+            log.Fatalf("DEVICE_ID_STRATEGY sequence needs the postgres STORAGE_DRIVER")
+        }
+        prefix, ok := os.LookupEnv("DEVICE_ID_PREFIX")
+        if !ok {
+            prefix = "DEV-"
+        }
+        return repository.NewSequenceGenerator(db, "devices", prefix, 6)
+    default:
+        log.Fatalf("Unknown DEVICE_ID_STRATEGY %q", strategy)
+        return nil
     }
-    r.Run(":" + port)
 }
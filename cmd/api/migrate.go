@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"device-api/internal/migrations"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// runMigrate implements the migrate command and returns the exit code: 0 on
+// success, 1 when a migration failed and 2 on a usage error.
+func runMigrate(db *gorm.DB, args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	steps := flags.Int("steps", 1, "how many migrations down rolls back")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: api migrate up | down [-steps N] | status")
+		flags.PrintDefaults()
+	}
+	if len(args) == 0 {
+		flags.Usage()
+		return 2
+	}
+	command := args[0]
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 || *steps < 1 {
+		flags.Usage()
+		return 2
+	}
+	if db == nil {
+		fmt.Fprintln(os.Stderr, "migrate needs the postgres STORAGE_DRIVER")
+		return 2
+	}
+
+	migrator, err := migrations.New(db, migrations.Postgres())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	ctx := context.Background()
+	var done []migrations.Migration
+	switch command {
+	case "up":
+		done, err = migrator.Up(ctx)
+		for _, migration := range done {
+			fmt.Printf("applied %s\n", migration)
+		}
+	case "down":
+		done, err = migrator.Down(ctx, *steps)
+		for _, migration := range done {
+			fmt.Printf("rolled back %s\n", migration)
+		}
+	case "status":
+		var statuses []migrations.Status
+		statuses, err = migrator.Status(ctx)
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%-40s %s\n", status, state)
+		}
+	default:
+		flags.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// DeviceCriteria selects devices for listing. Every non-empty field narrows
+// the result: the fields are combined with AND, while the values inside a
+// multi-valued field are combined with OR. The zero value matches every
+// device.
+type DeviceCriteria struct {
+	Brands []string
+	States []DeviceState
+	// NameContains matches devices whose name contains it, ignoring case.
+	NameContains string
+	// CreatedFrom and CreatedTo bound the creation time to the half-open
+	// range [CreatedFrom, CreatedTo).
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
+
+// Validate rejects criteria that can never be satisfied because they name
+// an unknown state.
+func (c DeviceCriteria) Validate() error {
+	for _, state := range c.States {
+		if !state.IsValid() {
+			return &FilterError{Field: "state", Value: string(state)}
+		}
+	}
+	return nil
+}
+
+// Matches reports whether device satisfies the criteria. Repositories that
+// filter in the database must return exactly the devices Matches accepts.
+func (c DeviceCriteria) Matches(device *Device) bool {
+	if len(c.Brands) > 0 && !contains(c.Brands, device.Brand) {
+		return false
+	}
+	if len(c.States) > 0 && !contains(c.States, device.State) {
+		return false
+	}
+	if c.NameContains != "" && !strings.Contains(strings.ToLower(device.Name), strings.ToLower(c.NameContains)) {
+		return false
+	}
+	if c.CreatedFrom != nil && device.CreatedAt.Before(*c.CreatedFrom) {
+		return false
+	}
+	if c.CreatedTo != nil && !device.CreatedAt.Before(*c.CreatedTo) {
+		return false
+	}
+	return true
+}
+
+func contains[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -12,12 +12,58 @@ const (
 	DeviceStateInactive  DeviceState = "inactive"
 )
 
+// deviceTransitions lists, for every state, the states a device may move to
+// next. Any state can be retired to inactive, but an inactive device has to be
+// reactivated (back to available) before it can be taken into use again.
+var deviceTransitions = map[DeviceState][]DeviceState{
+	DeviceStateAvailable: {DeviceStateInUse, DeviceStateInactive},
+	DeviceStateInUse:     {DeviceStateAvailable, DeviceStateInactive},
+	DeviceStateInactive:  {DeviceStateAvailable},
+}
+
+// IsValid reports whether s is one of the known device states.
+func (s DeviceState) IsValid() bool {
+	_, ok := deviceTransitions[s]
+	return ok
+}
+
+// AllowedTransitions returns the states a device in state s may move to.
+func (s DeviceState) AllowedTransitions() []DeviceState {
+	allowed := deviceTransitions[s]
+	out := make([]DeviceState, len(allowed))
+	copy(out, allowed)
+	return out
+}
+
+// CanTransitionTo reports whether a device in state s may move to next.
+// Staying in the same state is always allowed.
+func (s DeviceState) CanTransitionTo(next DeviceState) bool {
+	if !next.IsValid() {
+		return false
+	}
+	if s == next {
+		return true
+	}
+	for _, allowed := range deviceTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
 type Device struct {
-	ID        string      `json:"id" gorm:"primaryKey"`
-	Name      string      `json:"name"`
-	Brand     string      `json:"brand"`
-	State     DeviceState `json:"state"`
-	CreatedAt time.Time   `json:"created_at"`
+	ID               string      `json:"id" gorm:"primaryKey"`
+	Name             string      `json:"name"`
+	Brand            string      `json:"brand"`
+	State            DeviceState `json:"state"`
+	Assignee         string      `json:"assignee,omitempty"`
+	CheckedOutAt     *time.Time  `json:"checked_out_at,omitempty"`
+	ExpectedReturnAt *time.Time  `json:"expected_return_at,omitempty"`
+	CreatedAt        time.Time   `json:"created_at"`
+	DeletedAt        *time.Time  `json:"deleted_at,omitempty" gorm:"index"`
+	// Version is bumped on every write and used for optimistic concurrency.
+	Version int64 `json:"version" gorm:"not null;default:1"`
 }
 
 func NewDevice(id, name, brand string) *Device {
@@ -27,29 +73,156 @@ func NewDevice(id, name, brand string) *Device {
 		Brand:     brand,
 		State:     DeviceStateAvailable,
 		CreatedAt: time.Now(),
+		Version:   1,
 	}
 }
 
+// CanUpdateDetails reports whether name and brand may be changed once the
+// device has moved to newState. Details are locked while the device is in use
+// and stays in use.
 func (d *Device) CanUpdateDetails(newState DeviceState) error {
-	if d.State == DeviceStateInUse && (d.State != newState) {
-		return nil 
+	if d.State == DeviceStateInUse && newState == DeviceStateInUse {
+		return ErrDeviceInUse
 	}
 	return nil
 }
 
 func (d *Device) UpdateDetails(name, brand string) error {
-    if d.State == DeviceStateInUse {
-        if d.Name != name || d.Brand != brand {
-            return ErrDeviceInUse
-        }
-    }
-    d.Name = name
-    d.Brand = brand
-    return nil
-}
-
-func (d *Device) UpdateState(state DeviceState) {
-    d.State = state
+	if d.State == DeviceStateInUse {
+		if d.Name != name || d.Brand != brand {
+			return ErrDeviceInUse
+		}
+	}
+	d.Name = name
+	d.Brand = brand
+	return nil
+}
+
+// UpdateState moves the device to state, enforcing the transition table.
+func (d *Device) UpdateState(state DeviceState) error {
+	if err := d.checkTransition(state); err != nil {
+		return err
+	}
+	if state != DeviceStateInUse {
+		d.clearAssignment()
+	}
+	d.State = state
+	return nil
+}
+
+func (d *Device) checkTransition(state DeviceState) error {
+	if !d.State.CanTransitionTo(state) {
+		return &StateTransitionError{
+			From:    d.State,
+			To:      state,
+			Allowed: d.State.AllowedTransitions(),
+		}
+	}
+	return nil
+}
+
+// DeviceAttributes are the fields of a device clients edit directly. The
+// other fields are managed by the device itself.
+type DeviceAttributes struct {
+	Name  string      `json:"name"`
+	Brand string      `json:"brand"`
+	State DeviceState `json:"state"`
+}
+
+func (d *Device) Attributes() DeviceAttributes {
+	return DeviceAttributes{Name: d.Name, Brand: d.Brand, State: d.State}
+}
+
+// attributeRules are checked, in order, before attributes are applied. The
+// first broken rule is reported:
+//
+//  1. name and brand are required;
+//  2. the new state must be reachable from the current one;
+//  3. details are locked while the device is in use and stays in use. The
+//     lock is judged against the new state, so a device can be released
+//     and renamed in one step, or renamed and taken into use, but not
+//     renamed while it remains in use.
+var attributeRules = []func(d *Device, attrs DeviceAttributes) error{
+	func(d *Device, attrs DeviceAttributes) error {
+		if attrs.Name == "" {
+			return ErrDeviceNameRequired
+		}
+		if attrs.Brand == "" {
+			return ErrDeviceBrandRequired
+		}
+		return nil
+	},
+	func(d *Device, attrs DeviceAttributes) error {
+		return d.checkTransition(attrs.State)
+	},
+	func(d *Device, attrs DeviceAttributes) error {
+		if attrs.Name == d.Name && attrs.Brand == d.Brand {
+			return nil
+		}
+		return d.CanUpdateDetails(attrs.State)
+	},
+}
+
+// Apply changes the device to attrs in one step. Every rule in
+// attributeRules is checked before anything is changed, so on error the
+// device is left untouched.
+func (d *Device) Apply(attrs DeviceAttributes) error {
+	for _, rule := range attributeRules {
+		if err := rule(d, attrs); err != nil {
+			return err
+		}
+	}
+
+	if err := d.UpdateState(attrs.State); err != nil {
+		return err
+	}
+	d.Name = attrs.Name
+	d.Brand = attrs.Brand
+	return nil
+}
+
+// Checkout hands an available device to assignee and moves it to in-use.
+// expectedReturnAt is optional but, when given, must lie in the future.
+func (d *Device) Checkout(assignee string, expectedReturnAt *time.Time) error {
+	switch d.State {
+	case DeviceStateInUse:
+		return ErrDeviceInUse
+	case DeviceStateInactive:
+		return ErrDeviceInactive
+	}
+	if assignee == "" {
+		return ErrAssigneeRequired
+	}
+	now := time.Now()
+	if expectedReturnAt != nil && !expectedReturnAt.After(now) {
+		return ErrInvalidReturnTime
+	}
+	if err := d.UpdateState(DeviceStateInUse); err != nil {
+		return err
+	}
+	d.Assignee = assignee
+	d.CheckedOutAt = &now
+	d.ExpectedReturnAt = expectedReturnAt
+	return nil
+}
+
+// CheckIn returns a checked out device and makes it available again.
+func (d *Device) CheckIn() error {
+	if d.State != DeviceStateInUse {
+		return ErrDeviceNotCheckedOut
+	}
+	return d.UpdateState(DeviceStateAvailable)
+}
+
+func (d *Device) clearAssignment() {
+	d.Assignee = ""
+	d.CheckedOutAt = nil
+	d.ExpectedReturnAt = nil
+}
+
+// IsDeleted reports whether the device has been soft deleted.
+func (d *Device) IsDeleted() bool {
+	return d.DeletedAt != nil
 }
 
 func (d *Device) CanBeDeleted() error {
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"time"
+)
+
+// DeviceEvent announces a committed change to a device. Its Action matches
+// the history entry written for the same change. ID is assigned by the
+// events broker and grows with every event it publishes.
+type DeviceEvent struct {
+	ID       uint64        `json:"id,omitempty"`
+	Action   HistoryAction `json:"type"`
+	DeviceID string        `json:"device_id"`
+	// Device is the device after the change; it is empty for deletions.
+	Device *Device `json:"device,omitempty"`
+	// Previous holds the fields of the device before the change; it is
+	// empty for creations.
+	Previous   *DeviceSnapshot `json:"previous,omitempty"`
+	Actor      string          `json:"actor,omitempty"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+func NewDeviceEvent(deviceID string, action HistoryAction, previous *DeviceSnapshot, device *Device, actor string) DeviceEvent {
+	event := DeviceEvent{
+		DeviceID:   deviceID,
+		Action:     action,
+		Previous:   previous,
+		Actor:      actor,
+		OccurredAt: time.Now(),
+	}
+	if device != nil {
+		copied := *device
+		event.Device = &copied
+	}
+	return event
+}
+
+// IEventPublisher delivers device events to whoever listens for them.
+type IEventPublisher interface {
+	Publish(event DeviceEvent)
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"time"
+)
+
+type HistoryAction string
+
+const (
+	HistoryActionCreated      HistoryAction = "created"
+	HistoryActionUpdated      HistoryAction = "updated"
+	HistoryActionStateChanged HistoryAction = "state_changed"
+	HistoryActionCheckedOut   HistoryAction = "checked_out"
+	HistoryActionCheckedIn    HistoryAction = "checked_in"
+	HistoryActionDeleted      HistoryAction = "deleted"
+	HistoryActionRestored     HistoryAction = "restored"
+	HistoryActionPurged       HistoryAction = "purged"
+)
+
+var historyActions = []HistoryAction{
+	HistoryActionCreated,
+	HistoryActionUpdated,
+	HistoryActionStateChanged,
+	HistoryActionCheckedOut,
+	HistoryActionCheckedIn,
+	HistoryActionDeleted,
+	HistoryActionRestored,
+	HistoryActionPurged,
+}
+
+// IsValid reports whether a is one of the known history actions.
+func (a HistoryAction) IsValid() bool {
+	for _, known := range historyActions {
+		if a == known {
+			return true
+		}
+	}
+	return false
+}
+
+// DeviceSnapshot captures the mutable fields of a device at one point in time.
+type DeviceSnapshot struct {
+	Name     string      `json:"name"`
+	Brand    string      `json:"brand"`
+	State    DeviceState `json:"state"`
+	Assignee string      `json:"assignee,omitempty"`
+}
+
+func SnapshotOf(d *Device) *DeviceSnapshot {
+	if d == nil {
+		return nil
+	}
+	return &DeviceSnapshot{
+		Name:     d.Name,
+		Brand:    d.Brand,
+		State:    d.State,
+		Assignee: d.Assignee,
+	}
+}
+
+// DeviceHistoryEntry is an immutable record of a single change to a device.
+// OldValue is empty for creations and NewValue is empty for deletions.
+type DeviceHistoryEntry struct {
+	ID         uint            `json:"id" gorm:"primaryKey"`
+	DeviceID   string          `json:"device_id" gorm:"index"`
+	Action     HistoryAction   `json:"action"`
+	OldValue   *DeviceSnapshot `json:"old_value,omitempty" gorm:"serializer:json;type:text"`
+	NewValue   *DeviceSnapshot `json:"new_value,omitempty" gorm:"serializer:json;type:text"`
+	Actor      string          `json:"actor"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+func NewDeviceHistoryEntry(deviceID string, action HistoryAction, oldValue, newValue *DeviceSnapshot, actor string) *DeviceHistoryEntry {
+	return &DeviceHistoryEntry{
+		DeviceID:   deviceID,
+		Action:     action,
+		OldValue:   oldValue,
+		NewValue:   newValue,
+		Actor:      actor,
+		OccurredAt: time.Now(),
+	}
+}
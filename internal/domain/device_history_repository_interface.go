@@ -0,0 +1,12 @@
+package domain
+
+import "context"
+
+// IDeviceHistoryRepository stores device history entries. Entries are
+// append-only: there is no way to change or remove one once written.
+type IDeviceHistoryRepository interface {
+	Append(ctx context.Context, entry *DeviceHistoryEntry) error
+	// FindByDevice returns one page of a device's history, newest first,
+	// together with the total number of entries for that device.
+	FindByDevice(ctx context.Context, deviceID string, limit, offset int) ([]*DeviceHistoryEntry, int64, error)
+}
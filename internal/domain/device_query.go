@@ -0,0 +1,187 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+type DeviceSortField string
+
+const (
+	SortByID        DeviceSortField = "id"
+	SortByName      DeviceSortField = "name"
+	SortByBrand     DeviceSortField = "brand"
+	SortByState     DeviceSortField = "state"
+	SortByCreatedAt DeviceSortField = "created_at"
+)
+
+var sortFields = map[DeviceSortField]bool{
+	SortByID:        true,
+	SortByName:      true,
+	SortByBrand:     true,
+	SortByState:     true,
+	SortByCreatedAt: true,
+}
+
+type DeviceSort struct {
+	Field      DeviceSortField
+	Descending bool
+}
+
+// ParseDeviceSort parses a comma separated sort specification such as
+// "created_at,-name", where a leading '-' sorts that field descending.
+func ParseDeviceSort(raw string) ([]DeviceSort, error) {
+	var sorts []DeviceSort
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		sort := DeviceSort{Field: DeviceSortField(strings.TrimPrefix(part, "-"))}
+		sort.Descending = strings.HasPrefix(part, "-")
+		if !sortFields[sort.Field] {
+			return nil, ErrInvalidSort
+		}
+		sorts = append(sorts, sort)
+	}
+	return sorts, nil
+}
+
+func (s DeviceSort) String() string {
+	if s.Descending {
+		return "-" + string(s.Field)
+	}
+	return string(s.Field)
+}
+
+// DeviceQuery describes one page of a device listing.
+type DeviceQuery struct {
+	Criteria DeviceCriteria
+	Sort     []DeviceSort
+	// Limit caps the page size; zero means no limit.
+	Limit int
+	// Cursor is the NextCursor of the previous page, if any.
+	Cursor       string
+	IncludeTotal bool
+}
+
+// DevicePage is one page of devices. NextCursor is empty on the last page
+// and Total is only set when it was requested.
+type DevicePage struct {
+	Items      []*Device
+	NextCursor string
+	Total      *int64
+}
+
+// OrderKeys returns the effective ordering of the query: the requested sort,
+// or creation time by default, always followed by the ID so that the order
+// is total and keyset pagination is stable.
+func (q DeviceQuery) OrderKeys() []DeviceSort {
+	keys := append([]DeviceSort{}, q.Sort...)
+	if len(keys) == 0 {
+		keys = append(keys, DeviceSort{Field: SortByCreatedAt})
+	}
+	for _, key := range keys {
+		if key.Field == SortByID {
+			return keys
+		}
+	}
+	return append(keys, DeviceSort{Field: SortByID})
+}
+
+type deviceCursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+// CursorAfter returns the cursor that resumes the listing after device.
+func (q DeviceQuery) CursorAfter(device *Device) string {
+	keys := q.OrderKeys()
+	cursor := deviceCursor{Sort: sortSpec(keys)}
+	for _, key := range keys {
+		cursor.Values = append(cursor.Values, sortValue(key.Field, device))
+	}
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// CursorValues decodes the query cursor into one value per order key: a
+// time.Time for created_at and a string for every other field. It returns
+// nil when the query has no cursor and ErrInvalidCursor when the cursor is
+// malformed or was issued for a different sort order.
+func (q DeviceQuery) CursorValues() ([]interface{}, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor deviceCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	keys := q.OrderKeys()
+	if cursor.Sort != sortSpec(keys) || len(cursor.Values) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		if key.Field != SortByCreatedAt {
+			values[i] = cursor.Values[i]
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, cursor.Values[i])
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values[i] = t
+	}
+	return values, nil
+}
+
+// CompareDevices orders a and b by keys, returning a negative number when a
+// sorts first, a positive number when b does and zero when they tie.
+func CompareDevices(keys []DeviceSort, a, b *Device) int {
+	for _, key := range keys {
+		var cmp int
+		if key.Field == SortByCreatedAt {
+			cmp = a.CreatedAt.Compare(b.CreatedAt)
+		} else {
+			cmp = strings.Compare(sortValue(key.Field, a), sortValue(key.Field, b))
+		}
+		if key.Descending {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+func sortSpec(keys []DeviceSort) string {
+	specs := make([]string, len(keys))
+	for i, key := range keys {
+		specs[i] = key.String()
+	}
+	return strings.Join(specs, ",")
+}
+
+func sortValue(field DeviceSortField, device *Device) string {
+	switch field {
+	case SortByName:
+		return device.Name
+	case SortByBrand:
+		return device.Brand
+	case SortByState:
+		return string(device.State)
+	case SortByCreatedAt:
+		return device.CreatedAt.Format(time.RFC3339Nano)
+	default:
+		return device.ID
+	}
+}
@@ -1,11 +1,34 @@
 package domain
 
+import (
+	"context"
+	"time"
+)
+
 type IDeviceRepository interface {
-	Save(device *Device) error
-	FindByID(id string) (*Device, error)
-	FindAll() ([]*Device, error)
-	FindByBrand(brand string) ([]*Device, error)
-	FindByState(state DeviceState) ([]*Device, error)
-	Delete(id string) error
-	Update(device *Device) error
+	// Save atomically inserts a new device, failing with
+	// ErrDeviceAlreadyExists if the ID is taken.
+	Save(ctx context.Context, device *Device) error
+	FindByID(ctx context.Context, id string) (*Device, error)
+	// Find returns one page of the devices matching query.Criteria, ordered
+	// by query.OrderKeys and starting after query.Cursor.
+	Find(ctx context.Context, query DeviceQuery) (*DevicePage, error)
+	// Delete soft deletes a device if the stored row still has version. On an
+	// unscoped repository it removes the row permanently. It returns
+	// ErrVersionConflict when the row was changed in the meantime.
+	Delete(ctx context.Context, id string, version int64) error
+	// Update writes device only if the stored row still has device.Version,
+	// then increments the version. It returns ErrVersionConflict when the row
+	// was changed in the meantime.
+	Update(ctx context.Context, device *Device) error
+	// Restore clears the deletion marker of a soft deleted device if the
+	// stored row still has version. It returns ErrVersionConflict when the
+	// row was changed in the meantime.
+	Restore(ctx context.Context, id string, version int64) error
+	// PurgeDeletedBefore permanently removes devices soft deleted before
+	// cutoff and returns their IDs.
+	PurgeDeletedBefore(ctx context.Context, cutoff time.Time) ([]string, error)
+	// Unscoped returns a view of the repository that also sees soft deleted
+	// devices.
+	Unscoped() IDeviceRepository
 }
@@ -1,11 +1,81 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
-	ErrDeviceNotFound       = errors.New("device not found")
-	ErrDeviceAlreadyExists  = errors.New("device already exists")
-	ErrInvalidDeviceState   = errors.New("invalid device state")
-	ErrImmutableField       = errors.New("field cannot be updated")
-	ErrDeviceInUse          = errors.New("device is in use")
+	ErrDeviceNotFound      = errors.New("device not found")
+	ErrDeviceAlreadyExists = errors.New("device already exists")
+	ErrDeviceIDRequired    = errors.New("id is required")
+	ErrClientIDNotAllowed  = errors.New("client supplied ids are not allowed")
+	ErrDeviceIDReserved    = errors.New("id is reserved for generated ids")
+	ErrDeviceNameRequired  = errors.New("name is required")
+	ErrDeviceBrandRequired = errors.New("brand is required")
+	ErrInvalidDeviceState  = errors.New("invalid device state")
+	ErrImmutableField      = errors.New("field cannot be updated")
+	ErrDeviceInUse         = errors.New("device is in use")
+	ErrDeviceNotDeleted    = errors.New("device is not deleted")
+	ErrVersionConflict     = errors.New("device was modified concurrently")
+	ErrPreconditionFailed  = errors.New("device version does not match")
+	ErrInvalidFilter       = errors.New("invalid filter")
+	ErrInvalidSort         = errors.New("invalid sort field")
+	ErrInvalidCursor       = errors.New("invalid cursor")
+	ErrDeviceInactive      = errors.New("device is inactive")
+	ErrDeviceNotCheckedOut = errors.New("device is not checked out")
+	ErrAssigneeRequired    = errors.New("assignee is required")
+	ErrInvalidReturnTime   = errors.New("expected return time must be in the future")
+
+	ErrBatchTooLarge          = errors.New("batch has too many items")
+	ErrInvalidBatchMode       = errors.New("invalid batch mode")
+	ErrBatchAborted           = errors.New("not applied because another item of the batch failed")
+	ErrAtomicBatchUnavailable = errors.New("atomic batches need transactional storage")
+
+	ErrReservationNotFound      = errors.New("reservation not found")
+	ErrReservationConflict      = errors.New("reservation overlaps an existing reservation")
+	ErrInvalidReservationWindow = errors.New("reservation must end after it starts and in the future")
+	ErrReserverRequired         = errors.New("reserved_by is required")
+	ErrDeviceReserved           = errors.New("device is reserved by another user")
+
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrInvalidWebhookURL       = errors.New("webhook url must be an absolute http or https url")
+	ErrWebhookTargetNotAllowed = errors.New("webhook url points at a private, loopback or link-local address")
+	ErrInvalidEventType        = errors.New("invalid event type")
+	ErrDeliveryNotFound        = errors.New("webhook delivery not found")
 )
+
+// StateTransitionError is returned when a device is asked to move to a state
+// that is unknown or not reachable from its current state. It matches
+// ErrInvalidDeviceState with errors.Is.
+type StateTransitionError struct {
+	From    DeviceState
+	To      DeviceState
+	Allowed []DeviceState
+}
+
+func (e *StateTransitionError) Error() string {
+	if !e.To.IsValid() {
+		return fmt.Sprintf("%s: unknown state %q", ErrInvalidDeviceState, e.To)
+	}
+	return fmt.Sprintf("%s: cannot move from %q to %q", ErrInvalidDeviceState, e.From, e.To)
+}
+
+func (e *StateTransitionError) Unwrap() error {
+	return ErrInvalidDeviceState
+}
+
+// FilterError is returned when a filter names a value that no device can
+// have. It matches ErrInvalidFilter with errors.Is.
+type FilterError struct {
+	Field string
+	Value string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("%s: unknown %s %q", ErrInvalidFilter, e.Field, e.Value)
+}
+
+func (e *FilterError) Unwrap() error {
+	return ErrInvalidFilter
+}
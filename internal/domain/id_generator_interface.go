@@ -0,0 +1,15 @@
+package domain
+
+import "context"
+
+// IDGenerator produces identifiers for devices created without one.
+type IDGenerator interface {
+	NewID(ctx context.Context) (string, error)
+}
+
+// IDReserver is implemented by generators whose IDs a client could also
+// choose, such as sequential ones. Reserved reports whether the generator
+// may hand out id, in which case clients cannot use it.
+type IDReserver interface {
+	Reserved(id string) bool
+}
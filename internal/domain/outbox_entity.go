@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxMessage is a device event waiting to be relayed. It is written in
+// the same transaction as the change it announces, so an event is stored
+// if and only if its change is committed.
+type OutboxMessage struct {
+	ID        uint   `gorm:"primaryKey"`
+	DeviceID  string `gorm:"index"`
+	EventType HistoryAction
+	Payload   string `gorm:"type:text"`
+	CreatedAt time.Time
+	// Attempts counts the failed attempts at relaying the message.
+	Attempts  int
+	LastError string `gorm:"type:text"`
+	// DeadLetteredAt is set once the relay gave up on the message. The
+	// message is kept for inspection but no longer relayed.
+	DeadLetteredAt *time.Time `gorm:"index"`
+}
+
+func NewOutboxMessage(event DeviceEvent) (*OutboxMessage, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxMessage{
+		DeviceID:  event.DeviceID,
+		EventType: event.Action,
+		Payload:   string(payload),
+		CreatedAt: event.OccurredAt,
+	}, nil
+}
+
+// Event decodes the event held by the message.
+func (m *OutboxMessage) Event() (DeviceEvent, error) {
+	var event DeviceEvent
+	err := json.Unmarshal([]byte(m.Payload), &event)
+	return event, err
+}
+
+// Fail records a failed attempt at relaying the message and dead-letters it
+// once maxAttempts attempts failed.
+func (m *OutboxMessage) Fail(err error, maxAttempts int) {
+	m.Attempts++
+	m.LastError = err.Error()
+	if m.Attempts >= maxAttempts {
+		now := time.Now()
+		m.DeadLetteredAt = &now
+	}
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "context"
+
+// IOutboxRepository stores outbox messages until they are relayed.
+type IOutboxRepository interface {
+	Append(ctx context.Context, message *OutboxMessage) error
+	// Lock keeps every other relay away from the outbox until the
+	// surrounding unit of work ends. It reports false, without waiting, when
+	// another relay holds the outbox.
+	Lock(ctx context.Context) (bool, error)
+	// FindPending returns up to limit messages that are not dead-lettered,
+	// in the order they were appended, leaving out the messages of the
+	// devices in skip.
+	FindPending(ctx context.Context, limit int, skip []string) ([]*OutboxMessage, error)
+	// Remove deletes relayed messages.
+	Remove(ctx context.Context, ids []uint) error
+	// RecordFailure stores the attempts, last error and dead-letter time of
+	// a message that failed to relay.
+	RecordFailure(ctx context.Context, message *OutboxMessage) error
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"time"
+)
+
+// Reservation books a device for a user over the half-open window
+// [StartsAt, EndsAt).
+type Reservation struct {
+	ID         string    `json:"id" gorm:"primaryKey"`
+	DeviceID   string    `json:"device_id" gorm:"index"`
+	ReservedBy string    `json:"reserved_by"`
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func NewReservation(id, deviceID, reservedBy string, startsAt, endsAt time.Time) (*Reservation, error) {
+	if reservedBy == "" {
+		return nil, ErrReserverRequired
+	}
+	if !startsAt.Before(endsAt) || !endsAt.After(time.Now()) {
+		return nil, ErrInvalidReservationWindow
+	}
+	return &Reservation{
+		ID:         id,
+		DeviceID:   deviceID,
+		ReservedBy: reservedBy,
+		StartsAt:   startsAt,
+		EndsAt:     endsAt,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// Overlaps reports whether the reservation window intersects [start, end).
+func (r *Reservation) Overlaps(start, end time.Time) bool {
+	return r.StartsAt.Before(end) && start.Before(r.EndsAt)
+}
+
+// IsActiveAt reports whether t falls inside the reservation window.
+func (r *Reservation) IsActiveAt(t time.Time) bool {
+	return !t.Before(r.StartsAt) && t.Before(r.EndsAt)
+}
+
+// BlocksCheckout reports whether the reservation prevents assignee from
+// checking the device out at from and keeping it until the optional until.
+// A checkout without until may last forever, so every reservation that has
+// not ended by from blocks it. The reserving user is never blocked by their
+// own booking.
+func (r *Reservation) BlocksCheckout(assignee string, from time.Time, until *time.Time) bool {
+	if r.ReservedBy == assignee {
+		return false
+	}
+	if until == nil {
+		return from.Before(r.EndsAt)
+	}
+	return r.IsActiveAt(from) || r.Overlaps(from, *until)
+}
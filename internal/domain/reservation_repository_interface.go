@@ -0,0 +1,19 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type IReservationRepository interface {
+	Save(ctx context.Context, reservation *Reservation) error
+	FindByID(ctx context.Context, id string) (*Reservation, error)
+	// FindByDevice returns the reservations of a device that end after
+	// endingAfter, ordered by start time.
+	FindByDevice(ctx context.Context, deviceID string, endingAfter time.Time) ([]*Reservation, error)
+	Delete(ctx context.Context, id string) error
+	// LockDevice keeps other units of work from reserving the device until
+	// the current one ends. Outside a unit of work the lock is released
+	// straight away.
+	LockDevice(ctx context.Context, deviceID string) error
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "context"
+
+// Repositories are the repositories taking part in a unit of work.
+type Repositories struct {
+	Devices      IDeviceRepository
+	Reservations IReservationRepository
+	History      IDeviceHistoryRepository
+	Outbox       IOutboxRepository
+}
+
+// IUnitOfWork runs a piece of work atomically. Every write made through the
+// repositories handed to fn is committed when fn returns nil and rolled back
+// when it returns an error, which Do then returns.
+type IUnitOfWork interface {
+	Do(ctx context.Context, fn func(repos Repositories) error) error
+}
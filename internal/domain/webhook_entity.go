@@ -0,0 +1,133 @@
+package domain
+
+import (
+	"net/url"
+	"slices"
+	"time"
+)
+
+// Webhook subscribes an HTTP endpoint to device events. Empty EventTypes and
+// States match every event; States is compared with the state of the device
+// after the change, or before it for deletions.
+type Webhook struct {
+	ID         string          `json:"id" gorm:"primaryKey"`
+	URL        string          `json:"url"`
+	Secret     string          `json:"-"`
+	EventTypes []HistoryAction `json:"event_types" gorm:"serializer:json;type:text"`
+	States     []DeviceState   `json:"states" gorm:"serializer:json;type:text"`
+	Active     bool            `json:"active"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// WebhookSubscription holds the settings of a webhook a client chooses.
+type WebhookSubscription struct {
+	URL        string          `json:"url"`
+	EventTypes []HistoryAction `json:"event_types"`
+	States     []DeviceState   `json:"states"`
+	Active     bool            `json:"active"`
+}
+
+func NewWebhook(id, secret string, sub WebhookSubscription) (*Webhook, error) {
+	webhook := &Webhook{ID: id, Secret: secret, CreatedAt: time.Now()}
+	if err := webhook.Apply(sub); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// Apply validates sub and makes it the settings of the webhook.
+func (w *Webhook) Apply(sub WebhookSubscription) error {
+	target, err := url.Parse(sub.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	for _, eventType := range sub.EventTypes {
+		if !eventType.IsValid() {
+			return ErrInvalidEventType
+		}
+	}
+	for _, state := range sub.States {
+		if !state.IsValid() {
+			return &StateTransitionError{To: state}
+		}
+	}
+
+	w.URL = sub.URL
+	w.EventTypes = sub.EventTypes
+	w.States = sub.States
+	w.Active = sub.Active
+	w.UpdatedAt = time.Now()
+	return nil
+}
+
+// Matches reports whether the webhook wants to be told about event.
+func (w *Webhook) Matches(event DeviceEvent) bool {
+	if !w.Active {
+		return false
+	}
+	if len(w.EventTypes) > 0 && !slices.Contains(w.EventTypes, event.Action) {
+		return false
+	}
+	if len(w.States) == 0 {
+		return true
+	}
+	switch {
+	case event.Device != nil:
+		return slices.Contains(w.States, event.Device.State)
+	case event.Previous != nil:
+		return slices.Contains(w.States, event.Previous.State)
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	// DeliveryStatusPending deliveries are waiting for their next attempt.
+	DeliveryStatusPending   DeliveryStatus = "pending"
+	DeliveryStatusSucceeded DeliveryStatus = "succeeded"
+	// DeliveryStatusDead deliveries ran out of attempts. They stay in the
+	// dead-letter list until they are redelivered.
+	DeliveryStatusDead DeliveryStatus = "dead"
+)
+
+// WebhookDelivery is one event sent, or to be sent, to one webhook. It
+// doubles as the delivery log: every attempt updates it with the outcome.
+type WebhookDelivery struct {
+	ID        string         `json:"id" gorm:"primaryKey"`
+	WebhookID string         `json:"webhook_id" gorm:"index"`
+	EventType HistoryAction  `json:"event_type"`
+	DeviceID  string         `json:"device_id"`
+	Payload   string         `json:"payload" gorm:"type:text"`
+	Status    DeliveryStatus `json:"status" gorm:"index"`
+	Attempts  int            `json:"attempts"`
+	// ResponseStatus is the HTTP status of the last attempt, zero when no
+	// response was received.
+	ResponseStatus int        `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"index"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+func NewWebhookDelivery(id string, webhook *Webhook, event DeviceEvent, payload []byte) *WebhookDelivery {
+	now := time.Now()
+	return &WebhookDelivery{
+		ID:            id,
+		WebhookID:     webhook.ID,
+		EventType:     event.Action,
+		DeviceID:      event.DeviceID,
+		Payload:       string(payload),
+		Status:        DeliveryStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
+
+// Redeliver queues the delivery for another round of attempts.
+func (d *WebhookDelivery) Redeliver() {
+	d.Status = DeliveryStatusPending
+	d.Attempts = 0
+	d.NextAttemptAt = time.Now()
+}
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type IWebhookRepository interface {
+	Save(ctx context.Context, webhook *Webhook) error
+	FindByID(ctx context.Context, id string) (*Webhook, error)
+	FindAll(ctx context.Context) ([]*Webhook, error)
+	Update(ctx context.Context, webhook *Webhook) error
+	// Delete removes a webhook together with its deliveries.
+	Delete(ctx context.Context, id string) error
+}
+
+type IWebhookDeliveryRepository interface {
+	Save(ctx context.Context, delivery *WebhookDelivery) error
+	FindByID(ctx context.Context, id string) (*WebhookDelivery, error)
+	Update(ctx context.Context, delivery *WebhookDelivery) error
+	// FindByWebhook returns one page of a webhook's deliveries, newest
+	// first, optionally restricted to one status, together with their total.
+	FindByWebhook(ctx context.Context, webhookID string, status DeliveryStatus, limit, offset int) ([]*WebhookDelivery, int64, error)
+	// FindByStatus returns up to limit deliveries in status, oldest first.
+	FindByStatus(ctx context.Context, status DeliveryStatus, limit int) ([]*WebhookDelivery, error)
+	// ClaimDue returns up to limit pending deliveries whose next attempt is
+	// at or before now, oldest first, and moves their next attempt to
+	// until, so that no other caller claims them before then.
+	ClaimDue(ctx context.Context, now, until time.Time, limit int) ([]*WebhookDelivery, error)
+}
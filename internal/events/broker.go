@@ -0,0 +1,135 @@
+// Package events fans device events out to live subscribers.
+package events
+
+import (
+	"device-api/internal/domain"
+	"slices"
+	"sync"
+)
+
+// DefaultBacklog is the number of recent events a broker keeps so that
+// reconnecting subscribers can catch up.
+const DefaultBacklog = 1024
+
+// subscriberBuffer is the number of events a subscriber may fall behind
+// before it is dropped.
+const subscriberBuffer = 64
+
+// Filter selects the events a subscriber receives. Empty fields match
+// everything. Deletions are matched against the device as it was before.
+type Filter struct {
+	Brands    []string
+	States    []domain.DeviceState
+	DeviceIDs []string
+}
+
+func (f Filter) Matches(event domain.DeviceEvent) bool {
+	if len(f.DeviceIDs) > 0 && !slices.Contains(f.DeviceIDs, event.DeviceID) {
+		return false
+	}
+	var brand string
+	var state domain.DeviceState
+	switch {
+	case event.Device != nil:
+		brand, state = event.Device.Brand, event.Device.State
+	case event.Previous != nil:
+		brand, state = event.Previous.Brand, event.Previous.State
+	}
+	if len(f.Brands) > 0 && !slices.Contains(f.Brands, brand) {
+		return false
+	}
+	if len(f.States) > 0 && !slices.Contains(f.States, state) {
+		return false
+	}
+	return true
+}
+
+// Subscription delivers the events matching its filter. C is closed when
+// the subscription ends, either by Unsubscribe or because the subscriber
+// fell too far behind.
+type Subscription struct {
+	C      <-chan domain.DeviceEvent
+	ch     chan domain.DeviceEvent
+	filter Filter
+}
+
+// Broker is an in-process domain.IEventPublisher. It numbers the events it
+// publishes and keeps the most recent ones in a backlog.
+type Broker struct {
+	mu          sync.Mutex
+	lastID      uint64
+	backlog     []domain.DeviceEvent
+	capacity    int
+	subscribers map[*Subscription]struct{}
+}
+
+func NewBroker(backlog int) *Broker {
+	if backlog <= 0 {
+		backlog = DefaultBacklog
+	}
+	return &Broker{
+		capacity:    backlog,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish numbers event and hands it to every matching subscriber. It never
+// blocks; subscribers whose buffer is full are dropped.
+func (b *Broker) Publish(event domain.DeviceEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+	if len(b.backlog) == b.capacity {
+		b.backlog = slices.Delete(b.backlog, 0, 1)
+	}
+	b.backlog = append(b.backlog, event)
+
+	for sub := range b.subscribers {
+		if !sub.filter.Matches(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe starts a subscription. When lastEventID is not zero the events
+// after it that are still in the backlog are returned as well, so that a
+// client resuming a stream misses nothing that is still retained.
+func (b *Broker) Subscribe(filter Filter, lastEventID uint64) (*Subscription, []domain.DeviceEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed []domain.DeviceEvent
+	if lastEventID > 0 {
+		for _, event := range b.backlog {
+			if event.ID > lastEventID && filter.Matches(event) {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	ch := make(chan domain.DeviceEvent, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter}
+	b.subscribers[sub] = struct{}{}
+	return sub, missed
+}
+
+// Unsubscribe ends sub. It is safe to call more than once.
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,66 @@
+package events
+
+import (
+	"device-api/internal/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func created(id, brand string) domain.DeviceEvent {
+	return domain.NewDeviceEvent(id, domain.HistoryActionCreated, nil, domain.NewDevice(id, "Device "+id, brand), "")
+}
+
+func TestBrokerDeliversMatchingEvents(t *testing.T) {
+	b := NewBroker(10)
+	sub, missed := b.Subscribe(Filter{Brands: []string{"Apple"}}, 0)
+	assert.Empty(t, missed)
+
+	b.Publish(created("1", "Google"))
+	b.Publish(created("2", "Apple"))
+
+	event := <-sub.C
+	assert.Equal(t, "2", event.DeviceID)
+	assert.Equal(t, uint64(2), event.ID)
+	assert.Empty(t, sub.C)
+}
+
+func TestBrokerReplaysBacklog(t *testing.T) {
+	b := NewBroker(2)
+	for _, id := range []string{"1", "2", "3"} {
+		b.Publish(created(id, "Google"))
+	}
+
+	// Event 1 has left the backlog, so resuming after it yields 2 and 3.
+	_, missed := b.Subscribe(Filter{}, 1)
+	if assert.Len(t, missed, 2) {
+		assert.Equal(t, uint64(2), missed[0].ID)
+		assert.Equal(t, uint64(3), missed[1].ID)
+	}
+
+	_, missed = b.Subscribe(Filter{DeviceIDs: []string{"3"}}, 1)
+	assert.Len(t, missed, 1)
+}
+
+func TestBrokerDropsSlowSubscribers(t *testing.T) {
+	b := NewBroker(0)
+	sub, _ := b.Subscribe(Filter{}, 0)
+	for i := 0; i <= subscriberBuffer; i++ {
+		b.Publish(created("1", "Google"))
+	}
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received)
+	b.Unsubscribe(sub)
+}
+
+func TestFilterMatchesDeletionsOnPreviousState(t *testing.T) {
+	device := domain.NewDevice("1", "Pixel 8", "Google")
+	deleted := domain.NewDeviceEvent("1", domain.HistoryActionDeleted, domain.SnapshotOf(device), nil, "")
+
+	assert.True(t, Filter{Brands: []string{"Google"}}.Matches(deleted))
+	assert.False(t, Filter{States: []domain.DeviceState{domain.DeviceStateInUse}}.Matches(deleted))
+}
@@ -0,0 +1,12 @@
+package events
+
+import "device-api/internal/domain"
+
+// Fanout publishes every event to each of its publishers in turn.
+type Fanout []domain.IEventPublisher
+
+func (f Fanout) Publish(event domain.DeviceEvent) {
+	for _, publisher := range f {
+		publisher.Publish(event)
+	}
+}
@@ -0,0 +1,187 @@
+// Package exporter writes the device catalogue as CSV, NDJSON or XLSX.
+package exporter
+
+import (
+	"device-api/internal/domain"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Format is the encoding of an export.
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+	FormatXLSX   Format = "xlsx"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+func ParseFormat(s string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(s))); format {
+	case FormatCSV, FormatNDJSON, FormatXLSX:
+		return format, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, s)
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "application/octet-stream"
+}
+
+// Columns are the columns of CSV and XLSX exports. The first four match the
+// fields read by the importer, so an export can be imported again.
+var Columns = []string{"id", "name", "brand", "state", "assignee", "checked_out_at", "expected_return_at", "created_at", "deleted_at", "version"}
+
+// Writer writes devices one at a time. Close finishes the export; for XLSX
+// nothing reaches the underlying writer before it. Close must be called
+// even after a failed Write, to release the writer.
+type Writer interface {
+	Write(device *domain.Device) error
+	Close() error
+}
+
+func NewWriter(w io.Writer, format Format) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		out := csv.NewWriter(w)
+		if err := out.Write(Columns); err != nil {
+			return nil, err
+		}
+		return &csvWriter{out: out}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{out: json.NewEncoder(w)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+func record(device *domain.Device) []string {
+	return []string{
+		escapeCell(device.ID),
+		escapeCell(device.Name),
+		escapeCell(device.Brand),
+		string(device.State),
+		escapeCell(device.Assignee),
+		formatTime(device.CheckedOutAt),
+		formatTime(device.ExpectedReturnAt),
+		formatTime(&device.CreatedAt),
+		formatTime(device.DeletedAt),
+		strconv.FormatInt(device.Version, 10),
+	}
+}
+
+// escapeCell prefixes values that spreadsheets would read as a formula with
+// a quote, which makes them text.
+func escapeCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+type csvWriter struct {
+	out *csv.Writer
+}
+
+func (w *csvWriter) Write(device *domain.Device) error {
+	return w.out.Write(record(device))
+}
+
+func (w *csvWriter) Close() error {
+	w.out.Flush()
+	return w.out.Error()
+}
+
+type ndjsonWriter struct {
+	out *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(device *domain.Device) error {
+	return w.out.Encode(device)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
+
+// xlsxWriter uses the excelize stream writer, which keeps large sheets in a
+// temporary file rather than in memory.
+type xlsxWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+const xlsxSheet = "Devices"
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+	if err := file.SetSheetName("Sheet1", xlsxSheet); err != nil {
+		file.Close()
+		return nil, err
+	}
+	stream, err := file.NewStreamWriter(xlsxSheet)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	x := &xlsxWriter{w: w, file: file, stream: stream}
+	if err := x.writeRow(Columns); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *xlsxWriter) Write(device *domain.Device) error {
+	return x.writeRow(record(device))
+}
+
+func (x *xlsxWriter) writeRow(values []string) error {
+	x.row++
+	cells := make([]interface{}, len(values))
+	for i, value := range values {
+		cells[i] = value
+	}
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	return x.stream.SetRow(cell, cells)
+}
+
+func (x *xlsxWriter) Close() error {
+	defer x.file.Close()
+	if err := x.stream.Flush(); err != nil {
+		return err
+	}
+	_, err := x.file.WriteTo(x.w)
+	return err
+}
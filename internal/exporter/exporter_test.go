@@ -0,0 +1,96 @@
+package exporter
+
+import (
+	"bytes"
+	"device-api/internal/domain"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func testDevices() []*domain.Device {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	pixel := domain.NewDevice("1", "Pixel 8", "Google")
+	pixel.CreatedAt = created
+	iphone := domain.NewDevice("2", "iPhone, 15", "Apple")
+	iphone.CreatedAt = created
+	iphone.State = domain.DeviceStateInUse
+	iphone.Assignee = "alice"
+	return []*domain.Device{pixel, iphone}
+}
+
+func export(t *testing.T, format Format) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format)
+	assert.NoError(t, err)
+	for _, device := range testDevices() {
+		assert.NoError(t, w.Write(device))
+	}
+	assert.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestCSV(t *testing.T) {
+	assert.Equal(t, "id,name,brand,state,assignee,checked_out_at,expected_return_at,created_at,deleted_at,version\n"+
+		"1,Pixel 8,Google,available,,,,2024-05-01T12:00:00Z,,1\n"+
+		"2,\"iPhone, 15\",Apple,in-use,alice,,,2024-05-01T12:00:00Z,,1\n", string(export(t, FormatCSV)))
+}
+
+func TestNDJSON(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(string(export(t, FormatNDJSON))), "\n")
+	assert.Len(t, lines, 2)
+	var device domain.Device
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &device))
+	assert.Equal(t, "alice", device.Assignee)
+}
+
+func TestXLSX(t *testing.T) {
+	file, err := excelize.OpenReader(bytes.NewReader(export(t, FormatXLSX)))
+	assert.NoError(t, err)
+	defer file.Close()
+
+	rows, err := file.GetRows(xlsxSheet)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, Columns, rows[0])
+	assert.Equal(t, "iPhone, 15", rows[2][1])
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("XLSX")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatXLSX, format)
+
+	_, err = ParseFormat("pdf")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestFormulasAreEscaped(t *testing.T) {
+	device := domain.NewDevice("=1+1", "@SUM(A1:A2)", "-2+3")
+	device.CreatedAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	device.Assignee = "+cmd"
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, FormatCSV)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Write(device))
+	assert.NoError(t, w.Close())
+	assert.Contains(t, buf.String(), "'=1+1,'@SUM(A1:A2),'-2+3,available,'+cmd,")
+
+	buf.Reset()
+	w, err = NewWriter(&buf, FormatXLSX)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Write(device))
+	assert.NoError(t, w.Close())
+	file, err := excelize.OpenReader(&buf)
+	assert.NoError(t, err)
+	defer file.Close()
+	rows, err := file.GetRows(xlsxSheet)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"'=1+1", "'@SUM(A1:A2)", "'-2+3", "available", "'+cmd"}, rows[1][:5])
+}
@@ -0,0 +1,91 @@
+package graphqlapi
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"errors"
+)
+
+// Codes of errors that are not caused by a domain error.
+const (
+	codeInvalidArgument = "INVALID_ARGUMENT"
+	codeInternal        = "INTERNAL"
+)
+
+type errorCode struct {
+	err  error
+	code string
+}
+
+// errorCodes names the domain errors reported to clients, using the same
+// codes as the reasons of the gRPC API. Operations that ended before they
+// were answered report CANCELLED or DEADLINE_EXCEEDED.
+var errorCodes = []errorCode{
+	{domain.ErrDeviceNotFound, "DEVICE_NOT_FOUND"},
+	{domain.ErrDeviceAlreadyExists, "DEVICE_ALREADY_EXISTS"},
+	{domain.ErrDeviceIDRequired, "DEVICE_ID_REQUIRED"},
+	{domain.ErrClientIDNotAllowed, "CLIENT_ID_NOT_ALLOWED"},
+	{domain.ErrDeviceIDReserved, "DEVICE_ID_RESERVED"},
+	{domain.ErrDeviceNameRequired, "DEVICE_NAME_REQUIRED"},
+	{domain.ErrDeviceBrandRequired, "DEVICE_BRAND_REQUIRED"},
+	{domain.ErrInvalidDeviceState, "INVALID_DEVICE_STATE"},
+	{domain.ErrImmutableField, "IMMUTABLE_FIELD"},
+	{domain.ErrInvalidFilter, "INVALID_FILTER"},
+	{domain.ErrInvalidSort, "INVALID_SORT"},
+	{domain.ErrInvalidCursor, "INVALID_CURSOR"},
+	{domain.ErrDeviceInUse, "DEVICE_IN_USE"},
+	{domain.ErrDeviceInactive, "DEVICE_INACTIVE"},
+	{domain.ErrDeviceNotDeleted, "DEVICE_NOT_DELETED"},
+	{domain.ErrPreconditionFailed, "PRECONDITION_FAILED"},
+	{domain.ErrVersionConflict, "VERSION_CONFLICT"},
+	{context.Canceled, "CANCELLED"},
+	{context.DeadlineExceeded, "DEADLINE_EXCEEDED"},
+}
+
+// Error is a GraphQL error carrying a stable code in its extensions, e.g.
+// {"code": "DEVICE_IN_USE"}. Rejected state transitions also list the
+// allowedStates.
+type Error struct {
+	Code          string
+	Message       string
+	AllowedStates []domain.DeviceState
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Extensions implements gqlerrors.ExtendedError.
+func (e *Error) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": e.Code}
+	if e.AllowedStates != nil {
+		extensions["allowedStates"] = e.AllowedStates
+	}
+	return extensions
+}
+
+// graphqlError converts err into an *Error. Known domain errors keep their
+// message; the messages of unexpected errors are not exposed.
+func graphqlError(err error) error {
+	var gqlErr *Error
+	if errors.As(err, &gqlErr) {
+		return gqlErr
+	}
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			gqlErr = &Error{Code: c.code, Message: err.Error()}
+			break
+		}
+	}
+	if gqlErr == nil {
+		return &Error{Code: codeInternal, Message: "an unexpected error occurred"}
+	}
+
+	var transitionErr *domain.StateTransitionError
+	if errors.As(err, &transitionErr) && transitionErr.To.IsValid() {
+		gqlErr.AllowedStates = transitionErr.Allowed
+	}
+	return gqlErr
+}
+
+func invalidArgument(message string) error {
+	return &Error{Code: codeInvalidArgument, Message: message}
+}
@@ -0,0 +1,45 @@
+package graphqlapi
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphQLError(t *testing.T) {
+	tests := []struct {
+		err     error
+		code    string
+		allowed []domain.DeviceState
+	}{
+		{domain.ErrDeviceNotFound, "DEVICE_NOT_FOUND", nil},
+		{fmt.Errorf("saving: %w", domain.ErrDeviceAlreadyExists), "DEVICE_ALREADY_EXISTS", nil},
+		{domain.ErrDeviceInUse, "DEVICE_IN_USE", nil},
+		{domain.ErrVersionConflict, "VERSION_CONFLICT", nil},
+		{fmt.Errorf("finding device: %w", context.Canceled), "CANCELLED", nil},
+		{&domain.StateTransitionError{From: domain.DeviceStateInactive, To: domain.DeviceStateInUse, Allowed: []domain.DeviceState{domain.DeviceStateAvailable}}, "INVALID_DEVICE_STATE", []domain.DeviceState{domain.DeviceStateAvailable}},
+		{&domain.StateTransitionError{To: "banana"}, "INVALID_DEVICE_STATE", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			var gqlErr *Error
+			assert.True(t, errors.As(graphqlError(tt.err), &gqlErr))
+			assert.Equal(t, tt.err.Error(), gqlErr.Message)
+			assert.Equal(t, tt.code, gqlErr.Extensions()["code"])
+			if tt.allowed == nil {
+				assert.NotContains(t, gqlErr.Extensions(), "allowedStates")
+			} else {
+				assert.Equal(t, tt.allowed, gqlErr.Extensions()["allowedStates"])
+			}
+		})
+	}
+
+	var gqlErr *Error
+	assert.True(t, errors.As(graphqlError(errors.New("connection refused")), &gqlErr))
+	assert.Equal(t, codeInternal, gqlErr.Code)
+	assert.NotContains(t, gqlErr.Message, "connection refused")
+}
@@ -0,0 +1,130 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// ActorHeader names the request header identifying who performs a change,
+// as in the REST API.
+const ActorHeader = "X-Actor"
+
+var (
+	errSubscriptionOverHTTP = errors.New("subscriptions are only served over a WebSocket")
+	errMutationOverGET      = errors.New("mutations must be sent with POST")
+)
+
+// Request is a GraphQL request as sent in a POST body, or as the query,
+// operationName and variables parameters of a GET request.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+type Handler struct {
+	schema   graphql.Schema
+	upgrader websocket.Upgrader
+}
+
+func NewHandler(schema graphql.Schema) *Handler {
+	return &Handler{
+		schema:   schema,
+		upgrader: websocket.Upgrader{Subprotocols: []string{wsProtocol}},
+	}
+}
+
+func RegisterRoutes(r *gin.Engine, handler *Handler) {
+	r.GET("/graphql", handler.Serve)
+	r.POST("/graphql", handler.Serve)
+}
+
+// Serve answers queries and mutations sent with POST, and queries sent with
+// GET. A WebSocket upgrade starts a graphql-transport-ws session, which also
+// serves subscriptions.
+func (h *Handler) Serve(c *gin.Context) {
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		h.serveWebSocket(c)
+		return
+	}
+
+	var req Request
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if raw := c.Query("variables"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &req.Variables); err != nil {
+				c.JSON(http.StatusBadRequest, errorResult(errors.New("variables must be a JSON object")))
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResult(errors.New("the body must be a JSON object with a query")))
+		return
+	}
+
+	switch operationType(req) {
+	case ast.OperationTypeSubscription:
+		c.JSON(http.StatusBadRequest, errorResult(errSubscriptionOverHTTP))
+		return
+	case ast.OperationTypeMutation:
+		if c.Request.Method == http.MethodGet {
+			c.Header("Allow", http.MethodPost)
+			c.JSON(http.StatusMethodNotAllowed, errorResult(errMutationOverGET))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, graphql.Do(h.params(c.Request.Context(), req, c.GetHeader(ActorHeader))))
+}
+
+func (h *Handler) params(ctx context.Context, req Request, actor string) graphql.Params {
+	return graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        WithActor(ctx, actor),
+	}
+}
+
+// operationType returns the type of the operation req executes, or an empty
+// string when the query cannot be parsed or names no such operation; the
+// executor reports those errors.
+func operationType(req Request) string {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query)})})
+	if err != nil {
+		return ""
+	}
+	var found *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if req.OperationName == "" || (op.Name != nil && op.Name.Value == req.OperationName) {
+			if found != nil {
+				return ""
+			}
+			found = op
+		}
+	}
+	if found == nil {
+		return ""
+	}
+	return found.Operation
+}
+
+func errorResult(err error) *graphql.Result {
+	return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+}
@@ -0,0 +1,491 @@
+// Package graphqlapi serves the device service as a GraphQL schema. Queries
+// and mutations are answered over HTTP; subscriptions over a WebSocket.
+package graphqlapi
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"device-api/internal/events"
+	"device-api/internal/service"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	defaultHistoryPageSize = 50
+	maxHistoryPageSize     = 200
+)
+
+var errInvalidEventID = errors.New("afterEventId must be a non-negative integer")
+
+type contextKey int
+
+const actorKey contextKey = iota
+
+// WithActor returns a context attributing changes made by mutations to actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+func actorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey).(string); ok && actor != "" {
+		return actor
+	}
+	return "anonymous"
+}
+
+var deviceStateEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "DeviceState",
+	Values: graphql.EnumValueConfigMap{
+		"AVAILABLE": {Value: domain.DeviceStateAvailable},
+		"IN_USE":    {Value: domain.DeviceStateInUse},
+		"INACTIVE":  {Value: domain.DeviceStateInactive},
+	},
+})
+
+var eventTypeEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "EventType",
+	Values: graphql.EnumValueConfigMap{
+		"CREATED":       {Value: domain.HistoryActionCreated},
+		"UPDATED":       {Value: domain.HistoryActionUpdated},
+		"STATE_CHANGED": {Value: domain.HistoryActionStateChanged},
+		"CHECKED_OUT":   {Value: domain.HistoryActionCheckedOut},
+		"CHECKED_IN":    {Value: domain.HistoryActionCheckedIn},
+		"DELETED":       {Value: domain.HistoryActionDeleted},
+		"RESTORED":      {Value: domain.HistoryActionRestored},
+		"PURGED":        {Value: domain.HistoryActionPurged},
+	},
+})
+
+var snapshotType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "DeviceSnapshot",
+	Fields: graphql.Fields{
+		"name":     {Type: graphql.NewNonNull(graphql.String)},
+		"brand":    {Type: graphql.NewNonNull(graphql.String)},
+		"state":    {Type: graphql.NewNonNull(deviceStateEnum)},
+		"assignee": {Type: graphql.String},
+	},
+})
+
+var historyEntryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "HistoryEntry",
+	Fields: graphql.Fields{
+		"id":         {Type: graphql.NewNonNull(graphql.ID)},
+		"deviceId":   {Type: graphql.NewNonNull(graphql.ID)},
+		"action":     {Type: graphql.NewNonNull(eventTypeEnum)},
+		"oldValue":   {Type: snapshotType},
+		"newValue":   {Type: snapshotType},
+		"actor":      {Type: graphql.NewNonNull(graphql.String)},
+		"occurredAt": {Type: graphql.NewNonNull(graphql.DateTime)},
+	},
+})
+
+var reservationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Reservation",
+	Fields: graphql.Fields{
+		"id":         {Type: graphql.NewNonNull(graphql.ID)},
+		"deviceId":   {Type: graphql.NewNonNull(graphql.ID)},
+		"reservedBy": {Type: graphql.NewNonNull(graphql.String)},
+		"startsAt":   {Type: graphql.NewNonNull(graphql.DateTime)},
+		"endsAt":     {Type: graphql.NewNonNull(graphql.DateTime)},
+		"createdAt":  {Type: graphql.NewNonNull(graphql.DateTime)},
+	},
+})
+
+// resolver holds what the resolvers of the schema need.
+type resolver struct {
+	devices      *service.DeviceService
+	reservations *service.ReservationService
+	broker       *events.Broker
+}
+
+// NewSchema builds the GraphQL schema over the device and reservation
+// services. Subscriptions are fed by broker. Without a reservation service
+// every device lists no reservations.
+func NewSchema(devices *service.DeviceService, reservations *service.ReservationService, broker *events.Broker) (graphql.Schema, error) {
+	r := &resolver{devices: devices, reservations: reservations, broker: broker}
+
+	deviceType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Device",
+		Fields: graphql.Fields{
+			"id":               {Type: graphql.NewNonNull(graphql.ID)},
+			"name":             {Type: graphql.NewNonNull(graphql.String)},
+			"brand":            {Type: graphql.NewNonNull(graphql.String)},
+			"state":            {Type: graphql.NewNonNull(deviceStateEnum)},
+			"assignee":         {Type: graphql.String, Resolve: r.assignee},
+			"checkedOutAt":     {Type: graphql.DateTime},
+			"expectedReturnAt": {Type: graphql.DateTime},
+			"createdAt":        {Type: graphql.NewNonNull(graphql.DateTime)},
+			"deletedAt":        {Type: graphql.DateTime},
+			"version":          {Type: graphql.NewNonNull(graphql.Int)},
+			"history": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(historyEntryType))),
+				Description: "Changes to the device, newest first.",
+				Args: graphql.FieldConfigArgument{
+					"limit":  {Type: graphql.Int, DefaultValue: defaultHistoryPageSize},
+					"offset": {Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: r.history,
+			},
+			"reservations": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(reservationType))),
+				Description: "Current and upcoming reservations of the device.",
+				Args: graphql.FieldConfigArgument{
+					"includePast": {Type: graphql.Boolean, DefaultValue: false},
+				},
+				Resolve: r.deviceReservations,
+			},
+		},
+	})
+
+	devicePageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "DevicePage",
+		Fields: graphql.Fields{
+			"items":      {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(deviceType)))},
+			"nextCursor": {Type: graphql.String, Resolve: r.nextCursor},
+			"total":      {Type: graphql.Int},
+		},
+	})
+
+	eventType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "DeviceEvent",
+		Fields: graphql.Fields{
+			"id":         {Type: graphql.NewNonNull(graphql.ID)},
+			"type":       {Type: graphql.NewNonNull(eventTypeEnum)},
+			"deviceId":   {Type: graphql.NewNonNull(graphql.ID)},
+			"device":     {Type: deviceType},
+			"previous":   {Type: snapshotType},
+			"actor":      {Type: graphql.NewNonNull(graphql.String)},
+			"occurredAt": {Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+
+	versionArg := &graphql.ArgumentConfig{
+		Type:        graphql.Int,
+		Description: "Only apply the change when the device is at this version.",
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"device": {
+				Type: deviceType,
+				Args: graphql.FieldConfigArgument{
+					"id":             {Type: graphql.NewNonNull(graphql.ID)},
+					"includeDeleted": {Type: graphql.Boolean, DefaultValue: false},
+				},
+				Resolve: r.device,
+			},
+			"devices": {
+				Type:        graphql.NewNonNull(devicePageType),
+				Description: "A page of devices. Filters are combined with AND, the values of one filter with OR.",
+				Args: graphql.FieldConfigArgument{
+					"brands":         {Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+					"states":         {Type: graphql.NewList(graphql.NewNonNull(deviceStateEnum))},
+					"nameContains":   {Type: graphql.String},
+					"createdFrom":    {Type: graphql.DateTime},
+					"createdTo":      {Type: graphql.DateTime},
+					"sort":           {Type: graphql.String, Description: "Sort keys such as \"created_at,-name\"."},
+					"limit":          {Type: graphql.Int},
+					"cursor":         {Type: graphql.String},
+					"includeTotal":   {Type: graphql.Boolean, DefaultValue: false},
+					"includeDeleted": {Type: graphql.Boolean, DefaultValue: false},
+				},
+				Resolve: r.listDevices,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createDevice": {
+				Type: graphql.NewNonNull(deviceType),
+				Args: graphql.FieldConfigArgument{
+					"id":    {Type: graphql.ID},
+					"name":  {Type: graphql.NewNonNull(graphql.String)},
+					"brand": {Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: r.createDevice,
+			},
+			"updateDevice": {
+				Type:        graphql.NewNonNull(deviceType),
+				Description: "Change the name and brand of a device. Omitted fields are kept.",
+				Args: graphql.FieldConfigArgument{
+					"id":             {Type: graphql.NewNonNull(graphql.ID)},
+					"name":           {Type: graphql.String},
+					"brand":          {Type: graphql.String},
+					"ifMatchVersion": versionArg,
+				},
+				Resolve: r.updateDevice,
+			},
+			"updateDeviceState": {
+				Type: graphql.NewNonNull(deviceType),
+				Args: graphql.FieldConfigArgument{
+					"id":             {Type: graphql.NewNonNull(graphql.ID)},
+					"state":          {Type: graphql.NewNonNull(deviceStateEnum)},
+					"ifMatchVersion": versionArg,
+				},
+				Resolve: r.updateDeviceState,
+			},
+			"deleteDevice": {
+				Type:        graphql.NewNonNull(graphql.ID),
+				Description: "Soft delete a device, returning its ID.",
+				Args: graphql.FieldConfigArgument{
+					"id":             {Type: graphql.NewNonNull(graphql.ID)},
+					"ifMatchVersion": versionArg,
+				},
+				Resolve: r.deleteDevice,
+			},
+		},
+	})
+
+	subscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"deviceStateChanged": {
+				Type:        graphql.NewNonNull(eventType),
+				Description: "Events that move a device to another state, matching every given filter.",
+				Args: graphql.FieldConfigArgument{
+					"brands":       {Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+					"states":       {Type: graphql.NewList(graphql.NewNonNull(deviceStateEnum))},
+					"ids":          {Type: graphql.NewList(graphql.NewNonNull(graphql.ID))},
+					"afterEventId": {Type: graphql.ID, Description: "Resume after this event."},
+				},
+				Subscribe: r.subscribeStateChanges,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:        query,
+		Mutation:     mutation,
+		Subscription: subscription,
+	})
+}
+
+// serviceFor returns the device service scoped to the actor of the request
+// and, when the ifMatchVersion argument is given, to that version.
+func (r *resolver) serviceFor(p graphql.ResolveParams) *service.DeviceService {
+	svc := r.devices.As(actorFrom(p.Context))
+	if version, ok := p.Args["ifMatchVersion"].(int); ok {
+		svc = svc.IfMatch(int64(version))
+	}
+	return svc
+}
+
+func (r *resolver) assignee(p graphql.ResolveParams) (interface{}, error) {
+	if device := p.Source.(*domain.Device); device.Assignee != "" {
+		return device.Assignee, nil
+	}
+	return nil, nil
+}
+
+func (r *resolver) nextCursor(p graphql.ResolveParams) (interface{}, error) {
+	if page := p.Source.(*domain.DevicePage); page.NextCursor != "" {
+		return page.NextCursor, nil
+	}
+	return nil, nil
+}
+
+func (r *resolver) device(p graphql.ResolveParams) (interface{}, error) {
+	svc := r.devices
+	if p.Args["includeDeleted"].(bool) {
+		svc = svc.IncludingDeleted()
+	}
+	device, err := svc.GetDevice(p.Context, p.Args["id"].(string))
+	if errors.Is(err, domain.ErrDeviceNotFound) {
+		// A missing device is an absent result, not a failed query.
+		return nil, nil
+	}
+	return result(device, err)
+}
+
+func (r *resolver) listDevices(p graphql.ResolveParams) (interface{}, error) {
+	sortSpec, _ := p.Args["sort"].(string)
+	sort, err := domain.ParseDeviceSort(sortSpec)
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	limit, _ := p.Args["limit"].(int)
+	if limit < 0 {
+		return nil, invalidArgument("limit must not be negative")
+	}
+	cursor, _ := p.Args["cursor"].(string)
+	nameContains, _ := p.Args["nameContains"].(string)
+
+	query := domain.DeviceQuery{
+		Criteria: domain.DeviceCriteria{
+			Brands:       stringsArg(p.Args["brands"]),
+			States:       statesArg(p.Args["states"]),
+			NameContains: nameContains,
+			CreatedFrom:  timeArg(p.Args["createdFrom"]),
+			CreatedTo:    timeArg(p.Args["createdTo"]),
+		},
+		Sort:         sort,
+		Limit:        limit,
+		Cursor:       cursor,
+		IncludeTotal: p.Args["includeTotal"].(bool),
+	}
+
+	svc := r.devices
+	if p.Args["includeDeleted"].(bool) {
+		svc = svc.IncludingDeleted()
+	}
+	page, err := svc.ListDevices(p.Context, query)
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	if page.Items == nil {
+		page.Items = []*domain.Device{}
+	}
+	return page, nil
+}
+
+func (r *resolver) history(p graphql.ResolveParams) (interface{}, error) {
+	limit, offset := p.Args["limit"].(int), p.Args["offset"].(int)
+	if limit < 1 || limit > maxHistoryPageSize || offset < 0 {
+		return nil, invalidArgument("limit must be between 1 and " + strconv.Itoa(maxHistoryPageSize) + " and offset must not be negative")
+	}
+	entries, _, err := r.devices.DeviceHistory(p.Context, p.Source.(*domain.Device).ID, limit, offset)
+	return result(entries, err)
+}
+
+func (r *resolver) deviceReservations(p graphql.ResolveParams) (interface{}, error) {
+	if r.reservations == nil {
+		return []*domain.Reservation{}, nil
+	}
+	reservations, err := r.reservations.ListReservations(p.Context, p.Source.(*domain.Device).ID, p.Args["includePast"].(bool))
+	return result(reservations, err)
+}
+
+func (r *resolver) createDevice(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	device, err := r.serviceFor(p).CreateDevice(p.Context, id, p.Args["name"].(string), p.Args["brand"].(string))
+	return result(device, err)
+}
+
+func (r *resolver) updateDevice(p graphql.ResolveParams) (interface{}, error) {
+	device, err := r.serviceFor(p).ModifyDevice(p.Context, p.Args["id"].(string), func(attrs *domain.DeviceAttributes) error {
+		if name, ok := p.Args["name"].(string); ok {
+			attrs.Name = name
+		}
+		if brand, ok := p.Args["brand"].(string); ok {
+			attrs.Brand = brand
+		}
+		return nil
+	})
+	return result(device, err)
+}
+
+func (r *resolver) updateDeviceState(p graphql.ResolveParams) (interface{}, error) {
+	device, err := r.serviceFor(p).UpdateDeviceState(p.Context, p.Args["id"].(string), p.Args["state"].(domain.DeviceState))
+	return result(device, err)
+}
+
+func (r *resolver) deleteDevice(p graphql.ResolveParams) (interface{}, error) {
+	id := p.Args["id"].(string)
+	if err := r.serviceFor(p).DeleteDevice(p.Context, id); err != nil {
+		return nil, graphqlError(err)
+	}
+	return id, nil
+}
+
+// subscribeStateChanges subscribes to the broker and passes on the events
+// that change the state of a device until the subscription ends. The
+// channel is closed when the subscriber falls behind; the client is then
+// expected to resume with afterEventId.
+func (r *resolver) subscribeStateChanges(p graphql.ResolveParams) (interface{}, error) {
+	filter := events.Filter{
+		Brands:    stringsArg(p.Args["brands"]),
+		States:    statesArg(p.Args["states"]),
+		DeviceIDs: stringsArg(p.Args["ids"]),
+	}
+	var after uint64
+	if raw, ok := p.Args["afterEventId"].(string); ok {
+		var err error
+		if after, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			return nil, invalidArgument(errInvalidEventID.Error())
+		}
+	}
+
+	sub, missed := r.broker.Subscribe(filter, after)
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+		defer r.broker.Unsubscribe(sub)
+
+		send := func(event domain.DeviceEvent) bool {
+			if !changesState(event) {
+				return true
+			}
+			select {
+			case out <- event:
+				return true
+			case <-p.Context.Done():
+				return false
+			}
+		}
+		for _, event := range missed {
+			if !send(event) {
+				return
+			}
+		}
+		for {
+			select {
+			case <-p.Context.Done():
+				return
+			case event, ok := <-sub.C:
+				if !ok || !send(event) {
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// changesState reports whether event moved a device to another state.
+// Creations do not count, and a deleted device keeps its state.
+func changesState(event domain.DeviceEvent) bool {
+	return event.Previous != nil && event.Device != nil && event.Previous.State != event.Device.State
+}
+
+// result returns value, or the GraphQL error for err when it is not nil.
+func result(value interface{}, err error) (interface{}, error) {
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	return value, nil
+}
+
+func stringsArg(arg interface{}) []string {
+	var out []string
+	values, _ := arg.([]interface{})
+	for _, value := range values {
+		out = append(out, value.(string))
+	}
+	return out
+}
+
+func statesArg(arg interface{}) []domain.DeviceState {
+	var out []domain.DeviceState
+	values, _ := arg.([]interface{})
+	for _, value := range values {
+		out = append(out, value.(domain.DeviceState))
+	}
+	return out
+}
+
+func timeArg(arg interface{}) *time.Time {
+	if t, ok := arg.(time.Time); ok {
+		return &t
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// wsProtocol is the WebSocket subprotocol of the graphql-ws library,
+// https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md.
+const wsProtocol = "graphql-transport-ws"
+
+// Message types of the protocol.
+const (
+	msgConnectionInit = "connection_init"
+	msgConnectionAck  = "connection_ack"
+	msgPing           = "ping"
+	msgPong           = "pong"
+	msgSubscribe      = "subscribe"
+	msgNext           = "next"
+	msgError          = "error"
+	msgComplete       = "complete"
+)
+
+// Close codes of the protocol.
+const (
+	closeBadRequest          = 4400
+	closeUnauthorized        = 4401
+	closeSubprotocol         = 4406
+	closeInitTimeout         = 4408
+	closeSubscriberExists    = 4409
+	closeTooManyInitRequests = 4429
+)
+
+const (
+	// connectionInitWaitTimeout is how long a client may take to send
+	// connection_init.
+	connectionInitWaitTimeout = 10 * time.Second
+	// heartbeatInterval is how often an idle connection is pinged.
+	heartbeatInterval = 15 * time.Second
+)
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsSession is one graphql-transport-ws connection. Operations run
+// concurrently, so writes are serialized.
+type wsSession struct {
+	handler *Handler
+	conn    *websocket.Conn
+	actor   string
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	active  map[string]context.CancelFunc
+}
+
+// serveWebSocket runs a graphql-transport-ws session. The actor of the
+// session is the actor of the connection_init payload, falling back to the
+// X-Actor header of the upgrade request.
+func (h *Handler) serveWebSocket(c *gin.Context) {
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already answered the request.
+		return
+	}
+	defer conn.Close()
+
+	s := &wsSession{handler: h, conn: conn, actor: c.GetHeader(ActorHeader), active: map[string]context.CancelFunc{}}
+	if conn.Subprotocol() != wsProtocol {
+		s.close(closeSubprotocol, "Subprotocol not acceptable")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	go s.heartbeat(ctx)
+
+	conn.SetReadDeadline(time.Now().Add(connectionInitWaitTimeout))
+	acknowledged := false
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if !acknowledged {
+				s.close(closeInitTimeout, "Connection initialisation timeout")
+			}
+			return
+		}
+
+		switch msg.Type {
+		case msgConnectionInit:
+			if acknowledged {
+				s.close(closeTooManyInitRequests, "Too many initialisation requests")
+				return
+			}
+			var payload struct {
+				Actor string `json:"actor"`
+			}
+			if len(msg.Payload) > 0 && json.Unmarshal(msg.Payload, &payload) == nil && payload.Actor != "" {
+				s.actor = payload.Actor
+			}
+			acknowledged = true
+			conn.SetReadDeadline(time.Time{})
+			s.send(wsMessage{Type: msgConnectionAck})
+		case msgPing:
+			s.send(wsMessage{Type: msgPong})
+		case msgPong:
+		case msgSubscribe:
+			if !acknowledged {
+				s.close(closeUnauthorized, "Unauthorized")
+				return
+			}
+			var req Request
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil {
+				s.close(closeBadRequest, "Invalid subscribe message")
+				return
+			}
+			if !s.start(ctx, msg.ID, req) {
+				s.close(closeSubscriberExists, "Subscriber for "+msg.ID+" already exists")
+				return
+			}
+		case msgComplete:
+			s.stop(msg.ID)
+		default:
+			s.close(closeBadRequest, "Invalid message type")
+			return
+		}
+	}
+}
+
+// start runs the operation req under id. It returns false when an
+// operation with that id is still running.
+func (s *wsSession) start(ctx context.Context, id string, req Request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.active[id]; ok {
+		return false
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s.active[id] = cancel
+
+	params := s.handler.params(ctx, req, s.actor)
+	go func() {
+		defer cancel()
+		if operationType(req) != ast.OperationTypeSubscription {
+			s.finish(id, graphql.Do(params))
+			return
+		}
+
+		// The executor closes results once the subscription ends; it
+		// has to be drained to let the executor return.
+		results := graphql.Subscribe(params)
+		first := true
+		for result := range results {
+			if first && len(result.Errors) > 0 && result.Data == nil {
+				// The subscription could not be started.
+				s.fail(id, result.Errors)
+				for range results {
+				}
+				return
+			}
+			first = false
+			s.send(s.payload(msgNext, id, result))
+		}
+		s.finish(id, nil)
+	}()
+	return true
+}
+
+// finish sends the last result of an operation, if any, and completes it
+// unless the client completed it first.
+func (s *wsSession) finish(id string, result *graphql.Result) {
+	if !s.remove(id) {
+		return
+	}
+	if result != nil {
+		s.send(s.payload(msgNext, id, result))
+	}
+	s.send(wsMessage{ID: id, Type: msgComplete})
+}
+
+func (s *wsSession) fail(id string, errs []gqlerrors.FormattedError) {
+	if s.remove(id) {
+		s.send(s.payload(msgError, id, errs))
+	}
+}
+
+// stop ends the operation id on request of the client.
+func (s *wsSession) stop(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.active[id]; ok {
+		cancel()
+		delete(s.active, id)
+	}
+}
+
+// remove forgets the operation id, reporting whether it was still active.
+func (s *wsSession) remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.active[id]
+	delete(s.active, id)
+	return ok
+}
+
+func (s *wsSession) payload(msgType, id string, payload interface{}) wsMessage {
+	raw, _ := json.Marshal(payload)
+	return wsMessage{ID: id, Type: msgType, Payload: raw}
+}
+
+func (s *wsSession) send(msg wsMessage) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.WriteJSON(msg)
+}
+
+func (s *wsSession) close(code int, reason string) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+}
+
+// heartbeat pings the client so that proxies keep the connection open and
+// dead clients are noticed.
+func (s *wsSession) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.writeMu.Lock()
+			err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(heartbeatInterval))
+			s.writeMu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
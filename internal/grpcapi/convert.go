@@ -0,0 +1,114 @@
+package grpcapi
+
+import (
+	"device-api/internal/domain"
+	pb "device-api/internal/grpcapi/devicev1"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var deviceStates = map[domain.DeviceState]pb.DeviceState{
+	domain.DeviceStateAvailable: pb.DeviceState_DEVICE_STATE_AVAILABLE,
+	domain.DeviceStateInUse:     pb.DeviceState_DEVICE_STATE_IN_USE,
+	domain.DeviceStateInactive:  pb.DeviceState_DEVICE_STATE_INACTIVE,
+}
+
+var eventTypes = map[domain.HistoryAction]pb.EventType{
+	domain.HistoryActionCreated:      pb.EventType_EVENT_TYPE_CREATED,
+	domain.HistoryActionUpdated:      pb.EventType_EVENT_TYPE_UPDATED,
+	domain.HistoryActionStateChanged: pb.EventType_EVENT_TYPE_STATE_CHANGED,
+	domain.HistoryActionCheckedOut:   pb.EventType_EVENT_TYPE_CHECKED_OUT,
+	domain.HistoryActionCheckedIn:    pb.EventType_EVENT_TYPE_CHECKED_IN,
+	domain.HistoryActionDeleted:      pb.EventType_EVENT_TYPE_DELETED,
+	domain.HistoryActionRestored:     pb.EventType_EVENT_TYPE_RESTORED,
+	domain.HistoryActionPurged:       pb.EventType_EVENT_TYPE_PURGED,
+}
+
+// stateFromProto returns the domain state of s. The unspecified state maps
+// to the empty state, which the domain rejects where a state is required.
+func stateFromProto(s pb.DeviceState) domain.DeviceState {
+	for state, value := range deviceStates {
+		if value == s {
+			return state
+		}
+	}
+	return ""
+}
+
+func statesFromProto(states []pb.DeviceState) []domain.DeviceState {
+	var out []domain.DeviceState
+	for _, s := range states {
+		out = append(out, stateFromProto(s))
+	}
+	return out
+}
+
+func toProtoDevice(d *domain.Device) *pb.Device {
+	if d == nil {
+		return nil
+	}
+	return &pb.Device{
+		Id:               d.ID,
+		Name:             d.Name,
+		Brand:            d.Brand,
+		State:            deviceStates[d.State],
+		Assignee:         d.Assignee,
+		CheckedOutAt:     toTimestamp(d.CheckedOutAt),
+		ExpectedReturnAt: toTimestamp(d.ExpectedReturnAt),
+		CreatedAt:        timestamppb.New(d.CreatedAt),
+		DeletedAt:        toTimestamp(d.DeletedAt),
+		Version:          d.Version,
+	}
+}
+
+func toProtoSnapshot(s *domain.DeviceSnapshot) *pb.DeviceSnapshot {
+	if s == nil {
+		return nil
+	}
+	return &pb.DeviceSnapshot{
+		Name:     s.Name,
+		Brand:    s.Brand,
+		State:    deviceStates[s.State],
+		Assignee: s.Assignee,
+	}
+}
+
+func toProtoHistoryEntry(e *domain.DeviceHistoryEntry) *pb.HistoryEntry {
+	return &pb.HistoryEntry{
+		Id:         uint64(e.ID),
+		DeviceId:   e.DeviceID,
+		Action:     eventTypes[e.Action],
+		OldValue:   toProtoSnapshot(e.OldValue),
+		NewValue:   toProtoSnapshot(e.NewValue),
+		Actor:      e.Actor,
+		OccurredAt: timestamppb.New(e.OccurredAt),
+	}
+}
+
+func toProtoEvent(e domain.DeviceEvent) *pb.DeviceEvent {
+	return &pb.DeviceEvent{
+		Id:         e.ID,
+		Type:       eventTypes[e.Action],
+		DeviceId:   e.DeviceID,
+		Device:     toProtoDevice(e.Device),
+		Previous:   toProtoSnapshot(e.Previous),
+		Actor:      e.Actor,
+		OccurredAt: timestamppb.New(e.OccurredAt),
+	}
+}
+
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func fromTimestamp(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
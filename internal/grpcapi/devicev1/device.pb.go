@@ -0,0 +1,1676 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: device/v1/device.proto
+
+package devicev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type DeviceState int32
+
+const (
+	DeviceState_DEVICE_STATE_UNSPECIFIED DeviceState = 0
+	DeviceState_DEVICE_STATE_AVAILABLE   DeviceState = 1
+	DeviceState_DEVICE_STATE_IN_USE      DeviceState = 2
+	DeviceState_DEVICE_STATE_INACTIVE    DeviceState = 3
+)
+
+// Enum value maps for DeviceState.
+var (
+	DeviceState_name = map[int32]string{
+		0: "DEVICE_STATE_UNSPECIFIED",
+		1: "DEVICE_STATE_AVAILABLE",
+		2: "DEVICE_STATE_IN_USE",
+		3: "DEVICE_STATE_INACTIVE",
+	}
+	DeviceState_value = map[string]int32{
+		"DEVICE_STATE_UNSPECIFIED": 0,
+		"DEVICE_STATE_AVAILABLE":   1,
+		"DEVICE_STATE_IN_USE":      2,
+		"DEVICE_STATE_INACTIVE":    3,
+	}
+)
+
+func (x DeviceState) Enum() *DeviceState {
+	p := new(DeviceState)
+	*p = x
+	return p
+}
+
+func (x DeviceState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DeviceState) Descriptor() protoreflect.EnumDescriptor {
+	return file_device_v1_device_proto_enumTypes[0].Descriptor()
+}
+
+func (DeviceState) Type() protoreflect.EnumType {
+	return &file_device_v1_device_proto_enumTypes[0]
+}
+
+func (x DeviceState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DeviceState.Descriptor instead.
+func (DeviceState) EnumDescriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{0}
+}
+
+type EventType int32
+
+const (
+	EventType_EVENT_TYPE_UNSPECIFIED   EventType = 0
+	EventType_EVENT_TYPE_CREATED       EventType = 1
+	EventType_EVENT_TYPE_UPDATED       EventType = 2
+	EventType_EVENT_TYPE_STATE_CHANGED EventType = 3
+	EventType_EVENT_TYPE_CHECKED_OUT   EventType = 4
+	EventType_EVENT_TYPE_CHECKED_IN    EventType = 5
+	EventType_EVENT_TYPE_DELETED       EventType = 6
+	EventType_EVENT_TYPE_RESTORED      EventType = 7
+	EventType_EVENT_TYPE_PURGED        EventType = 8
+)
+
+// Enum value maps for EventType.
+var (
+	EventType_name = map[int32]string{
+		0: "EVENT_TYPE_UNSPECIFIED",
+		1: "EVENT_TYPE_CREATED",
+		2: "EVENT_TYPE_UPDATED",
+		3: "EVENT_TYPE_STATE_CHANGED",
+		4: "EVENT_TYPE_CHECKED_OUT",
+		5: "EVENT_TYPE_CHECKED_IN",
+		6: "EVENT_TYPE_DELETED",
+		7: "EVENT_TYPE_RESTORED",
+		8: "EVENT_TYPE_PURGED",
+	}
+	EventType_value = map[string]int32{
+		"EVENT_TYPE_UNSPECIFIED":   0,
+		"EVENT_TYPE_CREATED":       1,
+		"EVENT_TYPE_UPDATED":       2,
+		"EVENT_TYPE_STATE_CHANGED": 3,
+		"EVENT_TYPE_CHECKED_OUT":   4,
+		"EVENT_TYPE_CHECKED_IN":    5,
+		"EVENT_TYPE_DELETED":       6,
+		"EVENT_TYPE_RESTORED":      7,
+		"EVENT_TYPE_PURGED":        8,
+	}
+)
+
+func (x EventType) Enum() *EventType {
+	p := new(EventType)
+	*p = x
+	return p
+}
+
+func (x EventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_device_v1_device_proto_enumTypes[1].Descriptor()
+}
+
+func (EventType) Type() protoreflect.EnumType {
+	return &file_device_v1_device_proto_enumTypes[1]
+}
+
+func (x EventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EventType.Descriptor instead.
+func (EventType) EnumDescriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{1}
+}
+
+type Device struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Id               string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name             string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Brand            string                 `protobuf:"bytes,3,opt,name=brand,proto3" json:"brand,omitempty"`
+	State            DeviceState            `protobuf:"varint,4,opt,name=state,proto3,enum=device.v1.DeviceState" json:"state,omitempty"`
+	Assignee         string                 `protobuf:"bytes,5,opt,name=assignee,proto3" json:"assignee,omitempty"`
+	CheckedOutAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=checked_out_at,json=checkedOutAt,proto3" json:"checked_out_at,omitempty"`
+	ExpectedReturnAt *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=expected_return_at,json=expectedReturnAt,proto3" json:"expected_return_at,omitempty"`
+	CreatedAt        *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	DeletedAt        *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=deleted_at,json=deletedAt,proto3" json:"deleted_at,omitempty"`
+	Version          int64                  `protobuf:"varint,10,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Device) Reset() {
+	*x = Device{}
+	mi := &file_device_v1_device_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Device) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Device) ProtoMessage() {}
+
+func (x *Device) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Device.ProtoReflect.Descriptor instead.
+func (*Device) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Device) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Device) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Device) GetBrand() string {
+	if x != nil {
+		return x.Brand
+	}
+	return ""
+}
+
+func (x *Device) GetState() DeviceState {
+	if x != nil {
+		return x.State
+	}
+	return DeviceState_DEVICE_STATE_UNSPECIFIED
+}
+
+func (x *Device) GetAssignee() string {
+	if x != nil {
+		return x.Assignee
+	}
+	return ""
+}
+
+func (x *Device) GetCheckedOutAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CheckedOutAt
+	}
+	return nil
+}
+
+func (x *Device) GetExpectedReturnAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpectedReturnAt
+	}
+	return nil
+}
+
+func (x *Device) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Device) GetDeletedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DeletedAt
+	}
+	return nil
+}
+
+func (x *Device) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type CreateDeviceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Brand         string                 `protobuf:"bytes,3,opt,name=brand,proto3" json:"brand,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateDeviceRequest) Reset() {
+	*x = CreateDeviceRequest{}
+	mi := &file_device_v1_device_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateDeviceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateDeviceRequest) ProtoMessage() {}
+
+func (x *CreateDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateDeviceRequest.ProtoReflect.Descriptor instead.
+func (*CreateDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreateDeviceRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *CreateDeviceRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateDeviceRequest) GetBrand() string {
+	if x != nil {
+		return x.Brand
+	}
+	return ""
+}
+
+type GetDeviceRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	IncludeDeleted bool                   `protobuf:"varint,2,opt,name=include_deleted,json=includeDeleted,proto3" json:"include_deleted,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GetDeviceRequest) Reset() {
+	*x = GetDeviceRequest{}
+	mi := &file_device_v1_device_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDeviceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDeviceRequest) ProtoMessage() {}
+
+func (x *GetDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDeviceRequest.ProtoReflect.Descriptor instead.
+func (*GetDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetDeviceRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GetDeviceRequest) GetIncludeDeleted() bool {
+	if x != nil {
+		return x.IncludeDeleted
+	}
+	return false
+}
+
+type ListDevicesRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Brands         []string               `protobuf:"bytes,1,rep,name=brands,proto3" json:"brands,omitempty"`
+	States         []DeviceState          `protobuf:"varint,2,rep,packed,name=states,proto3,enum=device.v1.DeviceState" json:"states,omitempty"`
+	NameContains   string                 `protobuf:"bytes,3,opt,name=name_contains,json=nameContains,proto3" json:"name_contains,omitempty"`
+	CreatedFrom    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created_from,json=createdFrom,proto3" json:"created_from,omitempty"`
+	CreatedTo      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_to,json=createdTo,proto3" json:"created_to,omitempty"`
+	IncludeDeleted bool                   `protobuf:"varint,6,opt,name=include_deleted,json=includeDeleted,proto3" json:"include_deleted,omitempty"`
+	Sort           string                 `protobuf:"bytes,7,opt,name=sort,proto3" json:"sort,omitempty"`
+	PageSize       int32                  `protobuf:"varint,8,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken      string                 `protobuf:"bytes,9,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	IncludeTotal   bool                   `protobuf:"varint,10,opt,name=include_total,json=includeTotal,proto3" json:"include_total,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ListDevicesRequest) Reset() {
+	*x = ListDevicesRequest{}
+	mi := &file_device_v1_device_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDevicesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDevicesRequest) ProtoMessage() {}
+
+func (x *ListDevicesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDevicesRequest.ProtoReflect.Descriptor instead.
+func (*ListDevicesRequest) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListDevicesRequest) GetBrands() []string {
+	if x != nil {
+		return x.Brands
+	}
+	return nil
+}
+
+func (x *ListDevicesRequest) GetStates() []DeviceState {
+	if x != nil {
+		return x.States
+	}
+	return nil
+}
+
+func (x *ListDevicesRequest) GetNameContains() string {
+	if x != nil {
+		return x.NameContains
+	}
+	return ""
+}
+
+func (x *ListDevicesRequest) GetCreatedFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedFrom
+	}
+	return nil
+}
+
+func (x *ListDevicesRequest) GetCreatedTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedTo
+	}
+	return nil
+}
+
+func (x *ListDevicesRequest) GetIncludeDeleted() bool {
+	if x != nil {
+		return x.IncludeDeleted
+	}
+	return false
+}
+
+func (x *ListDevicesRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+func (x *ListDevicesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListDevicesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListDevicesRequest) GetIncludeTotal() bool {
+	if x != nil {
+		return x.IncludeTotal
+	}
+	return false
+}
+
+type ListDevicesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Devices       []*Device              `protobuf:"bytes,1,rep,name=devices,proto3" json:"devices,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	Total         *int64                 `protobuf:"varint,3,opt,name=total,proto3,oneof" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDevicesResponse) Reset() {
+	*x = ListDevicesResponse{}
+	mi := &file_device_v1_device_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDevicesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDevicesResponse) ProtoMessage() {}
+
+func (x *ListDevicesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDevicesResponse.ProtoReflect.Descriptor instead.
+func (*ListDevicesResponse) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListDevicesResponse) GetDevices() []*Device {
+	if x != nil {
+		return x.Devices
+	}
+	return nil
+}
+
+func (x *ListDevicesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+func (x *ListDevicesResponse) GetTotal() int64 {
+	if x != nil && x.Total != nil {
+		return *x.Total
+	}
+	return 0
+}
+
+type ReplaceDeviceRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name           string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Brand          string                 `protobuf:"bytes,3,opt,name=brand,proto3" json:"brand,omitempty"`
+	State          DeviceState            `protobuf:"varint,4,opt,name=state,proto3,enum=device.v1.DeviceState" json:"state,omitempty"`
+	IfMatchVersion *int64                 `protobuf:"varint,5,opt,name=if_match_version,json=ifMatchVersion,proto3,oneof" json:"if_match_version,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ReplaceDeviceRequest) Reset() {
+	*x = ReplaceDeviceRequest{}
+	mi := &file_device_v1_device_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplaceDeviceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplaceDeviceRequest) ProtoMessage() {}
+
+func (x *ReplaceDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplaceDeviceRequest.ProtoReflect.Descriptor instead.
+func (*ReplaceDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ReplaceDeviceRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ReplaceDeviceRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ReplaceDeviceRequest) GetBrand() string {
+	if x != nil {
+		return x.Brand
+	}
+	return ""
+}
+
+func (x *ReplaceDeviceRequest) GetState() DeviceState {
+	if x != nil {
+		return x.State
+	}
+	return DeviceState_DEVICE_STATE_UNSPECIFIED
+}
+
+func (x *ReplaceDeviceRequest) GetIfMatchVersion() int64 {
+	if x != nil && x.IfMatchVersion != nil {
+		return *x.IfMatchVersion
+	}
+	return 0
+}
+
+type UpdateDeviceRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name           *string                `protobuf:"bytes,2,opt,name=name,proto3,oneof" json:"name,omitempty"`
+	Brand          *string                `protobuf:"bytes,3,opt,name=brand,proto3,oneof" json:"brand,omitempty"`
+	State          *DeviceState           `protobuf:"varint,4,opt,name=state,proto3,enum=device.v1.DeviceState,oneof" json:"state,omitempty"`
+	IfMatchVersion *int64                 `protobuf:"varint,5,opt,name=if_match_version,json=ifMatchVersion,proto3,oneof" json:"if_match_version,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *UpdateDeviceRequest) Reset() {
+	*x = UpdateDeviceRequest{}
+	mi := &file_device_v1_device_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateDeviceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateDeviceRequest) ProtoMessage() {}
+
+func (x *UpdateDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateDeviceRequest.ProtoReflect.Descriptor instead.
+func (*UpdateDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdateDeviceRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UpdateDeviceRequest) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+func (x *UpdateDeviceRequest) GetBrand() string {
+	if x != nil && x.Brand != nil {
+		return *x.Brand
+	}
+	return ""
+}
+
+func (x *UpdateDeviceRequest) GetState() DeviceState {
+	if x != nil && x.State != nil {
+		return *x.State
+	}
+	return DeviceState_DEVICE_STATE_UNSPECIFIED
+}
+
+func (x *UpdateDeviceRequest) GetIfMatchVersion() int64 {
+	if x != nil && x.IfMatchVersion != nil {
+		return *x.IfMatchVersion
+	}
+	return 0
+}
+
+type DeleteDeviceRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	IfMatchVersion *int64                 `protobuf:"varint,2,opt,name=if_match_version,json=ifMatchVersion,proto3,oneof" json:"if_match_version,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *DeleteDeviceRequest) Reset() {
+	*x = DeleteDeviceRequest{}
+	mi := &file_device_v1_device_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteDeviceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteDeviceRequest) ProtoMessage() {}
+
+func (x *DeleteDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteDeviceRequest.ProtoReflect.Descriptor instead.
+func (*DeleteDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DeleteDeviceRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *DeleteDeviceRequest) GetIfMatchVersion() int64 {
+	if x != nil && x.IfMatchVersion != nil {
+		return *x.IfMatchVersion
+	}
+	return 0
+}
+
+type RestoreDeviceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreDeviceRequest) Reset() {
+	*x = RestoreDeviceRequest{}
+	mi := &file_device_v1_device_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreDeviceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreDeviceRequest) ProtoMessage() {}
+
+func (x *RestoreDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreDeviceRequest.ProtoReflect.Descriptor instead.
+func (*RestoreDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *RestoreDeviceRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type CheckoutDeviceRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Id               string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Assignee         string                 `protobuf:"bytes,2,opt,name=assignee,proto3" json:"assignee,omitempty"`
+	ExpectedReturnAt *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expected_return_at,json=expectedReturnAt,proto3" json:"expected_return_at,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *CheckoutDeviceRequest) Reset() {
+	*x = CheckoutDeviceRequest{}
+	mi := &file_device_v1_device_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckoutDeviceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckoutDeviceRequest) ProtoMessage() {}
+
+func (x *CheckoutDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckoutDeviceRequest.ProtoReflect.Descriptor instead.
+func (*CheckoutDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CheckoutDeviceRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *CheckoutDeviceRequest) GetAssignee() string {
+	if x != nil {
+		return x.Assignee
+	}
+	return ""
+}
+
+func (x *CheckoutDeviceRequest) GetExpectedReturnAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpectedReturnAt
+	}
+	return nil
+}
+
+type CheckinDeviceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckinDeviceRequest) Reset() {
+	*x = CheckinDeviceRequest{}
+	mi := &file_device_v1_device_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckinDeviceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckinDeviceRequest) ProtoMessage() {}
+
+func (x *CheckinDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckinDeviceRequest.ProtoReflect.Descriptor instead.
+func (*CheckinDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *CheckinDeviceRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type GetDeviceHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	PageSize      int32                  `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	Offset        int32                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDeviceHistoryRequest) Reset() {
+	*x = GetDeviceHistoryRequest{}
+	mi := &file_device_v1_device_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDeviceHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDeviceHistoryRequest) ProtoMessage() {}
+
+func (x *GetDeviceHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDeviceHistoryRequest.ProtoReflect.Descriptor instead.
+func (*GetDeviceHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetDeviceHistoryRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GetDeviceHistoryRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *GetDeviceHistoryRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type DeviceSnapshot struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Brand         string                 `protobuf:"bytes,2,opt,name=brand,proto3" json:"brand,omitempty"`
+	State         DeviceState            `protobuf:"varint,3,opt,name=state,proto3,enum=device.v1.DeviceState" json:"state,omitempty"`
+	Assignee      string                 `protobuf:"bytes,4,opt,name=assignee,proto3" json:"assignee,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeviceSnapshot) Reset() {
+	*x = DeviceSnapshot{}
+	mi := &file_device_v1_device_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceSnapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceSnapshot) ProtoMessage() {}
+
+func (x *DeviceSnapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceSnapshot.ProtoReflect.Descriptor instead.
+func (*DeviceSnapshot) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DeviceSnapshot) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DeviceSnapshot) GetBrand() string {
+	if x != nil {
+		return x.Brand
+	}
+	return ""
+}
+
+func (x *DeviceSnapshot) GetState() DeviceState {
+	if x != nil {
+		return x.State
+	}
+	return DeviceState_DEVICE_STATE_UNSPECIFIED
+}
+
+func (x *DeviceSnapshot) GetAssignee() string {
+	if x != nil {
+		return x.Assignee
+	}
+	return ""
+}
+
+type HistoryEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	DeviceId      string                 `protobuf:"bytes,2,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Action        EventType              `protobuf:"varint,3,opt,name=action,proto3,enum=device.v1.EventType" json:"action,omitempty"`
+	OldValue      *DeviceSnapshot        `protobuf:"bytes,4,opt,name=old_value,json=oldValue,proto3" json:"old_value,omitempty"`
+	NewValue      *DeviceSnapshot        `protobuf:"bytes,5,opt,name=new_value,json=newValue,proto3" json:"new_value,omitempty"`
+	Actor         string                 `protobuf:"bytes,6,opt,name=actor,proto3" json:"actor,omitempty"`
+	OccurredAt    *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryEntry) Reset() {
+	*x = HistoryEntry{}
+	mi := &file_device_v1_device_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryEntry) ProtoMessage() {}
+
+func (x *HistoryEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryEntry.ProtoReflect.Descriptor instead.
+func (*HistoryEntry) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *HistoryEntry) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetAction() EventType {
+	if x != nil {
+		return x.Action
+	}
+	return EventType_EVENT_TYPE_UNSPECIFIED
+}
+
+func (x *HistoryEntry) GetOldValue() *DeviceSnapshot {
+	if x != nil {
+		return x.OldValue
+	}
+	return nil
+}
+
+func (x *HistoryEntry) GetNewValue() *DeviceSnapshot {
+	if x != nil {
+		return x.NewValue
+	}
+	return nil
+}
+
+func (x *HistoryEntry) GetActor() string {
+	if x != nil {
+		return x.Actor
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+type GetDeviceHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*HistoryEntry        `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	Total         int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDeviceHistoryResponse) Reset() {
+	*x = GetDeviceHistoryResponse{}
+	mi := &file_device_v1_device_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDeviceHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDeviceHistoryResponse) ProtoMessage() {}
+
+func (x *GetDeviceHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDeviceHistoryResponse.ProtoReflect.Descriptor instead.
+func (*GetDeviceHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetDeviceHistoryResponse) GetEntries() []*HistoryEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *GetDeviceHistoryResponse) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type PurgeDeletedDevicesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PurgeDeletedDevicesRequest) Reset() {
+	*x = PurgeDeletedDevicesRequest{}
+	mi := &file_device_v1_device_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PurgeDeletedDevicesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PurgeDeletedDevicesRequest) ProtoMessage() {}
+
+func (x *PurgeDeletedDevicesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PurgeDeletedDevicesRequest.ProtoReflect.Descriptor instead.
+func (*PurgeDeletedDevicesRequest) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{15}
+}
+
+type PurgeDeletedDevicesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []string               `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PurgeDeletedDevicesResponse) Reset() {
+	*x = PurgeDeletedDevicesResponse{}
+	mi := &file_device_v1_device_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PurgeDeletedDevicesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PurgeDeletedDevicesResponse) ProtoMessage() {}
+
+func (x *PurgeDeletedDevicesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PurgeDeletedDevicesResponse.ProtoReflect.Descriptor instead.
+func (*PurgeDeletedDevicesResponse) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *PurgeDeletedDevicesResponse) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+type WatchDevicesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Brands        []string               `protobuf:"bytes,1,rep,name=brands,proto3" json:"brands,omitempty"`
+	States        []DeviceState          `protobuf:"varint,2,rep,packed,name=states,proto3,enum=device.v1.DeviceState" json:"states,omitempty"`
+	DeviceIds     []string               `protobuf:"bytes,3,rep,name=device_ids,json=deviceIds,proto3" json:"device_ids,omitempty"`
+	AfterEventId  uint64                 `protobuf:"varint,4,opt,name=after_event_id,json=afterEventId,proto3" json:"after_event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchDevicesRequest) Reset() {
+	*x = WatchDevicesRequest{}
+	mi := &file_device_v1_device_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchDevicesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchDevicesRequest) ProtoMessage() {}
+
+func (x *WatchDevicesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchDevicesRequest.ProtoReflect.Descriptor instead.
+func (*WatchDevicesRequest) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *WatchDevicesRequest) GetBrands() []string {
+	if x != nil {
+		return x.Brands
+	}
+	return nil
+}
+
+func (x *WatchDevicesRequest) GetStates() []DeviceState {
+	if x != nil {
+		return x.States
+	}
+	return nil
+}
+
+func (x *WatchDevicesRequest) GetDeviceIds() []string {
+	if x != nil {
+		return x.DeviceIds
+	}
+	return nil
+}
+
+func (x *WatchDevicesRequest) GetAfterEventId() uint64 {
+	if x != nil {
+		return x.AfterEventId
+	}
+	return 0
+}
+
+type DeviceEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          EventType              `protobuf:"varint,2,opt,name=type,proto3,enum=device.v1.EventType" json:"type,omitempty"`
+	DeviceId      string                 `protobuf:"bytes,3,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Device        *Device                `protobuf:"bytes,4,opt,name=device,proto3" json:"device,omitempty"`
+	Previous      *DeviceSnapshot        `protobuf:"bytes,5,opt,name=previous,proto3" json:"previous,omitempty"`
+	Actor         string                 `protobuf:"bytes,6,opt,name=actor,proto3" json:"actor,omitempty"`
+	OccurredAt    *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeviceEvent) Reset() {
+	*x = DeviceEvent{}
+	mi := &file_device_v1_device_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceEvent) ProtoMessage() {}
+
+func (x *DeviceEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_device_v1_device_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceEvent.ProtoReflect.Descriptor instead.
+func (*DeviceEvent) Descriptor() ([]byte, []int) {
+	return file_device_v1_device_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *DeviceEvent) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *DeviceEvent) GetType() EventType {
+	if x != nil {
+		return x.Type
+	}
+	return EventType_EVENT_TYPE_UNSPECIFIED
+}
+
+func (x *DeviceEvent) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *DeviceEvent) GetDevice() *Device {
+	if x != nil {
+		return x.Device
+	}
+	return nil
+}
+
+func (x *DeviceEvent) GetPrevious() *DeviceSnapshot {
+	if x != nil {
+		return x.Previous
+	}
+	return nil
+}
+
+func (x *DeviceEvent) GetActor() string {
+	if x != nil {
+		return x.Actor
+	}
+	return ""
+}
+
+func (x *DeviceEvent) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+var File_device_v1_device_proto protoreflect.FileDescriptor
+
+const file_device_v1_device_proto_rawDesc = "" +
+	"\n" +
+	"\x16device/v1/device.proto\x12\tdevice.v1\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xa8\x03\n" +
+	"\x06Device\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05brand\x18\x03 \x01(\tR\x05brand\x12,\n" +
+	"\x05state\x18\x04 \x01(\x0e2\x16.device.v1.DeviceStateR\x05state\x12\x1a\n" +
+	"\bassignee\x18\x05 \x01(\tR\bassignee\x12@\n" +
+	"\x0echecked_out_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\fcheckedOutAt\x12H\n" +
+	"\x12expected_return_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\x10expectedReturnAt\x129\n" +
+	"\n" +
+	"created_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"deleted_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tdeletedAt\x12\x18\n" +
+	"\aversion\x18\n" +
+	" \x01(\x03R\aversion\"O\n" +
+	"\x13CreateDeviceRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05brand\x18\x03 \x01(\tR\x05brand\"K\n" +
+	"\x10GetDeviceRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12'\n" +
+	"\x0finclude_deleted\x18\x02 \x01(\bR\x0eincludeDeleted\"\x99\x03\n" +
+	"\x12ListDevicesRequest\x12\x16\n" +
+	"\x06brands\x18\x01 \x03(\tR\x06brands\x12.\n" +
+	"\x06states\x18\x02 \x03(\x0e2\x16.device.v1.DeviceStateR\x06states\x12#\n" +
+	"\rname_contains\x18\x03 \x01(\tR\fnameContains\x12=\n" +
+	"\fcreated_from\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\vcreatedFrom\x129\n" +
+	"\n" +
+	"created_to\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedTo\x12'\n" +
+	"\x0finclude_deleted\x18\x06 \x01(\bR\x0eincludeDeleted\x12\x12\n" +
+	"\x04sort\x18\a \x01(\tR\x04sort\x12\x1b\n" +
+	"\tpage_size\x18\b \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\t \x01(\tR\tpageToken\x12#\n" +
+	"\rinclude_total\x18\n" +
+	" \x01(\bR\fincludeTotal\"\x8f\x01\n" +
+	"\x13ListDevicesResponse\x12+\n" +
+	"\adevices\x18\x01 \x03(\v2\x11.device.v1.DeviceR\adevices\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\x12\x19\n" +
+	"\x05total\x18\x03 \x01(\x03H\x00R\x05total\x88\x01\x01B\b\n" +
+	"\x06_total\"\xc2\x01\n" +
+	"\x14ReplaceDeviceRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05brand\x18\x03 \x01(\tR\x05brand\x12,\n" +
+	"\x05state\x18\x04 \x01(\x0e2\x16.device.v1.DeviceStateR\x05state\x12-\n" +
+	"\x10if_match_version\x18\x05 \x01(\x03H\x00R\x0eifMatchVersion\x88\x01\x01B\x13\n" +
+	"\x11_if_match_version\"\xed\x01\n" +
+	"\x13UpdateDeviceRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\x04name\x18\x02 \x01(\tH\x00R\x04name\x88\x01\x01\x12\x19\n" +
+	"\x05brand\x18\x03 \x01(\tH\x01R\x05brand\x88\x01\x01\x121\n" +
+	"\x05state\x18\x04 \x01(\x0e2\x16.device.v1.DeviceStateH\x02R\x05state\x88\x01\x01\x12-\n" +
+	"\x10if_match_version\x18\x05 \x01(\x03H\x03R\x0eifMatchVersion\x88\x01\x01B\a\n" +
+	"\x05_nameB\b\n" +
+	"\x06_brandB\b\n" +
+	"\x06_stateB\x13\n" +
+	"\x11_if_match_version\"i\n" +
+	"\x13DeleteDeviceRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12-\n" +
+	"\x10if_match_version\x18\x02 \x01(\x03H\x00R\x0eifMatchVersion\x88\x01\x01B\x13\n" +
+	"\x11_if_match_version\"&\n" +
+	"\x14RestoreDeviceRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x8d\x01\n" +
+	"\x15CheckoutDeviceRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
+	"\bassignee\x18\x02 \x01(\tR\bassignee\x12H\n" +
+	"\x12expected_return_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x10expectedReturnAt\"&\n" +
+	"\x14CheckinDeviceRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"^\n" +
+	"\x17GetDeviceHistoryRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\"\x84\x01\n" +
+	"\x0eDeviceSnapshot\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05brand\x18\x02 \x01(\tR\x05brand\x12,\n" +
+	"\x05state\x18\x03 \x01(\x0e2\x16.device.v1.DeviceStateR\x05state\x12\x1a\n" +
+	"\bassignee\x18\x04 \x01(\tR\bassignee\"\xac\x02\n" +
+	"\fHistoryEntry\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x1b\n" +
+	"\tdevice_id\x18\x02 \x01(\tR\bdeviceId\x12,\n" +
+	"\x06action\x18\x03 \x01(\x0e2\x14.device.v1.EventTypeR\x06action\x126\n" +
+	"\told_value\x18\x04 \x01(\v2\x19.device.v1.DeviceSnapshotR\boldValue\x126\n" +
+	"\tnew_value\x18\x05 \x01(\v2\x19.device.v1.DeviceSnapshotR\bnewValue\x12\x14\n" +
+	"\x05actor\x18\x06 \x01(\tR\x05actor\x12;\n" +
+	"\voccurred_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAt\"c\n" +
+	"\x18GetDeviceHistoryResponse\x121\n" +
+	"\aentries\x18\x01 \x03(\v2\x17.device.v1.HistoryEntryR\aentries\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x03R\x05total\"\x1c\n" +
+	"\x1aPurgeDeletedDevicesRequest\"/\n" +
+	"\x1bPurgeDeletedDevicesResponse\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\tR\x03ids\"\xa2\x01\n" +
+	"\x13WatchDevicesRequest\x12\x16\n" +
+	"\x06brands\x18\x01 \x03(\tR\x06brands\x12.\n" +
+	"\x06states\x18\x02 \x03(\x0e2\x16.device.v1.DeviceStateR\x06states\x12\x1d\n" +
+	"\n" +
+	"device_ids\x18\x03 \x03(\tR\tdeviceIds\x12$\n" +
+	"\x0eafter_event_id\x18\x04 \x01(\x04R\fafterEventId\"\x99\x02\n" +
+	"\vDeviceEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12(\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x14.device.v1.EventTypeR\x04type\x12\x1b\n" +
+	"\tdevice_id\x18\x03 \x01(\tR\bdeviceId\x12)\n" +
+	"\x06device\x18\x04 \x01(\v2\x11.device.v1.DeviceR\x06device\x125\n" +
+	"\bprevious\x18\x05 \x01(\v2\x19.device.v1.DeviceSnapshotR\bprevious\x12\x14\n" +
+	"\x05actor\x18\x06 \x01(\tR\x05actor\x12;\n" +
+	"\voccurred_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAt*{\n" +
+	"\vDeviceState\x12\x1c\n" +
+	"\x18DEVICE_STATE_UNSPECIFIED\x10\x00\x12\x1a\n" +
+	"\x16DEVICE_STATE_AVAILABLE\x10\x01\x12\x17\n" +
+	"\x13DEVICE_STATE_IN_USE\x10\x02\x12\x19\n" +
+	"\x15DEVICE_STATE_INACTIVE\x10\x03*\xf4\x01\n" +
+	"\tEventType\x12\x1a\n" +
+	"\x16EVENT_TYPE_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12EVENT_TYPE_CREATED\x10\x01\x12\x16\n" +
+	"\x12EVENT_TYPE_UPDATED\x10\x02\x12\x1c\n" +
+	"\x18EVENT_TYPE_STATE_CHANGED\x10\x03\x12\x1a\n" +
+	"\x16EVENT_TYPE_CHECKED_OUT\x10\x04\x12\x19\n" +
+	"\x15EVENT_TYPE_CHECKED_IN\x10\x05\x12\x16\n" +
+	"\x12EVENT_TYPE_DELETED\x10\x06\x12\x17\n" +
+	"\x13EVENT_TYPE_RESTORED\x10\a\x12\x15\n" +
+	"\x11EVENT_TYPE_PURGED\x10\b2\x8b\a\n" +
+	"\rDeviceService\x12A\n" +
+	"\fCreateDevice\x12\x1e.device.v1.CreateDeviceRequest\x1a\x11.device.v1.Device\x12;\n" +
+	"\tGetDevice\x12\x1b.device.v1.GetDeviceRequest\x1a\x11.device.v1.Device\x12L\n" +
+	"\vListDevices\x12\x1d.device.v1.ListDevicesRequest\x1a\x1e.device.v1.ListDevicesResponse\x12C\n" +
+	"\rReplaceDevice\x12\x1f.device.v1.ReplaceDeviceRequest\x1a\x11.device.v1.Device\x12A\n" +
+	"\fUpdateDevice\x12\x1e.device.v1.UpdateDeviceRequest\x1a\x11.device.v1.Device\x12F\n" +
+	"\fDeleteDevice\x12\x1e.device.v1.DeleteDeviceRequest\x1a\x16.google.protobuf.Empty\x12C\n" +
+	"\rRestoreDevice\x12\x1f.device.v1.RestoreDeviceRequest\x1a\x11.device.v1.Device\x12E\n" +
+	"\x0eCheckoutDevice\x12 .device.v1.CheckoutDeviceRequest\x1a\x11.device.v1.Device\x12C\n" +
+	"\rCheckinDevice\x12\x1f.device.v1.CheckinDeviceRequest\x1a\x11.device.v1.Device\x12[\n" +
+	"\x10GetDeviceHistory\x12\".device.v1.GetDeviceHistoryRequest\x1a#.device.v1.GetDeviceHistoryResponse\x12d\n" +
+	"\x13PurgeDeletedDevices\x12%.device.v1.PurgeDeletedDevicesRequest\x1a&.device.v1.PurgeDeletedDevicesResponse\x12H\n" +
+	"\fWatchDevices\x12\x1e.device.v1.WatchDevicesRequest\x1a\x16.device.v1.DeviceEvent0\x01B/Z-device-api/internal/grpcapi/devicev1;devicev1b\x06proto3"
+
+var (
+	file_device_v1_device_proto_rawDescOnce sync.Once
+	file_device_v1_device_proto_rawDescData []byte
+)
+
+func file_device_v1_device_proto_rawDescGZIP() []byte {
+	file_device_v1_device_proto_rawDescOnce.Do(func() {
+		file_device_v1_device_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_device_v1_device_proto_rawDesc), len(file_device_v1_device_proto_rawDesc)))
+	})
+	return file_device_v1_device_proto_rawDescData
+}
+
+var file_device_v1_device_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_device_v1_device_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
+var file_device_v1_device_proto_goTypes = []any{
+	(DeviceState)(0),                    // 0: device.v1.DeviceState
+	(EventType)(0),                      // 1: device.v1.EventType
+	(*Device)(nil),                      // 2: device.v1.Device
+	(*CreateDeviceRequest)(nil),         // 3: device.v1.CreateDeviceRequest
+	(*GetDeviceRequest)(nil),            // 4: device.v1.GetDeviceRequest
+	(*ListDevicesRequest)(nil),          // 5: device.v1.ListDevicesRequest
+	(*ListDevicesResponse)(nil),         // 6: device.v1.ListDevicesResponse
+	(*ReplaceDeviceRequest)(nil),        // 7: device.v1.ReplaceDeviceRequest
+	(*UpdateDeviceRequest)(nil),         // 8: device.v1.UpdateDeviceRequest
+	(*DeleteDeviceRequest)(nil),         // 9: device.v1.DeleteDeviceRequest
+	(*RestoreDeviceRequest)(nil),        // 10: device.v1.RestoreDeviceRequest
+	(*CheckoutDeviceRequest)(nil),       // 11: device.v1.CheckoutDeviceRequest
+	(*CheckinDeviceRequest)(nil),        // 12: device.v1.CheckinDeviceRequest
+	(*GetDeviceHistoryRequest)(nil),     // 13: device.v1.GetDeviceHistoryRequest
+	(*DeviceSnapshot)(nil),              // 14: device.v1.DeviceSnapshot
+	(*HistoryEntry)(nil),                // 15: device.v1.HistoryEntry
+	(*GetDeviceHistoryResponse)(nil),    // 16: device.v1.GetDeviceHistoryResponse
+	(*PurgeDeletedDevicesRequest)(nil),  // 17: device.v1.PurgeDeletedDevicesRequest
+	(*PurgeDeletedDevicesResponse)(nil), // 18: device.v1.PurgeDeletedDevicesResponse
+	(*WatchDevicesRequest)(nil),         // 19: device.v1.WatchDevicesRequest
+	(*DeviceEvent)(nil),                 // 20: device.v1.DeviceEvent
+	(*timestamppb.Timestamp)(nil),       // 21: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),               // 22: google.protobuf.Empty
+}
+var file_device_v1_device_proto_depIdxs = []int32{
+	0,  // 0: device.v1.Device.state:type_name -> device.v1.DeviceState
+	21, // 1: device.v1.Device.checked_out_at:type_name -> google.protobuf.Timestamp
+	21, // 2: device.v1.Device.expected_return_at:type_name -> google.protobuf.Timestamp
+	21, // 3: device.v1.Device.created_at:type_name -> google.protobuf.Timestamp
+	21, // 4: device.v1.Device.deleted_at:type_name -> google.protobuf.Timestamp
+	0,  // 5: device.v1.ListDevicesRequest.states:type_name -> device.v1.DeviceState
+	21, // 6: device.v1.ListDevicesRequest.created_from:type_name -> google.protobuf.Timestamp
+	21, // 7: device.v1.ListDevicesRequest.created_to:type_name -> google.protobuf.Timestamp
+	2,  // 8: device.v1.ListDevicesResponse.devices:type_name -> device.v1.Device
+	0,  // 9: device.v1.ReplaceDeviceRequest.state:type_name -> device.v1.DeviceState
+	0,  // 10: device.v1.UpdateDeviceRequest.state:type_name -> device.v1.DeviceState
+	21, // 11: device.v1.CheckoutDeviceRequest.expected_return_at:type_name -> google.protobuf.Timestamp
+	0,  // 12: device.v1.DeviceSnapshot.state:type_name -> device.v1.DeviceState
+	1,  // 13: device.v1.HistoryEntry.action:type_name -> device.v1.EventType
+	14, // 14: device.v1.HistoryEntry.old_value:type_name -> device.v1.DeviceSnapshot
+	14, // 15: device.v1.HistoryEntry.new_value:type_name -> device.v1.DeviceSnapshot
+	21, // 16: device.v1.HistoryEntry.occurred_at:type_name -> google.protobuf.Timestamp
+	15, // 17: device.v1.GetDeviceHistoryResponse.entries:type_name -> device.v1.HistoryEntry
+	0,  // 18: device.v1.WatchDevicesRequest.states:type_name -> device.v1.DeviceState
+	1,  // 19: device.v1.DeviceEvent.type:type_name -> device.v1.EventType
+	2,  // 20: device.v1.DeviceEvent.device:type_name -> device.v1.Device
+	14, // 21: device.v1.DeviceEvent.previous:type_name -> device.v1.DeviceSnapshot
+	21, // 22: device.v1.DeviceEvent.occurred_at:type_name -> google.protobuf.Timestamp
+	3,  // 23: device.v1.DeviceService.CreateDevice:input_type -> device.v1.CreateDeviceRequest
+	4,  // 24: device.v1.DeviceService.GetDevice:input_type -> device.v1.GetDeviceRequest
+	5,  // 25: device.v1.DeviceService.ListDevices:input_type -> device.v1.ListDevicesRequest
+	7,  // 26: device.v1.DeviceService.ReplaceDevice:input_type -> device.v1.ReplaceDeviceRequest
+	8,  // 27: device.v1.DeviceService.UpdateDevice:input_type -> device.v1.UpdateDeviceRequest
+	9,  // 28: device.v1.DeviceService.DeleteDevice:input_type -> device.v1.DeleteDeviceRequest
+	10, // 29: device.v1.DeviceService.RestoreDevice:input_type -> device.v1.RestoreDeviceRequest
+	11, // 30: device.v1.DeviceService.CheckoutDevice:input_type -> device.v1.CheckoutDeviceRequest
+	12, // 31: device.v1.DeviceService.CheckinDevice:input_type -> device.v1.CheckinDeviceRequest
+	13, // 32: device.v1.DeviceService.GetDeviceHistory:input_type -> device.v1.GetDeviceHistoryRequest
+	17, // 33: device.v1.DeviceService.PurgeDeletedDevices:input_type -> device.v1.PurgeDeletedDevicesRequest
+	19, // 34: device.v1.DeviceService.WatchDevices:input_type -> device.v1.WatchDevicesRequest
+	2,  // 35: device.v1.DeviceService.CreateDevice:output_type -> device.v1.Device
+	2,  // 36: device.v1.DeviceService.GetDevice:output_type -> device.v1.Device
+	6,  // 37: device.v1.DeviceService.ListDevices:output_type -> device.v1.ListDevicesResponse
+	2,  // 38: device.v1.DeviceService.ReplaceDevice:output_type -> device.v1.Device
+	2,  // 39: device.v1.DeviceService.UpdateDevice:output_type -> device.v1.Device
+	22, // 40: device.v1.DeviceService.DeleteDevice:output_type -> google.protobuf.Empty
+	2,  // 41: device.v1.DeviceService.RestoreDevice:output_type -> device.v1.Device
+	2,  // 42: device.v1.DeviceService.CheckoutDevice:output_type -> device.v1.Device
+	2,  // 43: device.v1.DeviceService.CheckinDevice:output_type -> device.v1.Device
+	16, // 44: device.v1.DeviceService.GetDeviceHistory:output_type -> device.v1.GetDeviceHistoryResponse
+	18, // 45: device.v1.DeviceService.PurgeDeletedDevices:output_type -> device.v1.PurgeDeletedDevicesResponse
+	20, // 46: device.v1.DeviceService.WatchDevices:output_type -> device.v1.DeviceEvent
+	35, // [35:47] is the sub-list for method output_type
+	23, // [23:35] is the sub-list for method input_type
+	23, // [23:23] is the sub-list for extension type_name
+	23, // [23:23] is the sub-list for extension extendee
+	0,  // [0:23] is the sub-list for field type_name
+}
+
+func init() { file_device_v1_device_proto_init() }
+func file_device_v1_device_proto_init() {
+	if File_device_v1_device_proto != nil {
+		return
+	}
+	file_device_v1_device_proto_msgTypes[4].OneofWrappers = []any{}
+	file_device_v1_device_proto_msgTypes[5].OneofWrappers = []any{}
+	file_device_v1_device_proto_msgTypes[6].OneofWrappers = []any{}
+	file_device_v1_device_proto_msgTypes[7].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_device_v1_device_proto_rawDesc), len(file_device_v1_device_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   19,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_device_v1_device_proto_goTypes,
+		DependencyIndexes: file_device_v1_device_proto_depIdxs,
+		EnumInfos:         file_device_v1_device_proto_enumTypes,
+		MessageInfos:      file_device_v1_device_proto_msgTypes,
+	}.Build()
+	File_device_v1_device_proto = out.File
+	file_device_v1_device_proto_goTypes = nil
+	file_device_v1_device_proto_depIdxs = nil
+}
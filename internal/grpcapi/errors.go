@@ -0,0 +1,86 @@
+package grpcapi
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain is the domain of the ErrorInfo details attached to errors.
+const errorDomain = "device-api"
+
+type statusMapping struct {
+	err    error
+	code   codes.Code
+	reason string
+}
+
+// statusMappings maps domain errors to gRPC codes, following the status
+// codes of the REST API: 404 is NotFound, 409 conflicts on creation are
+// AlreadyExists and concurrent writes Aborted, 400 is InvalidArgument and
+// 412 and 422 rule violations are FailedPrecondition. Requests that ended
+// before they were answered report Canceled or DeadlineExceeded.
+var statusMappings = []statusMapping{
+	{domain.ErrDeviceNotFound, codes.NotFound, "DEVICE_NOT_FOUND"},
+	{domain.ErrDeviceAlreadyExists, codes.AlreadyExists, "DEVICE_ALREADY_EXISTS"},
+	{domain.ErrDeviceIDRequired, codes.InvalidArgument, "DEVICE_ID_REQUIRED"},
+	{domain.ErrClientIDNotAllowed, codes.InvalidArgument, "CLIENT_ID_NOT_ALLOWED"},
+	{domain.ErrDeviceIDReserved, codes.InvalidArgument, "DEVICE_ID_RESERVED"},
+	{domain.ErrDeviceNameRequired, codes.InvalidArgument, "DEVICE_NAME_REQUIRED"},
+	{domain.ErrDeviceBrandRequired, codes.InvalidArgument, "DEVICE_BRAND_REQUIRED"},
+	{domain.ErrImmutableField, codes.InvalidArgument, "IMMUTABLE_FIELD"},
+	{domain.ErrInvalidFilter, codes.InvalidArgument, "INVALID_FILTER"},
+	{domain.ErrInvalidSort, codes.InvalidArgument, "INVALID_SORT"},
+	{domain.ErrInvalidCursor, codes.InvalidArgument, "INVALID_CURSOR"},
+	{domain.ErrAssigneeRequired, codes.InvalidArgument, "ASSIGNEE_REQUIRED"},
+	{domain.ErrInvalidReturnTime, codes.InvalidArgument, "INVALID_RETURN_TIME"},
+	{domain.ErrDeviceInUse, codes.FailedPrecondition, "DEVICE_IN_USE"},
+	{domain.ErrDeviceInactive, codes.FailedPrecondition, "DEVICE_INACTIVE"},
+	{domain.ErrDeviceNotCheckedOut, codes.FailedPrecondition, "DEVICE_NOT_CHECKED_OUT"},
+	{domain.ErrDeviceNotDeleted, codes.FailedPrecondition, "DEVICE_NOT_DELETED"},
+	{domain.ErrDeviceReserved, codes.FailedPrecondition, "DEVICE_RESERVED"},
+	{domain.ErrPreconditionFailed, codes.FailedPrecondition, "PRECONDITION_FAILED"},
+	{domain.ErrVersionConflict, codes.Aborted, "VERSION_CONFLICT"},
+	{context.Canceled, codes.Canceled, "CANCELLED"},
+	{context.DeadlineExceeded, codes.DeadlineExceeded, "DEADLINE_EXCEEDED"},
+}
+
+// statusError converts err into a gRPC status error. Known domain errors
+// keep their message and carry an ErrorInfo whose reason names the error,
+// e.g. DEVICE_IN_USE; the messages of unexpected errors are not exposed.
+func statusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var transitionErr *domain.StateTransitionError
+	if errors.As(err, &transitionErr) {
+		code := codes.FailedPrecondition
+		if !transitionErr.To.IsValid() {
+			code = codes.InvalidArgument
+		}
+		return withReason(code, err.Error(), "INVALID_DEVICE_STATE")
+	}
+	for _, m := range statusMappings {
+		if errors.Is(err, m.err) {
+			return withReason(m.code, err.Error(), m.reason)
+		}
+	}
+	return status.Error(codes.Internal, "an unexpected error occurred")
+}
+
+func withReason(code codes.Code, message, reason string) error {
+	st := status.New(code, message)
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
@@ -0,0 +1,49 @@
+package grpcapi
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		err    error
+		code   codes.Code
+		reason string
+	}{
+		{domain.ErrDeviceNotFound, codes.NotFound, "DEVICE_NOT_FOUND"},
+		{fmt.Errorf("saving: %w", domain.ErrDeviceAlreadyExists), codes.AlreadyExists, "DEVICE_ALREADY_EXISTS"},
+		{domain.ErrDeviceInUse, codes.FailedPrecondition, "DEVICE_IN_USE"},
+		{domain.ErrVersionConflict, codes.Aborted, "VERSION_CONFLICT"},
+		{fmt.Errorf("finding device: %w", context.DeadlineExceeded), codes.DeadlineExceeded, "DEADLINE_EXCEEDED"},
+		{&domain.StateTransitionError{From: domain.DeviceStateInactive, To: domain.DeviceStateInUse}, codes.FailedPrecondition, "INVALID_DEVICE_STATE"},
+		{&domain.StateTransitionError{To: "banana"}, codes.InvalidArgument, "INVALID_DEVICE_STATE"},
+		{&domain.FilterError{Field: "state", Value: "banana"}, codes.InvalidArgument, "INVALID_FILTER"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reason, func(t *testing.T) {
+			st := status.Convert(statusError(tt.err))
+			assert.Equal(t, tt.code, st.Code())
+			assert.Equal(t, tt.err.Error(), st.Message())
+			if assert.Len(t, st.Details(), 1) {
+				info := st.Details()[0].(*errdetails.ErrorInfo)
+				assert.Equal(t, tt.reason, info.Reason)
+				assert.Equal(t, errorDomain, info.Domain)
+			}
+		})
+	}
+
+	st := status.Convert(statusError(errors.New("connection refused")))
+	assert.Equal(t, codes.Internal, st.Code())
+	assert.NotContains(t, st.Message(), "connection refused")
+
+	assert.NoError(t, statusError(nil))
+}
@@ -0,0 +1,224 @@
+// Package grpcapi serves the device service over gRPC. The protobuf schema
+// lives in proto/device/v1; regenerate the code in devicev1 after changing it.
+package grpcapi
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=device-api --go-grpc_out=../.. --go-grpc_opt=module=device-api device/v1/device.proto
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"device-api/internal/events"
+	pb "device-api/internal/grpcapi/devicev1"
+	"device-api/internal/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// ActorMetadataKey names the metadata identifying who performs a change.
+const ActorMetadataKey = "x-actor"
+
+const (
+	defaultHistoryPageSize = 50
+	maxHistoryPageSize     = 200
+)
+
+type Server struct {
+	pb.UnimplementedDeviceServiceServer
+	service *service.DeviceService
+	broker  *events.Broker
+}
+
+func NewServer(s *service.DeviceService, broker *events.Broker) *Server {
+	return &Server{service: s, broker: broker}
+}
+
+// Register installs the server on g.
+func (s *Server) Register(g *grpc.Server) {
+	pb.RegisterDeviceServiceServer(g, s)
+}
+
+// serviceFor returns the device service scoped to the actor of the call
+// and, when set, to the version the change is conditional on.
+func (s *Server) serviceFor(ctx context.Context, ifMatch *int64) *service.DeviceService {
+	actor := "anonymous"
+	if values := metadata.ValueFromIncomingContext(ctx, ActorMetadataKey); len(values) > 0 && values[0] != "" {
+		actor = values[0]
+	}
+	svc := s.service.As(actor)
+	if ifMatch != nil {
+		svc = svc.IfMatch(*ifMatch)
+	}
+	return svc
+}
+
+func (s *Server) CreateDevice(ctx context.Context, req *pb.CreateDeviceRequest) (*pb.Device, error) {
+	device, err := s.serviceFor(ctx, nil).CreateDevice(ctx, req.GetId(), req.GetName(), req.GetBrand())
+	return toProtoDevice(device), statusError(err)
+}
+
+func (s *Server) GetDevice(ctx context.Context, req *pb.GetDeviceRequest) (*pb.Device, error) {
+	svc := s.service
+	if req.GetIncludeDeleted() {
+		svc = svc.IncludingDeleted()
+	}
+	device, err := svc.GetDevice(ctx, req.GetId())
+	return toProtoDevice(device), statusError(err)
+}
+
+func (s *Server) ListDevices(ctx context.Context, req *pb.ListDevicesRequest) (*pb.ListDevicesResponse, error) {
+	sort, err := domain.ParseDeviceSort(req.GetSort())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	if req.GetPageSize() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	}
+	query := domain.DeviceQuery{
+		Criteria: domain.DeviceCriteria{
+			Brands:       req.GetBrands(),
+			States:       statesFromProto(req.GetStates()),
+			NameContains: req.GetNameContains(),
+			CreatedFrom:  fromTimestamp(req.GetCreatedFrom()),
+			CreatedTo:    fromTimestamp(req.GetCreatedTo()),
+		},
+		Sort:         sort,
+		Limit:        int(req.GetPageSize()),
+		Cursor:       req.GetPageToken(),
+		IncludeTotal: req.GetIncludeTotal(),
+	}
+
+	svc := s.service
+	if req.GetIncludeDeleted() {
+		svc = svc.IncludingDeleted()
+	}
+	page, err := svc.ListDevices(ctx, query)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	resp := &pb.ListDevicesResponse{NextPageToken: page.NextCursor, Total: page.Total}
+	for _, device := range page.Items {
+		resp.Devices = append(resp.Devices, toProtoDevice(device))
+	}
+	return resp, nil
+}
+
+func (s *Server) ReplaceDevice(ctx context.Context, req *pb.ReplaceDeviceRequest) (*pb.Device, error) {
+	device, err := s.serviceFor(ctx, req.IfMatchVersion).ReplaceDevice(ctx, req.GetId(), domain.DeviceAttributes{
+		Name:  req.GetName(),
+		Brand: req.GetBrand(),
+		State: stateFromProto(req.GetState()),
+	})
+	return toProtoDevice(device), statusError(err)
+}
+
+func (s *Server) UpdateDevice(ctx context.Context, req *pb.UpdateDeviceRequest) (*pb.Device, error) {
+	device, err := s.serviceFor(ctx, req.IfMatchVersion).ModifyDevice(ctx, req.GetId(), func(attrs *domain.DeviceAttributes) error {
+		if req.Name != nil {
+			attrs.Name = req.GetName()
+		}
+		if req.Brand != nil {
+			attrs.Brand = req.GetBrand()
+		}
+		if req.State != nil {
+			attrs.State = stateFromProto(req.GetState())
+		}
+		return nil
+	})
+	return toProtoDevice(device), statusError(err)
+}
+
+func (s *Server) DeleteDevice(ctx context.Context, req *pb.DeleteDeviceRequest) (*emptypb.Empty, error) {
+	if err := s.serviceFor(ctx, req.IfMatchVersion).DeleteDevice(ctx, req.GetId()); err != nil {
+		return nil, statusError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) RestoreDevice(ctx context.Context, req *pb.RestoreDeviceRequest) (*pb.Device, error) {
+	device, err := s.serviceFor(ctx, nil).RestoreDevice(ctx, req.GetId())
+	return toProtoDevice(device), statusError(err)
+}
+
+func (s *Server) CheckoutDevice(ctx context.Context, req *pb.CheckoutDeviceRequest) (*pb.Device, error) {
+	device, err := s.serviceFor(ctx, nil).CheckoutDevice(ctx, req.GetId(), req.GetAssignee(), fromTimestamp(req.GetExpectedReturnAt()))
+	return toProtoDevice(device), statusError(err)
+}
+
+func (s *Server) CheckinDevice(ctx context.Context, req *pb.CheckinDeviceRequest) (*pb.Device, error) {
+	device, err := s.serviceFor(ctx, nil).CheckinDevice(ctx, req.GetId())
+	return toProtoDevice(device), statusError(err)
+}
+
+func (s *Server) GetDeviceHistory(ctx context.Context, req *pb.GetDeviceHistoryRequest) (*pb.GetDeviceHistoryResponse, error) {
+	pageSize := int(req.GetPageSize())
+	if pageSize == 0 {
+		pageSize = defaultHistoryPageSize
+	}
+	if pageSize < 0 || pageSize > maxHistoryPageSize || req.GetOffset() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "page_size must be between 1 and %d and offset must not be negative", maxHistoryPageSize)
+	}
+
+	entries, total, err := s.service.DeviceHistory(ctx, req.GetId(), pageSize, int(req.GetOffset()))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	resp := &pb.GetDeviceHistoryResponse{Total: total}
+	for _, entry := range entries {
+		resp.Entries = append(resp.Entries, toProtoHistoryEntry(entry))
+	}
+	return resp, nil
+}
+
+func (s *Server) PurgeDeletedDevices(ctx context.Context, req *pb.PurgeDeletedDevicesRequest) (*pb.PurgeDeletedDevicesResponse, error) {
+	ids, err := s.serviceFor(ctx, nil).PurgeDeletedDevices(ctx)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &pb.PurgeDeletedDevicesResponse{Ids: ids}, nil
+}
+
+// WatchDevices streams the events of the broker matching the request until
+// the client goes away. Response headers are sent as soon as the
+// subscription is in place, so a client that waits for them misses no
+// later event. A client that falls too far behind is disconnected with
+// Unavailable and should resume with after_event_id.
+func (s *Server) WatchDevices(req *pb.WatchDevicesRequest, stream grpc.ServerStreamingServer[pb.DeviceEvent]) error {
+	filter := events.Filter{
+		Brands:    req.GetBrands(),
+		States:    statesFromProto(req.GetStates()),
+		DeviceIDs: req.GetDeviceIds(),
+	}
+	if err := (domain.DeviceCriteria{States: filter.States}).Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	sub, missed := s.broker.Subscribe(filter, req.GetAfterEventId())
+	defer s.broker.Unsubscribe(sub)
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	for _, event := range missed {
+		if err := stream.Send(toProtoEvent(event)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-sub.C:
+			if !ok {
+				return status.Error(codes.Unavailable, "subscriber fell behind; resume with after_event_id")
+			}
+			if err := stream.Send(toProtoEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
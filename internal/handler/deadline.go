@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// StatusClientClosedRequest is the nginx convention for requests the client
+// gave up on before they were answered. Nobody reads the response; the
+// status is there for the request log.
+const StatusClientClosedRequest = 499
+
+// DefaultRequestTimeout bounds routes without a timeout of their own.
+const DefaultRequestTimeout = 30 * time.Second
+
+// Timeouts sets how long requests may take, per route. Routes are named by
+// method and path pattern as registered, e.g. "GET /api/v1/devices/:id". A
+// zero timeout leaves requests to the route unbounded.
+type Timeouts struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+// DefaultTimeouts gives exports, imports and batches more time than the
+// default and leaves the event streams unbounded.
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Default: DefaultRequestTimeout,
+		Routes: map[string]time.Duration{
+			"GET /api/v1/devices/export":    5 * time.Minute,
+			"POST /api/v1/devices:method":   5 * time.Minute,
+			"GET /api/v1/devices/events":    0,
+			"GET /api/v1/devices/events/ws": 0,
+		},
+	}
+}
+
+// ParseRouteTimeouts reads a comma separated list of route timeouts such as
+// "GET /api/v1/devices/export=10m,POST /api/v1/devices:method=0" into
+// t.Routes, overriding the routes it names.
+func (t *Timeouts) ParseRouteTimeouts(s string) error {
+	if t.Routes == nil {
+		t.Routes = map[string]time.Duration{}
+	}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndex(entry, "=")
+		if i < 0 {
+			return fmt.Errorf("want 'METHOD /path=duration', got %q", entry)
+		}
+		timeout, err := time.ParseDuration(entry[i+1:])
+		if err != nil {
+			return fmt.Errorf("route %q: %w", entry[:i], err)
+		}
+		t.Routes[strings.TrimSpace(entry[:i])] = timeout
+	}
+	return nil
+}
+
+func (t Timeouts) timeoutFor(method, path string) time.Duration {
+	if timeout, ok := t.Routes[method+" "+path]; ok {
+		return timeout
+	}
+	return t.Default
+}
+
+// Deadlines bounds the context of every request by the timeout of its
+// route. Handlers pass the context down to the database, so a request that
+// runs out of time fails with context.DeadlineExceeded and is answered with
+// 504 Gateway Timeout; one whose client went away fails with
+// context.Canceled and is logged as 499. WebSocket upgrades are never
+// bounded: the connection outlives the request.
+func Deadlines(timeouts Timeouts) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := timeouts.timeoutFor(c.Request.Method, c.FullPath())
+		if timeout <= 0 || websocket.IsWebSocketUpgrade(c.Request) {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
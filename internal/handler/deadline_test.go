@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadlines(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Deadlines(Timeouts{
+		Default: 10 * time.Millisecond,
+		Routes:  map[string]time.Duration{"GET /stream": 0},
+	}), RequestID(), Problems())
+	r.GET("/slow", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		// Drivers may fail with an error that does not wrap the context's.
+		c.Error(errors.New("query interrupted"))
+	})
+	r.GET("/stream", func(c *gin.Context) {
+		_, bounded := c.Request.Context().Deadline()
+		c.JSON(http.StatusOK, gin.H{"bounded": bounded})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"timeout"`)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"bounded": false}`, w.Body.String())
+}
+
+func TestParseRouteTimeouts(t *testing.T) {
+	timeouts := DefaultTimeouts()
+	assert.NoError(t, timeouts.ParseRouteTimeouts("GET /api/v1/devices/export=10m, GET /api/v1/devices/:id=2s"))
+	assert.Equal(t, 10*time.Minute, timeouts.timeoutFor(http.MethodGet, "/api/v1/devices/export"))
+	assert.Equal(t, 2*time.Second, timeouts.timeoutFor(http.MethodGet, "/api/v1/devices/:id"))
+	assert.Equal(t, DefaultRequestTimeout, timeouts.timeoutFor(http.MethodGet, "/api/v1/devices"))
+	assert.Equal(t, time.Duration(0), timeouts.timeoutFor(http.MethodGet, "/api/v1/devices/events"))
+
+	assert.Error(t, timeouts.ParseRouteTimeouts("GET /api/v1/devices"))
+	assert.Error(t, timeouts.ParseRouteTimeouts("GET /api/v1/devices=soon"))
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"device-api/internal/domain"
+	"device-api/internal/service"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceCollectionMethod dispatches the custom methods of the device
+// collection, such as POST /devices:batchCreate.
+func (h *DeviceHandler) DeviceCollectionMethod(c *gin.Context) {
+	switch strings.TrimPrefix(c.Param("method"), ":") {
+	case "batchCreate":
+		h.BatchCreateDevices(c)
+	case "batchUpdate":
+		h.BatchUpdateDevices(c)
+	case "batchDelete":
+		h.BatchDeleteDevices(c)
+	case "import":
+		h.ImportDevices(c)
+	default:
+		c.Error(errUnknownMethod)
+	}
+}
+
+// BatchCreateDevices godoc
+// @Summary Create devices in bulk
+// @Description Create up to 1000 devices. In atomic mode (the default) either all devices are created or none; in best_effort mode every valid item is created. Each item gets its own result.
+// @Tags devices
+// @Accept  json
+// @Produce  json
+// @Param batch body BatchCreateRequest true "Devices to create"
+// @Success 200 {object} BatchResponse "Every item succeeded"
+// @Success 207 {object} BatchResponse "At least one item failed"
+// @Failure 400 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /devices:batchCreate [post]
+func (h *DeviceHandler) BatchCreateDevices(c *gin.Context) {
+	var req BatchCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(badRequest(err))
+		return
+	}
+
+	items := make([]service.DeviceCreate, len(req.Items))
+	ids := make([]string, len(req.Items))
+	for i, item := range req.Items {
+		items[i] = service.DeviceCreate{ID: item.ID, Name: item.Name, Brand: item.Brand}
+		ids[i] = item.ID
+	}
+	results, err := h.serviceFor(c).CreateDevices(c.Request.Context(), items, batchMode(req.Mode))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	writeBatch(c, req.Mode, ids, results, http.StatusCreated)
+}
+
+// BatchUpdateDevices godoc
+// @Summary Update devices in bulk
+// @Description Change the name, brand or state of up to 1000 devices. Omitted fields are left as they are and version, when given, must match the current device version. In atomic mode (the default) either all devices are updated or none; in best_effort mode every valid item is applied.
+// @Tags devices
+// @Accept  json
+// @Produce  json
+// @Param batch body BatchUpdateRequest true "Device changes"
+// @Success 200 {object} BatchResponse "Every item succeeded"
+// @Success 207 {object} BatchResponse "At least one item failed"
+// @Failure 400 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /devices:batchUpdate [post]
+func (h *DeviceHandler) BatchUpdateDevices(c *gin.Context) {
+	var req BatchUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(badRequest(err))
+		return
+	}
+
+	items := make([]service.DeviceUpdate, len(req.Items))
+	ids := make([]string, len(req.Items))
+	for i, item := range req.Items {
+		items[i] = service.DeviceUpdate{
+			ID:      item.ID,
+			Version: item.Version,
+			Change: func(attrs *domain.DeviceAttributes) error {
+				if item.Name != nil {
+					attrs.Name = *item.Name
+				}
+				if item.Brand != nil {
+					attrs.Brand = *item.Brand
+				}
+				if item.State != nil {
+					attrs.State = domain.DeviceState(*item.State)
+				}
+				return nil
+			},
+		}
+		ids[i] = item.ID
+	}
+	results, err := h.serviceFor(c).UpdateDevices(c.Request.Context(), items, batchMode(req.Mode))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	writeBatch(c, req.Mode, ids, results, http.StatusOK)
+}
+
+// BatchDeleteDevices godoc
+// @Summary Delete devices in bulk
+// @Description Soft delete up to 1000 devices. version, when given, must match the current device version. In atomic mode (the default) either all devices are deleted or none; in best_effort mode every valid item is deleted.
+// @Tags devices
+// @Accept  json
+// @Produce  json
+// @Param batch body BatchDeleteRequest true "Devices to delete"
+// @Success 200 {object} BatchResponse "Every item succeeded"
+// @Success 207 {object} BatchResponse "At least one item failed"
+// @Failure 400 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /devices:batchDelete [post]
+func (h *DeviceHandler) BatchDeleteDevices(c *gin.Context) {
+	var req BatchDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(badRequest(err))
+		return
+	}
+
+	items := make([]service.DeviceDelete, len(req.Items))
+	ids := make([]string, len(req.Items))
+	for i, item := range req.Items {
+		items[i] = service.DeviceDelete{ID: item.ID, Version: item.Version}
+		ids[i] = item.ID
+	}
+	results, err := h.serviceFor(c).DeleteDevices(c.Request.Context(), items, batchMode(req.Mode))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	writeBatch(c, req.Mode, ids, results, http.StatusNoContent)
+}
+
+func batchMode(mode string) service.BatchMode {
+	if mode == "" {
+		return service.BatchAtomic
+	}
+	return service.BatchMode(mode)
+}
+
+// writeBatch answers a batch with one result per item. Successful items get
+// successStatus, failed ones the status and problem their error maps to.
+func writeBatch(c *gin.Context, mode string, ids []string, results []service.BatchResult, successStatus int) {
+	resp := BatchResponse{
+		Mode:    string(batchMode(mode)),
+		Results: make([]BatchItemResult, len(results)),
+	}
+	for i, result := range results {
+		item := BatchItemResult{Index: i, ID: ids[i], Status: successStatus}
+		if result.Err != nil {
+			problem := problemFor(result.Err)
+			item.Status = problem.Status
+			item.Error = &problem
+			resp.Failed++
+		} else {
+			item.Device = result.Device
+			resp.Succeeded++
+		}
+		if result.Device != nil {
+			item.ID = result.Device.ID
+		}
+		resp.Results[i] = item
+	}
+
+	status := http.StatusOK
+	if resp.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, resp)
+}
+
+type BatchCreateRequest struct {
+	// Mode is atomic (the default) or best_effort.
+	Mode  string                `json:"mode" enums:"atomic,best_effort"`
+	Items []CreateDeviceRequest `json:"items" binding:"required"`
+}
+
+type BatchUpdateItem struct {
+	ID      string  `json:"id"`
+	Version int64   `json:"version,omitempty"`
+	Name    *string `json:"name,omitempty"`
+	Brand   *string `json:"brand,omitempty"`
+	State   *string `json:"state,omitempty"`
+}
+
+type BatchUpdateRequest struct {
+	Mode  string            `json:"mode" enums:"atomic,best_effort"`
+	Items []BatchUpdateItem `json:"items" binding:"required"`
+}
+
+type BatchDeleteItem struct {
+	ID      string `json:"id"`
+	Version int64  `json:"version,omitempty"`
+}
+
+type BatchDeleteRequest struct {
+	Mode  string            `json:"mode" enums:"atomic,best_effort"`
+	Items []BatchDeleteItem `json:"items" binding:"required"`
+}
+
+type BatchResponse struct {
+	Mode      string            `json:"mode"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []BatchItemResult `json:"results"`
+}
+
+type BatchItemResult struct {
+	Index  int            `json:"index"`
+	ID     string         `json:"id,omitempty"`
+	Status int            `json:"status"`
+	Device *domain.Device `json:"device,omitempty"`
+	Error  *Problem       `json:"error,omitempty"`
+}
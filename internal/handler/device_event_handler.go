@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"device-api/internal/domain"
+	"device-api/internal/events"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// heartbeatInterval is how often an idle event stream is pinged so that
+// proxies keep the connection open and dead clients are noticed.
+const heartbeatInterval = 15 * time.Second
+
+// LastEventIDHeader is sent by reconnecting EventSource clients.
+const LastEventIDHeader = "Last-Event-ID"
+
+var errInvalidLastEventID = errors.New("last event ID must be a non-negative integer")
+
+type EventHandler struct {
+	broker   *events.Broker
+	upgrader websocket.Upgrader
+}
+
+func NewEventHandler(broker *events.Broker) *EventHandler {
+	return &EventHandler{broker: broker}
+}
+
+func RegisterEventRoutes(r *gin.Engine, handler *EventHandler) {
+	api := r.Group("/api/v1")
+	{
+		api.GET("/devices/events", handler.StreamEvents)
+		api.GET("/devices/events/ws", handler.StreamEventsWebSocket)
+	}
+}
+
+// StreamEvents godoc
+// @Summary Stream device events
+// @Description Stream created, updated, state_changed, checked_out, checked_in, deleted, restored and purged events as Server-Sent Events. Each event carries its ID, so a client that reconnects with Last-Event-ID receives the events it missed while they are still retained.
+// @Tags events
+// @Produce  text/event-stream
+// @Param brand query string false "Brand filter, e.g. Apple,Google"
+// @Param state query string false "State filter (available, in-use, inactive), e.g. available,in-use"
+// @Param id query string false "Device ID filter, e.g. DEV-000001,DEV-000002"
+// @Param last_event_id query int false "Resume after this event; the Last-Event-ID header takes precedence"
+// @Param Last-Event-ID header int false "Resume after this event"
+// @Success 200 {object} domain.DeviceEvent
+// @Failure 400 {object} Problem
+// @Router /devices/events [get]
+func (h *EventHandler) StreamEvents(c *gin.Context) {
+	sub, missed, err := h.subscribe(c, c.GetHeader(LastEventIDHeader))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer h.broker.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	for _, event := range missed {
+		writeSSE(c.Writer, event)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind; the client reconnects
+				// with Last-Event-ID and catches up from the backlog.
+				return
+			}
+			writeSSE(c.Writer, event)
+		case <-heartbeat.C:
+			io.WriteString(c.Writer, ": ping\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeSSE(w io.Writer, event domain.DeviceEvent) {
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Action, data)
+}
+
+// StreamEventsWebSocket godoc
+// @Summary Stream device events over a WebSocket
+// @Description Upgrade to a WebSocket and receive the events of /devices/events as JSON text messages. Messages sent by the client are ignored.
+// @Tags events
+// @Param brand query string false "Brand filter, e.g. Apple,Google"
+// @Param state query string false "State filter (available, in-use, inactive), e.g. available,in-use"
+// @Param id query string false "Device ID filter, e.g. DEV-000001,DEV-000002"
+// @Param last_event_id query int false "Resume after this event"
+// @Success 101
+// @Failure 400 {object} Problem
+// @Router /devices/events/ws [get]
+func (h *EventHandler) StreamEventsWebSocket(c *gin.Context) {
+	sub, missed, err := h.subscribe(c, "")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer h.broker.Unsubscribe(sub)
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already answered the request.
+		return
+	}
+	defer conn.Close()
+
+	// Reading is needed to process control frames and to notice the
+	// client going away.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, event := range missed {
+		if conn.WriteJSON(event) != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "subscriber fell behind"))
+				return
+			}
+			if conn.WriteJSON(event) != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(heartbeatInterval)) != nil {
+				return
+			}
+		}
+	}
+}
+
+// subscribe subscribes to the events selected by the query string. The
+// stream resumes after lastEventID, falling back to the last_event_id
+// parameter when it is empty.
+func (h *EventHandler) subscribe(c *gin.Context, lastEventID string) (*events.Subscription, []domain.DeviceEvent, error) {
+	filter := events.Filter{
+		Brands:    queryList(c, "brand"),
+		DeviceIDs: queryList(c, "id"),
+	}
+	for _, state := range queryList(c, "state") {
+		filter.States = append(filter.States, domain.DeviceState(state))
+	}
+	if err := (domain.DeviceCriteria{States: filter.States}).Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var after uint64
+	if lastEventID != "" {
+		var err error
+		if after, err = strconv.ParseUint(lastEventID, 10, 64); err != nil {
+			return nil, nil, badRequest(errInvalidLastEventID)
+		}
+	}
+
+	sub, missed := h.broker.Subscribe(filter, after)
+	return sub, missed, nil
+}
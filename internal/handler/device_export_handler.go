@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"device-api/internal/domain"
+	"device-api/internal/exporter"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportDevices godoc
+// @Summary Export devices
+// @Description Download every device matching the listing filters as a CSV, NDJSON or XLSX file. Devices are streamed from storage page by page.
+// @Tags devices
+// @Produce  text/csv
+// @Produce  application/x-ndjson
+// @Produce  application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "csv (default), ndjson or xlsx"
+// @Param brand query string false "Brand filter, e.g. Apple,Google"
+// @Param state query string false "State filter (available, in-use, inactive), e.g. available,in-use"
+// @Param name query string false "Case-insensitive name substring"
+// @Param created_from query string false "Only devices created at or after this RFC 3339 time"
+// @Param created_to query string false "Only devices created before this RFC 3339 time"
+// @Param include_deleted query bool false "Include soft deleted devices"
+// @Param sort query string false "Sort fields (id, name, brand, state, created_at), '-' prefix for descending"
+// @Success 200 {file} file
+// @Header 200 {string} Content-Disposition "attachment; filename=devices-YYYYMMDD.csv"
+// @Failure 400 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /devices/export [get]
+func (h *DeviceHandler) ExportDevices(c *gin.Context) {
+	format, err := exporter.ParseFormat(c.DefaultQuery("format", string(exporter.FormatCSV)))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	criteria, err := criteriaFromQuery(c)
+	if err != nil {
+		c.Error(badRequest(err))
+		return
+	}
+	sort, err := domain.ParseDeviceSort(c.Query("sort"))
+	if err != nil {
+		c.Error(badRequest(err))
+		return
+	}
+
+	svc := h.service
+	if c.Query("include_deleted") == "true" {
+		svc = svc.IncludingDeleted()
+	}
+
+	// Headers are only sent with the first device, so that errors found
+	// before that can still be reported as problems.
+	var out exporter.Writer
+	err = svc.ExportDevices(c.Request.Context(), domain.DeviceQuery{Criteria: criteria, Sort: sort}, func(device *domain.Device) error {
+		if out == nil {
+			if out, err = startExport(c, format); err != nil {
+				return err
+			}
+		}
+		return out.Write(device)
+	})
+	if err == nil && out == nil {
+		out, err = startExport(c, format)
+	}
+	if out != nil {
+		// Closed after failures too, which releases the temporary files of
+		// XLSX; the first error is kept.
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		// Once the body has started the status cannot change; the error
+		// only shows in the request log and the file is cut short.
+		c.Error(err)
+	}
+}
+
+func startExport(c *gin.Context, format exporter.Format) (exporter.Writer, error) {
+	filename := fmt.Sprintf("devices-%s.%s", time.Now().UTC().Format("20060102"), format)
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	return exporter.NewWriter(c.Writer, format)
+}
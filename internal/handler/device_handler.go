@@ -3,8 +3,16 @@ package handler
 import (
 	"device-api/internal/domain"
 	"device-api/internal/service"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	jsonpatch "github.com/evanphx/json-patch/v5"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,48 +24,74 @@ func NewDeviceHandler(s *service.DeviceService) *DeviceHandler {
 	return &DeviceHandler{service: s}
 }
 
+// ActorHeader names the request header identifying who performs a change.
+const ActorHeader = "X-Actor"
+
+// serviceFor returns the device service scoped to the actor of the request
+// and to the versions listed in its If-Match header.
+func (h *DeviceHandler) serviceFor(c *gin.Context) *service.DeviceService {
+	actor := c.GetHeader(ActorHeader)
+	if actor == "" {
+		actor = "anonymous"
+	}
+	svc := h.service.As(actor)
+
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return svc
+	}
+	var versions []int64
+	for _, tag := range strings.Split(ifMatch, ",") {
+		if version, ok := parseETag(tag); ok {
+			versions = append(versions, version)
+		}
+	}
+	return svc.IfMatch(versions...)
+}
+
+func etagFor(device *domain.Device) string {
+	return `"` + strconv.FormatInt(device.Version, 10) + `"`
+}
+
+func parseETag(tag string) (int64, bool) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	unquoted, err := strconv.Unquote(tag)
+	if err != nil {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	return version, err == nil
+}
+
+func setETag(c *gin.Context, device *domain.Device) {
+	c.Header("ETag", etagFor(device))
+}
+
 // CreateDevice godoc
 // @Summary Create a new device
-// @Description Create a new device with the input payload
+// @Description Create a new device with the input payload. The ID is generated by the server when omitted.
 // @Tags devices
 // @Accept  json
 // @Produce  json
 // @Param device body CreateDeviceRequest true "Create Device"
 // @Success 201 {object} domain.Device
-// @Failure 400 {object} ErrorResponse
-// @Failure 409 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /devices [post]
 func (h *DeviceHandler) CreateDevice(c *gin.Context) {
 	var req CreateDeviceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		c.Error(badRequest(err))
 		return
 	}
 
-    // ID is required by domain.NewDevice? The prompt didn't specify if ID is generic or user provided.
-    // The previous implementation of Service.CreateDevice assumes ID is passed.
-    // If not in request, maybe generate it?
-    // Let's assume user provides it or we generate UUID if missing.
-    // Spec says: "Id" in Device Domain. 
-    // I will add ID to request or generate it.
-    // "Fetch a single device" -> by ID.
-    // Let's assume for now request has it or we generate it. 
-    // Ideally we'd use UUID.
-    if req.ID == "" {
-         c.JSON(http.StatusBadRequest, ErrorResponse{Error: "id is required"})
-         return
-    }
-
-	device, err := h.service.CreateDevice(req.ID, req.Name, req.Brand)
+	device, err := h.serviceFor(c).CreateDevice(c.Request.Context(), req.ID, req.Name, req.Brand)
 	if err != nil {
-		if err == domain.ErrDeviceAlreadyExists {
-			c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
+	setETag(c, device)
 	c.JSON(http.StatusCreated, device)
 }
 
@@ -68,165 +102,466 @@ func (h *DeviceHandler) CreateDevice(c *gin.Context) {
 // @Produce  json
 // @Param id path string true "Device ID"
 // @Success 200 {object} domain.Device
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Header 200 {string} ETag "Current device version"
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /devices/{id} [get]
 func (h *DeviceHandler) GetDevice(c *gin.Context) {
 	id := c.Param("id")
-	device, err := h.service.GetDevice(id)
+	device, err := h.service.GetDevice(c.Request.Context(), id)
 	if err != nil {
-		if err == domain.ErrDeviceNotFound {
-			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
+	setETag(c, device)
 	c.JSON(http.StatusOK, device)
 }
 
 // ListDevices godoc
 // @Summary List all devices
-// @Description Get a list of devices, optionally filtered by brand or state
+// @Description Get a page of devices. Filters are combined with AND; comma separated values within one filter are combined with OR.
 // @Tags devices
 // @Produce  json
-// @Param brand query string false "Brand filter"
-// @Param state query string false "State filter (available, in-use, inactive)"
-// @Success 200 {array} domain.Device
-// @Failure 500 {object} ErrorResponse
+// @Param brand query string false "Brand filter, e.g. Apple,Google"
+// @Param state query string false "State filter (available, in-use, inactive), e.g. available,in-use"
+// @Param name query string false "Case-insensitive name substring"
+// @Param created_from query string false "Only devices created at or after this RFC 3339 time"
+// @Param created_to query string false "Only devices created before this RFC 3339 time"
+// @Param include_deleted query bool false "Include soft deleted devices"
+// @Param sort query string false "Sort fields (id, name, brand, state, created_at), '-' prefix for descending, e.g. created_at,-name"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param include_total query bool false "Also count all matching devices"
+// @Success 200 {object} DeviceListResponse
+// @Failure 400 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /devices [get]
 func (h *DeviceHandler) ListDevices(c *gin.Context) {
-	brand := c.Query("brand")
-	state := c.Query("state")
+	query, err := deviceQueryFromRequest(c)
+	if err != nil {
+		c.Error(badRequest(err))
+		return
+	}
+
+	svc := h.service
+	if c.Query("include_deleted") == "true" {
+		svc = svc.IncludingDeleted()
+	}
+
+	page, err := svc.ListDevices(c.Request.Context(), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	resp := DeviceListResponse{
+		Items:      page.Items,
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	}
+	if resp.Items == nil {
+		resp.Items = []*domain.Device{}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// deviceQueryFromRequest builds a device listing query from the query string.
+func deviceQueryFromRequest(c *gin.Context) (domain.DeviceQuery, error) {
+	criteria, err := criteriaFromQuery(c)
+	if err != nil {
+		return domain.DeviceQuery{}, err
+	}
+	sort, err := domain.ParseDeviceSort(c.Query("sort"))
+	if err != nil {
+		return domain.DeviceQuery{}, err
+	}
+	limit, err := queryInt(c, "limit", 0)
+	if err != nil || limit < 0 {
+		return domain.DeviceQuery{}, errors.New("limit must be a positive integer")
+	}
+	return domain.DeviceQuery{
+		Criteria:     criteria,
+		Sort:         sort,
+		Limit:        limit,
+		Cursor:       c.Query("cursor"),
+		IncludeTotal: c.Query("include_total") == "true",
+	}, nil
+}
+
+// criteriaFromQuery builds device criteria from the listing query string.
+// Multi-valued filters accept both repeated parameters and comma separated
+// values.
+func criteriaFromQuery(c *gin.Context) (domain.DeviceCriteria, error) {
+	criteria := domain.DeviceCriteria{
+		Brands:       queryList(c, "brand"),
+		NameContains: c.Query("name"),
+	}
+	for _, state := range queryList(c, "state") {
+		criteria.States = append(criteria.States, domain.DeviceState(state))
+	}
 
-	var devices []*domain.Device
 	var err error
+	if criteria.CreatedFrom, err = queryTime(c, "created_from"); err != nil {
+		return criteria, err
+	}
+	if criteria.CreatedTo, err = queryTime(c, "created_to"); err != nil {
+		return criteria, err
+	}
+	return criteria, nil
+}
 
-	if brand != "" {
-		devices, err = h.service.ListDevicesByBrand(brand)
-	} else if state != "" {
-		devices, err = h.service.ListDevicesByState(domain.DeviceState(state))
-	} else {
-		devices, err = h.service.ListAllDevices()
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(key) {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
 	}
+	return values
+}
 
+func queryTime(c *gin.Context, key string) (*time.Time, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
-		return
+		return nil, fmt.Errorf("%s must be an RFC 3339 time", key)
 	}
-	c.JSON(http.StatusOK, devices)
+	return &t, nil
 }
 
-// UpdateDevice godoc
-// @Summary Update a device
-// @Description Fully or partially update a device (details or state)
+// ReplaceDevice godoc
+// @Summary Replace a device
+// @Description Set the name, brand and state of a device. All fields are required.
 // @Tags devices
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Device ID"
-// @Param device body UpdateDeviceRequest true "Update Device"
+// @Param device body ReplaceDeviceRequest true "Replace Device"
+// @Param If-Match header string false "ETag the device must still have"
 // @Success 200 {object} domain.Device
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 422 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Header 200 {string} ETag "New device version"
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /devices/{id} [put]
-// @Router /devices/{id} [patch]
-func (h *DeviceHandler) UpdateDevice(c *gin.Context) {
-    id := c.Param("id")
-    var req UpdateDeviceRequest
+func (h *DeviceHandler) ReplaceDevice(c *gin.Context) {
+	var req ReplaceDeviceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
-		return
-	}
-
-    var device *domain.Device
-    var err error
-
-    if req.State != "" {
-        device, err = h.service.UpdateDeviceState(id, domain.DeviceState(req.State))
-        if err != nil {
-            if err == domain.ErrDeviceNotFound {
-                c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
-                return
-            }
-             c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
-             return
-        }
-    }
-
-    if req.Name != "" || req.Brand != "" {
-        device, err = h.service.UpdateDevice(id, req.Name, req.Brand)
-         if err != nil {
-            if err == domain.ErrDeviceNotFound {
-                c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
-                return
-            }
-            if err == domain.ErrDeviceInUse {
-                 c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error()}) 
-                 return
-            }
-            c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
-            return
-        }
-    }
-    
-    if req.Name == "" && req.Brand == "" && req.State == "" {
-        device, err = h.service.GetDevice(id)
-        if err != nil {
-             if err == domain.ErrDeviceNotFound {
-                c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
-                return
-            }
-            c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
-            return
-        }
-    }
-
-    c.JSON(http.StatusOK, device)
+		c.Error(badRequest(err))
+		return
+	}
+
+	device, err := h.serviceFor(c).ReplaceDevice(c.Request.Context(), c.Param("id"), domain.DeviceAttributes{
+		Name:  req.Name,
+		Brand: req.Brand,
+		State: domain.DeviceState(req.State),
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	setETag(c, device)
+	c.JSON(http.StatusOK, device)
+}
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// PatchDevice godoc
+// @Summary Patch a device
+// @Description Change some of the name, brand and state of a device with a JSON Merge Patch (RFC 7396, also accepted as application/json) or a JSON Patch (RFC 6902). The patch is applied as a whole or not at all.
+// @Tags devices
+// @Accept  application/merge-patch+json
+// @Accept  application/json-patch+json
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Device ID"
+// @Param patch body domain.DeviceAttributes true "Merge patch of the device attributes"
+// @Param If-Match header string false "ETag the device must still have"
+// @Success 200 {object} domain.Device
+// @Header 200 {string} ETag "New device version"
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 415 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /devices/{id} [patch]
+func (h *DeviceHandler) PatchDevice(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.Error(badRequest(err))
+		return
+	}
+
+	var apply func(doc []byte) ([]byte, error)
+	switch c.ContentType() {
+	case MergePatchContentType, "application/json", "":
+		apply = func(doc []byte) ([]byte, error) {
+			return jsonpatch.MergePatch(doc, body)
+		}
+	case JSONPatchContentType:
+		patch, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			c.Error(badRequest(err))
+			return
+		}
+		apply = patch.Apply
+	default:
+		c.Error(errUnsupportedMediaType)
+		return
+	}
+
+	device, err := h.serviceFor(c).ModifyDevice(c.Request.Context(), c.Param("id"), func(attrs *domain.DeviceAttributes) error {
+		return patchAttributes(attrs, apply)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	setETag(c, device)
+	c.JSON(http.StatusOK, device)
+}
+
+// patchAttributes applies a patch to the JSON document of attrs. Patches
+// that add members other than the device attributes are rejected.
+func patchAttributes(attrs *domain.DeviceAttributes, apply func(doc []byte) ([]byte, error)) error {
+	doc, err := json.Marshal(attrs)
+	if err != nil {
+		return err
+	}
+	patched, err := apply(doc)
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return err
+		}
+		return badRequest(err)
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(patched, &members); err != nil {
+		return badRequest(err)
+	}
+	for name := range members {
+		if name != "name" && name != "brand" && name != "state" {
+			return fmt.Errorf("%w: %s", domain.ErrImmutableField, name)
+		}
+	}
+
+	*attrs = domain.DeviceAttributes{}
+	if err := json.Unmarshal(patched, attrs); err != nil {
+		return badRequest(err)
+	}
+	return nil
 }
 
 // DeleteDevice godoc
 // @Summary Delete a device
-// @Description Delete a device by ID
+// @Description Soft delete a device by ID. It can be restored until it is purged.
 // @Tags devices
 // @Param id path string true "Device ID"
+// @Param If-Match header string false "ETag the device must still have"
 // @Success 204 "No Content"
-// @Failure 404 {object} ErrorResponse
-// @Failure 422 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 404 {object} Problem
+// @Failure 412 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /devices/{id} [delete]
 func (h *DeviceHandler) DeleteDevice(c *gin.Context) {
 	id := c.Param("id")
-	err := h.service.DeleteDevice(id)
+	err := h.serviceFor(c).DeleteDevice(c.Request.Context(), id)
 	if err != nil {
-		if err == domain.ErrDeviceNotFound {
-			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
-			return
-		}
-        if err == domain.ErrDeviceInUse {
-             c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error()}) 
-             return
-        }
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
 
+// CheckoutDevice godoc
+// @Summary Check out a device
+// @Description Assign an available device to someone and mark it in use
+// @Tags devices
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Device ID"
+// @Param checkout body CheckoutRequest true "Checkout"
+// @Success 200 {object} domain.Device
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /devices/{id}/checkout [post]
+func (h *DeviceHandler) CheckoutDevice(c *gin.Context) {
+	id := c.Param("id")
+	var req CheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(badRequest(err))
+		return
+	}
+
+	device, err := h.serviceFor(c).CheckoutDevice(c.Request.Context(), id, req.Assignee, req.ExpectedReturnAt)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	setETag(c, device)
+	c.JSON(http.StatusOK, device)
+}
+
+// CheckinDevice godoc
+// @Summary Check in a device
+// @Description Return a checked out device and make it available again
+// @Tags devices
+// @Produce  json
+// @Param id path string true "Device ID"
+// @Success 200 {object} domain.Device
+// @Failure 404 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /devices/{id}/checkin [post]
+func (h *DeviceHandler) CheckinDevice(c *gin.Context) {
+	id := c.Param("id")
+	device, err := h.serviceFor(c).CheckinDevice(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	setETag(c, device)
+	c.JSON(http.StatusOK, device)
+}
+
+// RestoreDevice godoc
+// @Summary Restore a deleted device
+// @Description Undo the soft deletion of a device
+// @Tags devices
+// @Produce  json
+// @Param id path string true "Device ID"
+// @Success 200 {object} domain.Device
+// @Failure 404 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /devices/{id}/restore [post]
+func (h *DeviceHandler) RestoreDevice(c *gin.Context) {
+	device, err := h.serviceFor(c).RestoreDevice(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	setETag(c, device)
+	c.JSON(http.StatusOK, device)
+}
+
+// PurgeDeletedDevices godoc
+// @Summary Purge deleted devices
+// @Description Permanently remove devices that were deleted longer ago than the configured retention
+// @Tags admin
+// @Produce  json
+// @Success 200 {object} PurgeResponse
+// @Failure 500 {object} Problem
+// @Router /admin/devices/purge [post]
+func (h *DeviceHandler) PurgeDeletedDevices(c *gin.Context) {
+	ids, err := h.serviceFor(c).PurgeDeletedDevices(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, PurgeResponse{Purged: ids})
+}
+
+// GetDeviceHistory godoc
+// @Summary Get the change history of a device
+// @Description Get the create, update, state change and delete records of a device, newest first
+// @Tags devices
+// @Produce  json
+// @Param id path string true "Device ID"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of entries to skip"
+// @Success 200 {object} HistoryPage
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /devices/{id}/history [get]
+func (h *DeviceHandler) GetDeviceHistory(c *gin.Context) {
+	limit, err := queryInt(c, "limit", defaultHistoryLimit)
+	if err != nil || limit < 1 || limit > maxHistoryLimit {
+		c.Error(badRequest(errors.New("limit must be between 1 and " + strconv.Itoa(maxHistoryLimit))))
+		return
+	}
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil || offset < 0 {
+		c.Error(badRequest(errors.New("offset must be a non-negative integer")))
+		return
+	}
+
+	entries, total, err := h.service.DeviceHistory(c.Request.Context(), c.Param("id"), limit, offset)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, HistoryPage{
+		Items:  entries,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+func queryInt(c *gin.Context, key string, fallback int) (int, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(raw)
+}
 
 type CreateDeviceRequest struct {
-    ID    string `json:"id"`
+	// ID is optional; the server generates one when it is left empty.
+	ID    string `json:"id"`
+	Name  string `json:"name" binding:"required"`
+	Brand string `json:"brand" binding:"required"`
+}
+
+type ReplaceDeviceRequest struct {
 	Name  string `json:"name" binding:"required"`
 	Brand string `json:"brand" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+type CheckoutRequest struct {
+	Assignee         string     `json:"assignee" binding:"required"`
+	ExpectedReturnAt *time.Time `json:"expected_return_at"`
+}
+
+type DeviceListResponse struct {
+	Items      []*domain.Device `json:"items"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+	Total      *int64           `json:"total,omitempty"`
 }
 
-type UpdateDeviceRequest struct {
-	Name  string `json:"name"`
-	Brand string `json:"brand"`
-    State string `json:"state"`
+type PurgeResponse struct {
+	Purged []string `json:"purged"`
 }
 
-type ErrorResponse struct {
-	Error string `json:"error"`
+type HistoryPage struct {
+	Items  []*domain.DeviceHistoryEntry `json:"items"`
+	Total  int64                        `json:"total"`
+	Limit  int                          `json:"limit"`
+	Offset int                          `json:"offset"`
 }
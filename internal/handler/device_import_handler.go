@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"device-api/internal/importer"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize caps the size of an uploaded inventory.
+const maxImportSize = 32 << 20
+
+// ImportDevices godoc
+// @Summary Import devices from a file
+// @Description Create or update devices from a CSV or NDJSON inventory, sent as the request body or as the "file" field of a multipart form. Rows are upserted by id; rows without an id create a device. CSV columns and NDJSON keys named id, name, brand and state are imported; others can be mapped with map. Rejected rows do not stop the import.
+// @Tags devices
+// @Accept  text/csv
+// @Accept  application/x-ndjson
+// @Accept  mpfd
+// @Produce  json
+// @Produce  text/csv
+// @Param format query string false "csv or ndjson; taken from the Content-Type or file name when omitted"
+// @Param map query []string false "Column mapping such as 'Asset Tag:id', repeatable" collectionFormat(multi)
+// @Param dry_run query bool false "Check every row without changing anything"
+// @Param report query string false "csv to download the rejected rows instead of the summary"
+// @Param file formData file false "Inventory file"
+// @Success 200 {object} ImportResponse
+// @Failure 400 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /devices:import [post]
+func (h *DeviceHandler) ImportDevices(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	body, name, err := importFile(c)
+	if err != nil {
+		c.Error(badRequest(err))
+		return
+	}
+	defer body.Close()
+
+	opts := importer.Options{
+		Columns: map[string]string{},
+		DryRun:  c.Query("dry_run") == "true",
+	}
+	format := c.Query("format")
+	if format == "" {
+		format = name
+	}
+	if opts.Format, err = importer.ParseFormat(format); err != nil {
+		c.Error(err)
+		return
+	}
+	for _, mapping := range c.QueryArray("map") {
+		i := strings.LastIndex(mapping, ":")
+		if i < 0 {
+			c.Error(fmt.Errorf("%w: map must look like 'column:field'", importer.ErrUnknownField))
+			return
+		}
+		opts.Columns[mapping[:i]] = mapping[i+1:]
+	}
+
+	result, err := importer.New(h.serviceFor(c)).Import(c.Request.Context(), body, opts)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = badRequest(err)
+		}
+		c.Error(err)
+		return
+	}
+
+	if c.Query("report") == "csv" {
+		c.Header("Content-Disposition", `attachment; filename="import-errors.csv"`)
+		c.Header("Content-Type", "text/csv")
+		c.Status(http.StatusOK)
+		importer.WriteRejections(c.Writer, result.Rejected)
+		return
+	}
+
+	resp := ImportResponse{
+		DryRun:   result.DryRun,
+		Total:    result.Total,
+		Created:  result.Created,
+		Updated:  result.Updated,
+		Rejected: len(result.Rejected),
+		Errors:   make([]ImportRowError, len(result.Rejected)),
+	}
+	for i, rejection := range result.Rejected {
+		problem := problemFor(rejection.Err)
+		resp.Errors[i] = ImportRowError{
+			Line:  rejection.Row.Line,
+			ID:    rejection.Row.ID,
+			Code:  problem.Code,
+			Error: problem.Detail,
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// importFile returns the uploaded inventory and, if known, its file name or
+// media type.
+func importFile(c *gin.Context) (io.ReadCloser, string, error) {
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", err
+		}
+		file, err := header.Open()
+		return file, header.Filename, err
+	}
+	return c.Request.Body, c.ContentType(), nil
+}
+
+type ImportResponse struct {
+	DryRun   bool             `json:"dry_run"`
+	Total    int              `json:"total"`
+	Created  int              `json:"created"`
+	Updated  int              `json:"updated"`
+	Rejected int              `json:"rejected"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+type ImportRowError struct {
+	Line  int    `json:"line"`
+	ID    string `json:"id,omitempty"`
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"device-api/internal/exporter"
+	"device-api/internal/importer"
+	"errors"
+	"net/http"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ProblemContentType is the media type of error responses (RFC 7807).
+const ProblemContentType = "application/problem+json"
+
+// RequestIDHeader carries the ID of a request. A client supplied value is
+// kept, otherwise one is generated; either way it is echoed in the response.
+const RequestIDHeader = "X-Request-ID"
+
+const requestIDKey = "request_id"
+
+// problemTypeBase prefixes the code of a problem to form its type URI.
+const problemTypeBase = "urn:device-api:problem:"
+
+// Problem is an RFC 7807 problem details body. Code is stable and meant for
+// clients to branch on; Title and Detail are for humans.
+type Problem struct {
+	Type          string               `json:"type"`
+	Title         string               `json:"title"`
+	Status        int                  `json:"status"`
+	Detail        string               `json:"detail,omitempty"`
+	Instance      string               `json:"instance,omitempty"`
+	Code          string               `json:"code"`
+	RequestID     string               `json:"request_id,omitempty"`
+	AllowedStates []domain.DeviceState `json:"allowed_states,omitempty"`
+}
+
+type problemType struct {
+	err    error
+	status int
+	code   string
+	title  string
+}
+
+// problemTypes maps domain errors to problems. Errors are matched with
+// errors.Is, so wrapped errors resolve to the sentinel they wrap.
+var problemTypes = []problemType{
+	{domain.ErrDeviceNotFound, http.StatusNotFound, "device_not_found", "Device not found"},
+	{domain.ErrDeviceAlreadyExists, http.StatusConflict, "device_already_exists", "Device already exists"},
+	{domain.ErrDeviceIDRequired, http.StatusBadRequest, "device_id_required", "Device ID required"},
+	{domain.ErrClientIDNotAllowed, http.StatusBadRequest, "client_id_not_allowed", "Client supplied ID not allowed"},
+	{domain.ErrDeviceIDReserved, http.StatusBadRequest, "device_id_reserved", "Device ID reserved"},
+	{domain.ErrDeviceNameRequired, http.StatusUnprocessableEntity, "device_name_required", "Device name required"},
+	{domain.ErrDeviceBrandRequired, http.StatusUnprocessableEntity, "device_brand_required", "Device brand required"},
+	{domain.ErrInvalidDeviceState, http.StatusUnprocessableEntity, "invalid_device_state", "Invalid device state"},
+	{domain.ErrImmutableField, http.StatusUnprocessableEntity, "immutable_field", "Field cannot be updated"},
+	{domain.ErrDeviceInUse, http.StatusUnprocessableEntity, "device_in_use", "Device in use"},
+	{domain.ErrDeviceInactive, http.StatusUnprocessableEntity, "device_inactive", "Device inactive"},
+	{domain.ErrDeviceNotCheckedOut, http.StatusUnprocessableEntity, "device_not_checked_out", "Device not checked out"},
+	{domain.ErrDeviceNotDeleted, http.StatusConflict, "device_not_deleted", "Device not deleted"},
+	{domain.ErrVersionConflict, http.StatusConflict, "version_conflict", "Version conflict"},
+	{domain.ErrPreconditionFailed, http.StatusPreconditionFailed, "precondition_failed", "Precondition failed"},
+	{domain.ErrInvalidFilter, http.StatusBadRequest, "invalid_filter", "Invalid filter"},
+	{domain.ErrInvalidSort, http.StatusBadRequest, "invalid_sort", "Invalid sort"},
+	{domain.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor", "Invalid cursor"},
+	{domain.ErrAssigneeRequired, http.StatusBadRequest, "assignee_required", "Assignee required"},
+	{domain.ErrInvalidReturnTime, http.StatusBadRequest, "invalid_return_time", "Invalid return time"},
+	{domain.ErrReservationNotFound, http.StatusNotFound, "reservation_not_found", "Reservation not found"},
+	{domain.ErrReservationConflict, http.StatusConflict, "reservation_conflict", "Reservation conflict"},
+	{domain.ErrInvalidReservationWindow, http.StatusBadRequest, "invalid_reservation_window", "Invalid reservation window"},
+	{domain.ErrReserverRequired, http.StatusBadRequest, "reserver_required", "Reserver required"},
+	{domain.ErrDeviceReserved, http.StatusConflict, "device_reserved", "Device reserved"},
+	{domain.ErrWebhookNotFound, http.StatusNotFound, "webhook_not_found", "Webhook not found"},
+	{domain.ErrInvalidWebhookURL, http.StatusUnprocessableEntity, "invalid_webhook_url", "Invalid webhook URL"},
+	{domain.ErrWebhookTargetNotAllowed, http.StatusUnprocessableEntity, "webhook_target_not_allowed", "Webhook target not allowed"},
+	{domain.ErrInvalidEventType, http.StatusUnprocessableEntity, "invalid_event_type", "Invalid event type"},
+	{domain.ErrDeliveryNotFound, http.StatusNotFound, "delivery_not_found", "Webhook delivery not found"},
+	{domain.ErrBatchTooLarge, http.StatusBadRequest, "batch_too_large", "Batch too large"},
+	{domain.ErrInvalidBatchMode, http.StatusBadRequest, "invalid_batch_mode", "Invalid batch mode"},
+	{domain.ErrBatchAborted, http.StatusFailedDependency, "batch_aborted", "Batch aborted"},
+	{domain.ErrAtomicBatchUnavailable, http.StatusNotImplemented, "atomic_batch_unavailable", "Atomic batch unavailable"},
+	{exporter.ErrUnknownFormat, http.StatusBadRequest, "unknown_export_format", "Unknown export format"},
+	{importer.ErrUnknownFormat, http.StatusBadRequest, "unknown_import_format", "Unknown import format"},
+	{importer.ErrUnknownField, http.StatusBadRequest, "unknown_import_field", "Unknown import field"},
+	{importer.ErrMissingColumn, http.StatusBadRequest, "missing_import_column", "Missing import column"},
+	{importer.ErrMalformedRow, http.StatusBadRequest, "malformed_row", "Malformed row"},
+	{errUnknownMethod, http.StatusNotFound, "unknown_method", "Unknown method"},
+	{errUnsupportedMediaType, http.StatusUnsupportedMediaType, "unsupported_media_type", "Unsupported media type"},
+	{jsonpatch.ErrTestFailed, http.StatusConflict, "patch_test_failed", "Patch test failed"},
+	{context.Canceled, StatusClientClosedRequest, "client_closed_request", "Client closed request"},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, "timeout", "Request timed out"},
+}
+
+var (
+	errUnknownMethod        = errors.New("unknown method")
+	errUnsupportedMediaType = errors.New("unsupported media type")
+)
+
+var (
+	invalidRequestProblem = problemType{status: http.StatusBadRequest, code: "invalid_request", title: "Invalid request"}
+	internalProblem       = problemType{status: http.StatusInternalServerError, code: "internal_error", title: "Internal server error"}
+)
+
+// requestError marks an error caused by a malformed request. It is always
+// answered with 400 Bad Request, keeping the code of the wrapped domain
+// error when there is one.
+type requestError struct {
+	err error
+}
+
+func (e *requestError) Error() string { return e.err.Error() }
+func (e *requestError) Unwrap() error { return e.err }
+
+func badRequest(err error) error {
+	return &requestError{err: err}
+}
+
+// RequestID assigns every request an ID, see RequestIDHeader.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = uuid.NewString()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// Problems answers requests whose handler reported an error with c.Error and
+// wrote no response with a problem details body. The messages of unexpected
+// errors are not exposed; they are only visible in the request log.
+func Problems() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		problem := problemFor(c.Errors.Last().Err)
+		if ctxErr := c.Request.Context().Err(); ctxErr != nil && problem.Status == http.StatusInternalServerError {
+			// Database drivers do not always wrap the context error that
+			// made a query fail.
+			problem = problemFor(ctxErr)
+		}
+		problem.Instance = c.Request.URL.Path
+		problem.RequestID = c.GetString(requestIDKey)
+
+		// c.JSON keeps a Content-Type that is already set.
+		c.Header("Content-Type", ProblemContentType)
+		c.JSON(problem.Status, problem)
+	}
+}
+
+func problemFor(err error) Problem {
+	kind, known := internalProblem, false
+	for _, t := range problemTypes {
+		if errors.Is(err, t.err) {
+			kind, known = t, true
+			break
+		}
+	}
+
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		if !known {
+			kind = invalidRequestProblem
+		}
+		kind.status, known = http.StatusBadRequest, true
+	}
+
+	problem := Problem{
+		Type:   problemTypeBase + kind.code,
+		Title:  kind.title,
+		Status: kind.status,
+		Code:   kind.code,
+		Detail: "An unexpected error occurred.",
+	}
+	if known {
+		problem.Detail = err.Error()
+	}
+
+	var transitionErr *domain.StateTransitionError
+	if errors.As(err, &transitionErr) {
+		problem.AllowedStates = transitionErr.Allowed
+	}
+	return problem
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProblemFor(t *testing.T) {
+	t.Run("wrapped sentinel", func(t *testing.T) {
+		problem := problemFor(fmt.Errorf("checkout: %w", domain.ErrDeviceReserved))
+		assert.Equal(t, http.StatusConflict, problem.Status)
+		assert.Equal(t, "device_reserved", problem.Code)
+		assert.Equal(t, "urn:device-api:problem:device_reserved", problem.Type)
+	})
+
+	t.Run("state transition", func(t *testing.T) {
+		err := &domain.StateTransitionError{From: domain.DeviceStateInactive, To: domain.DeviceStateInUse, Allowed: []domain.DeviceState{domain.DeviceStateAvailable}}
+		problem := problemFor(err)
+		assert.Equal(t, http.StatusUnprocessableEntity, problem.Status)
+		assert.Equal(t, "invalid_device_state", problem.Code)
+		assert.Equal(t, []domain.DeviceState{domain.DeviceStateAvailable}, problem.AllowedStates)
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		problem := problemFor(&domain.FilterError{Field: "state", Value: "banana"})
+		assert.Equal(t, http.StatusBadRequest, problem.Status)
+		assert.Equal(t, "invalid_filter", problem.Code)
+		assert.Nil(t, problem.AllowedStates)
+	})
+
+	t.Run("bad request keeps domain code", func(t *testing.T) {
+		problem := problemFor(badRequest(domain.ErrInvalidSort))
+		assert.Equal(t, http.StatusBadRequest, problem.Status)
+		assert.Equal(t, "invalid_sort", problem.Code)
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		problem := problemFor(fmt.Errorf("finding device: %w", context.DeadlineExceeded))
+		assert.Equal(t, http.StatusGatewayTimeout, problem.Status)
+		assert.Equal(t, "timeout", problem.Code)
+	})
+
+	t.Run("client closed request", func(t *testing.T) {
+		problem := problemFor(context.Canceled)
+		assert.Equal(t, StatusClientClosedRequest, problem.Status)
+		assert.Equal(t, "client_closed_request", problem.Code)
+	})
+
+	t.Run("unexpected error is hidden", func(t *testing.T) {
+		problem := problemFor(errors.New(`pq: relation "devices" does not exist`))
+		assert.Equal(t, http.StatusInternalServerError, problem.Status)
+		assert.Equal(t, "internal_error", problem.Code)
+		assert.NotContains(t, problem.Detail, "devices")
+	})
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"device-api/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReservationHandler struct {
+	service *service.ReservationService
+}
+
+func NewReservationHandler(s *service.ReservationService) *ReservationHandler {
+	return &ReservationHandler{service: s}
+}
+
+// CreateReservation godoc
+// @Summary Reserve a device
+// @Description Book a device for a user over a future time window
+// @Tags reservations
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Device ID"
+// @Param reservation body CreateReservationRequest true "Create Reservation"
+// @Success 201 {object} domain.Reservation
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 409 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /devices/{id}/reservations [post]
+func (h *ReservationHandler) CreateReservation(c *gin.Context) {
+	var req CreateReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(badRequest(err))
+		return
+	}
+
+	reservation, err := h.service.Reserve(c.Request.Context(), c.Param("id"), req.ReservedBy, req.StartsAt, req.EndsAt)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, reservation)
+}
+
+// ListReservations godoc
+// @Summary List reservations of a device
+// @Description Get the current and upcoming reservations of a device
+// @Tags reservations
+// @Produce  json
+// @Param id path string true "Device ID"
+// @Param include_past query bool false "Include reservations that already ended"
+// @Success 200 {array} domain.Reservation
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /devices/{id}/reservations [get]
+func (h *ReservationHandler) ListReservations(c *gin.Context) {
+	includePast := c.Query("include_past") == "true"
+	reservations, err := h.service.ListReservations(c.Request.Context(), c.Param("id"), includePast)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, reservations)
+}
+
+// GetReservation godoc
+// @Summary Get a reservation
+// @Description Get a single reservation of a device
+// @Tags reservations
+// @Produce  json
+// @Param id path string true "Device ID"
+// @Param reservation_id path string true "Reservation ID"
+// @Success 200 {object} domain.Reservation
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /devices/{id}/reservations/{reservation_id} [get]
+func (h *ReservationHandler) GetReservation(c *gin.Context) {
+	reservation, err := h.service.GetReservation(c.Request.Context(), c.Param("id"), c.Param("reservation_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, reservation)
+}
+
+// CancelReservation godoc
+// @Summary Cancel a reservation
+// @Description Delete a reservation of a device
+// @Tags reservations
+// @Param id path string true "Device ID"
+// @Param reservation_id path string true "Reservation ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /devices/{id}/reservations/{reservation_id} [delete]
+func (h *ReservationHandler) CancelReservation(c *gin.Context) {
+	err := h.service.CancelReservation(c.Request.Context(), c.Param("id"), c.Param("reservation_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+type CreateReservationRequest struct {
+	ReservedBy string    `json:"reserved_by" binding:"required"`
+	StartsAt   time.Time `json:"starts_at" binding:"required"`
+	EndsAt     time.Time `json:"ends_at" binding:"required"`
+}
@@ -6,22 +6,48 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+// RegisterRoutes installs the request ID and problem details middleware and
+// the device routes. It must be called before the other Register functions
+// so that their routes share the middleware.
 func RegisterRoutes(r *gin.Engine, handler *DeviceHandler) {
-    r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	r.Use(RequestID(), Problems())
 
-    api := r.Group("/api/v1")
-    {
-        api.POST("/devices", handler.CreateDevice)
-        api.GET("/devices/:id", handler.GetDevice)
-        api.GET("/devices", handler.ListDevices)
-        api.PUT("/devices/:id", handler.UpdateDevice)
-        api.PATCH("/devices/:id", handler.UpdateDevice)
-        api.DELETE("/devices/:id", handler.DeleteDevice)
-    }
-    
-    r.GET("/ping", func(c *gin.Context) {
-        c.JSON(200, gin.H{
-            "message": "pong",
-        })
-    })
+	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+	api := r.Group("/api/v1")
+	{
+		api.POST("/devices", handler.CreateDevice)
+		api.GET("/devices/:id", handler.GetDevice)
+		api.GET("/devices", handler.ListDevices)
+		api.GET("/devices/export", handler.ExportDevices)
+		api.POST("/devices:method", handler.DeviceCollectionMethod)
+		api.PUT("/devices/:id", handler.ReplaceDevice)
+		api.PATCH("/devices/:id", handler.PatchDevice)
+		api.DELETE("/devices/:id", handler.DeleteDevice)
+		api.POST("/devices/:id/checkout", handler.CheckoutDevice)
+		api.POST("/devices/:id/checkin", handler.CheckinDevice)
+		api.GET("/devices/:id/history", handler.GetDeviceHistory)
+		api.POST("/devices/:id/restore", handler.RestoreDevice)
+	}
+
+	admin := api.Group("/admin")
+	{
+		admin.POST("/devices/purge", handler.PurgeDeletedDevices)
+	}
+
+	r.GET("/ping", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"message": "pong",
+		})
+	})
+}
+
+func RegisterReservationRoutes(r *gin.Engine, handler *ReservationHandler) {
+	api := r.Group("/api/v1")
+	{
+		api.POST("/devices/:id/reservations", handler.CreateReservation)
+		api.GET("/devices/:id/reservations", handler.ListReservations)
+		api.GET("/devices/:id/reservations/:reservation_id", handler.GetReservation)
+		api.DELETE("/devices/:id/reservations/:reservation_id", handler.CancelReservation)
+	}
 }
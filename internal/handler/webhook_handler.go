@@ -0,0 +1,270 @@
+package handler
+
+import (
+	"device-api/internal/domain"
+	"device-api/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	service *service.WebhookService
+}
+
+func NewWebhookHandler(s *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: s}
+}
+
+func RegisterWebhookRoutes(r *gin.Engine, handler *WebhookHandler) {
+	api := r.Group("/api/v1")
+	{
+		api.POST("/webhooks", handler.CreateWebhook)
+		api.GET("/webhooks", handler.ListWebhooks)
+		api.GET("/webhooks/dead-letters", handler.ListDeadLetters)
+		api.GET("/webhooks/:id", handler.GetWebhook)
+		api.PUT("/webhooks/:id", handler.UpdateWebhook)
+		api.DELETE("/webhooks/:id", handler.DeleteWebhook)
+		api.GET("/webhooks/:id/deliveries", handler.ListDeliveries)
+		api.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", handler.Redeliver)
+	}
+}
+
+// CreateWebhook godoc
+// @Summary Subscribe a webhook
+// @Description Register a URL to be sent device events. Event types are the history actions (created, updated, state_changed, checked_out, checked_in, deleted, restored, purged); states narrow them to devices in those states, e.g. event_types [state_changed, checked_in] with states [available] for devices becoming available. The secret signing the payloads is generated when omitted and only returned here.
+// @Tags webhooks
+// @Accept  json
+// @Produce  json
+// @Param webhook body WebhookRequest true "Webhook"
+// @Success 201 {object} CreateWebhookResponse
+// @Failure 400 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(badRequest(err))
+		return
+	}
+
+	webhook, err := h.service.CreateWebhook(c.Request.Context(), req.subscription(), req.Secret)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, CreateWebhookResponse{Webhook: webhook, Secret: webhook.Secret})
+}
+
+// ListWebhooks godoc
+// @Summary List webhooks
+// @Tags webhooks
+// @Produce  json
+// @Success 200 {array} domain.Webhook
+// @Failure 500 {object} Problem
+// @Router /webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.service.ListWebhooks(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// GetWebhook godoc
+// @Summary Get a webhook
+// @Tags webhooks
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} domain.Webhook
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	webhook, err := h.service.GetWebhook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook godoc
+// @Summary Update a webhook
+// @Description Replace the URL, filters and active flag of a webhook. The secret cannot be changed.
+// @Tags webhooks
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Param webhook body WebhookRequest true "Webhook"
+// @Success 200 {object} domain.Webhook
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /webhooks/{id} [put]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(badRequest(err))
+		return
+	}
+	if req.Secret != "" {
+		c.Error(domain.ErrImmutableField)
+		return
+	}
+
+	webhook, err := h.service.UpdateWebhook(c.Request.Context(), c.Param("id"), req.subscription())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Remove a webhook together with its delivery log
+// @Tags webhooks
+// @Param id path string true "Webhook ID"
+// @Success 204
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	if err := h.service.DeleteWebhook(c.Request.Context(), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries godoc
+// @Summary Get the delivery log of a webhook
+// @Description Get the deliveries of a webhook, newest first, with the outcome of their last attempt
+// @Tags webhooks
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Param status query string false "pending, succeeded or dead"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Number of deliveries to skip"
+// @Success 200 {object} DeliveryPage
+// @Failure 400 {object} Problem
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	status := domain.DeliveryStatus(c.Query("status"))
+	switch status {
+	case "", domain.DeliveryStatusPending, domain.DeliveryStatusSucceeded, domain.DeliveryStatusDead:
+	default:
+		c.Error(badRequest(errors.New("status must be pending, succeeded or dead")))
+		return
+	}
+	limit, err := queryInt(c, "limit", defaultDeliveryLimit)
+	if err != nil || limit < 1 || limit > maxDeliveryLimit {
+		c.Error(badRequest(errors.New("limit must be between 1 and " + strconv.Itoa(maxDeliveryLimit))))
+		return
+	}
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil || offset < 0 {
+		c.Error(badRequest(errors.New("offset must be a non-negative integer")))
+		return
+	}
+
+	deliveries, total, err := h.service.Deliveries(c.Request.Context(), c.Param("id"), status, limit, offset)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, DeliveryPage{
+		Items:  deliveries,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+// ListDeadLetters godoc
+// @Summary List dead-lettered deliveries
+// @Description Get the deliveries of all webhooks that failed every attempt, oldest first
+// @Tags webhooks
+// @Produce  json
+// @Param limit query int false "Maximum number of deliveries (default and max 500)"
+// @Success 200 {array} domain.WebhookDelivery
+// @Failure 400 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /webhooks/dead-letters [get]
+func (h *WebhookHandler) ListDeadLetters(c *gin.Context) {
+	limit, err := queryInt(c, "limit", service.MaxDeadLetters)
+	if err != nil || limit < 1 {
+		c.Error(badRequest(errors.New("limit must be a positive integer")))
+		return
+	}
+	deliveries, err := h.service.DeadLetters(c.Request.Context(), limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// Redeliver godoc
+// @Summary Redeliver a webhook delivery
+// @Description Queue a delivery, typically a dead-lettered one, to be sent again with a fresh set of attempts
+// @Tags webhooks
+// @Produce  json
+// @Param id path string true "Webhook ID"
+// @Param delivery_id path string true "Delivery ID"
+// @Success 202 {object} domain.WebhookDelivery
+// @Failure 404 {object} Problem
+// @Failure 500 {object} Problem
+// @Router /webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	delivery, err := h.service.Redeliver(c.Request.Context(), c.Param("id"), c.Param("delivery_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// WebhookRequest subscribes a webhook. Active defaults to true.
+type WebhookRequest struct {
+	URL        string                 `json:"url" binding:"required"`
+	Secret     string                 `json:"secret,omitempty"`
+	EventTypes []domain.HistoryAction `json:"event_types"`
+	States     []domain.DeviceState   `json:"states"`
+	Active     *bool                  `json:"active"`
+}
+
+func (r WebhookRequest) subscription() domain.WebhookSubscription {
+	return domain.WebhookSubscription{
+		URL:        r.URL,
+		EventTypes: r.EventTypes,
+		States:     r.States,
+		Active:     r.Active == nil || *r.Active,
+	}
+}
+
+// CreateWebhookResponse is the created webhook together with its secret.
+type CreateWebhookResponse struct {
+	*domain.Webhook
+	Secret string `json:"secret"`
+}
+
+type DeliveryPage struct {
+	Items  []*domain.WebhookDelivery `json:"items"`
+	Total  int64                     `json:"total"`
+	Limit  int                       `json:"limit"`
+	Offset int                       `json:"offset"`
+}
@@ -0,0 +1,39 @@
+// Package idgen provides the stateless domain.IDGenerator strategies. The
+// database backed sequence lives in the repository package.
+package idgen
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+)
+
+// UUIDv4 generates random RFC 4122 version 4 UUIDs.
+type UUIDv4 struct{}
+
+func (UUIDv4) NewID(context.Context) (string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// UUIDv7 generates time-ordered version 7 UUIDs.
+type UUIDv7 struct{}
+
+func (UUIDv7) NewID(context.Context) (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// ULID generates lexicographically sortable ULIDs.
+type ULID struct{}
+
+func (ULID) NewID(context.Context) (string, error) {
+	return ulid.Make().String(), nil
+}
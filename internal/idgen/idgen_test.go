@@ -0,0 +1,34 @@
+package idgen_test
+
+import (
+	"context"
+
+	"device-api/internal/domain"
+	"device-api/internal/idgen"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		name      string
+		generator domain.IDGenerator
+		pattern   string
+	}{
+		{"uuidv4", idgen.UUIDv4{}, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{"uuidv7", idgen.UUIDv7{}, `^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{"ulid", idgen.ULID{}, `^[0-9A-HJKMNP-TV-Z]{26}$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := tt.generator.NewID(context.Background())
+			assert.NoError(t, err)
+			second, err := tt.generator.NewID(context.Background())
+			assert.NoError(t, err)
+
+			assert.Regexp(t, tt.pattern, first)
+			assert.NotEqual(t, first, second)
+		})
+	}
+}
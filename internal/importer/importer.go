@@ -0,0 +1,307 @@
+// Package importer loads device inventories from CSV or NDJSON files.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"device-api/internal/domain"
+	"device-api/internal/service"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Format is the encoding of an inventory file.
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown import format")
+	ErrUnknownField  = errors.New("unknown device field")
+	ErrMissingColumn = errors.New("missing column")
+	ErrMalformedRow  = errors.New("malformed row")
+)
+
+// Fields are the device fields an inventory can set. Name and brand are
+// required; rows without an ID create a device with a generated one.
+var Fields = []string{"id", "name", "brand", "state"}
+
+// ParseFormat accepts a format name, a file name with a known extension or a
+// media type.
+func ParseFormat(s string) (Format, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if i := strings.Index(s, ";"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	switch {
+	case s == "csv", s == "text/csv", strings.HasSuffix(s, ".csv"):
+		return FormatCSV, nil
+	case s == "ndjson", s == "jsonl", s == "application/x-ndjson", s == "application/ndjson",
+		strings.HasSuffix(s, ".ndjson"), strings.HasSuffix(s, ".jsonl"):
+		return FormatNDJSON, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, s)
+}
+
+type Options struct {
+	Format Format
+	// Columns maps CSV column names or NDJSON keys to device fields. Columns
+	// named after a field, ignoring case, need no entry.
+	Columns map[string]string
+	// DryRun checks every row without changing anything.
+	DryRun bool
+}
+
+// Row is one device record of an inventory. Line is where it starts in the
+// file, counting from 1.
+type Row struct {
+	Line  int
+	ID    string
+	Name  string
+	Brand string
+	State string
+}
+
+type Rejection struct {
+	Row Row
+	Err error
+}
+
+type Result struct {
+	DryRun   bool
+	Total    int
+	Created  int
+	Updated  int
+	Rejected []Rejection
+}
+
+// Importer upserts inventory rows through a DeviceService, so every row is
+// held to the same rules as the API.
+type Importer struct {
+	devices *service.DeviceService
+}
+
+func New(devices *service.DeviceService) *Importer {
+	return &Importer{devices: devices}
+}
+
+// Import reads every row of r and creates or updates its device. Rows that
+// cannot be parsed or break a rule are rejected without stopping the import;
+// an error is only returned when the file as a whole cannot be read or ctx
+// ends before every row was imported.
+func (im *Importer) Import(ctx context.Context, r io.Reader, opts Options) (*Result, error) {
+	devices := im.devices
+	if opts.DryRun {
+		devices = devices.DryRun()
+	}
+
+	result := &Result{DryRun: opts.DryRun}
+	err := readRows(r, opts, func(row Row, err error) error {
+		// Stop reading once the client is gone or the deadline passed.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		result.Total++
+		if err == nil {
+			var created bool
+			_, created, err = devices.UpsertDevice(ctx, row.ID, domain.DeviceAttributes{
+				Name:  row.Name,
+				Brand: row.Brand,
+				State: domain.DeviceState(row.State),
+			})
+			if created {
+				result.Created++
+			} else if err == nil {
+				result.Updated++
+			}
+		}
+		if err != nil {
+			result.Rejected = append(result.Rejected, Rejection{Row: row, Err: err})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// WriteRejections writes rejected rows as CSV with the reason in a last
+// column, ready to be fixed and imported again.
+func WriteRejections(w io.Writer, rejected []Rejection) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"line", "id", "name", "brand", "state", "error"})
+	for _, r := range rejected {
+		out.Write([]string{strconv.Itoa(r.Row.Line), r.Row.ID, r.Row.Name, r.Row.Brand, r.Row.State, r.Err.Error()})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func readRows(r io.Reader, opts Options, fn func(Row, error) error) error {
+	for column, field := range opts.Columns {
+		if !isField(field) {
+			return fmt.Errorf("%w: %q for column %q", ErrUnknownField, field, column)
+		}
+	}
+	switch opts.Format {
+	case FormatCSV:
+		return readCSV(r, opts.Columns, fn)
+	case FormatNDJSON:
+		return readNDJSON(r, opts.Columns, fn)
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownFormat, opts.Format)
+}
+
+func readCSV(r io.Reader, columns map[string]string, fn func(Row, error) error) error {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return fmt.Errorf("%w: the file has no header", ErrMissingColumn)
+	}
+	if err != nil {
+		return err
+	}
+	// Spreadsheet exports often start with a byte order mark.
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	fields := make([]string, len(header))
+	for i, column := range header {
+		fields[i] = fieldFor(columns, strings.TrimSpace(column))
+	}
+	if err := requireFields(fields); err != nil {
+		return err
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if err := fn(Row{Line: parseErr.StartLine}, fmt.Errorf("%w: %v", ErrMalformedRow, parseErr.Err)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := Row{Line: line}
+		if len(record) != len(fields) {
+			if err := fn(row, fmt.Errorf("%w: %d columns, expected %d", ErrMalformedRow, len(record), len(fields))); err != nil {
+				return err
+			}
+			continue
+		}
+		for i, value := range record {
+			setField(&row, fields[i], strings.TrimSpace(value))
+		}
+		if err := fn(row, nil); err != nil {
+			return err
+		}
+	}
+}
+
+func readNDJSON(r io.Reader, columns map[string]string, fn func(Row, error) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		row := Row{Line: line}
+
+		var object map[string]interface{}
+		if err := json.Unmarshal(data, &object); err != nil {
+			if err := fn(row, fmt.Errorf("%w: %v", ErrMalformedRow, err)); err != nil {
+				return err
+			}
+			continue
+		}
+		var err error
+		for key, value := range object {
+			field := fieldFor(columns, key)
+			if field == "" || value == nil {
+				continue
+			}
+			text, ok := value.(string)
+			if !ok {
+				err = fmt.Errorf("%w: %s must be a string", ErrMalformedRow, key)
+				break
+			}
+			setField(&row, field, strings.TrimSpace(text))
+		}
+		if err := fn(row, err); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// fieldFor returns the device field a column maps to, or "" for columns
+// that are not imported.
+func fieldFor(columns map[string]string, column string) string {
+	if field, ok := columns[column]; ok {
+		return field
+	}
+	if field := strings.ToLower(column); isField(field) {
+		return field
+	}
+	return ""
+}
+
+func requireFields(fields []string) error {
+	for _, required := range []string{"name", "brand"} {
+		found := false
+		for _, field := range fields {
+			found = found || field == required
+		}
+		if !found {
+			return fmt.Errorf("%w: no column maps to %s", ErrMissingColumn, required)
+		}
+	}
+	return nil
+}
+
+func isField(name string) bool {
+	for _, field := range Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+func setField(row *Row, field, value string) {
+	switch field {
+	case "id":
+		row.ID = value
+	case "name":
+		row.Name = value
+	case "brand":
+		row.Brand = value
+	case "state":
+		row.State = value
+	}
+}
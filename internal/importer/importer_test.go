@@ -0,0 +1,108 @@
+package importer
+
+import (
+	"context"
+	"device-api/internal/repository"
+	"device-api/internal/service"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type parsedRow struct {
+	Row Row
+	Err error
+}
+
+func parse(t *testing.T, input string, opts Options) ([]parsedRow, error) {
+	t.Helper()
+	var rows []parsedRow
+	err := readRows(strings.NewReader(input), opts, func(row Row, err error) error {
+		rows = append(rows, parsedRow{row, err})
+		return nil
+	})
+	return rows, err
+}
+
+func TestReadCSV(t *testing.T) {
+	t.Run("maps_columns", func(t *testing.T) {
+		input := "\ufeffAsset Tag,Name,BRAND,Location\n" +
+			"A-1,Pixel 8,Google,Lab 1\n" +
+			"A-2,\"iPhone, 15\",Apple\n"
+		rows, err := parse(t, input, Options{Format: FormatCSV, Columns: map[string]string{"Asset Tag": "id"}})
+		assert.NoError(t, err)
+		assert.Len(t, rows, 2)
+		assert.Equal(t, Row{Line: 2, ID: "A-1", Name: "Pixel 8", Brand: "Google"}, rows[0].Row)
+		assert.NoError(t, rows[0].Err)
+		assert.Equal(t, 3, rows[1].Row.Line)
+		assert.ErrorIs(t, rows[1].Err, ErrMalformedRow)
+	})
+
+	t.Run("requires_name_and_brand", func(t *testing.T) {
+		_, err := parse(t, "id,name\n1,Pixel\n", Options{Format: FormatCSV})
+		assert.ErrorIs(t, err, ErrMissingColumn)
+	})
+
+	t.Run("rejects_unknown_target_field", func(t *testing.T) {
+		_, err := parse(t, "name,brand\n", Options{Format: FormatCSV, Columns: map[string]string{"Owner": "assignee"}})
+		assert.ErrorIs(t, err, ErrUnknownField)
+	})
+}
+
+func TestReadNDJSON(t *testing.T) {
+	input := `{"id":"1","name":"Pixel 8","brand":"Google","color":"black"}` + "\n" +
+		"\n" +
+		`{"name":"iPhone","brand":7}` + "\n" +
+		`not json` + "\n"
+	rows, err := parse(t, input, Options{Format: FormatNDJSON})
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, Row{Line: 1, ID: "1", Name: "Pixel 8", Brand: "Google"}, rows[0].Row)
+	assert.Equal(t, 3, rows[1].Row.Line)
+	assert.True(t, errors.Is(rows[1].Err, ErrMalformedRow))
+	assert.Equal(t, 4, rows[2].Row.Line)
+	assert.ErrorIs(t, rows[2].Err, ErrMalformedRow)
+}
+
+// countingReader counts the bytes read from it.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestImportStopsWhenContextEnds(t *testing.T) {
+	input := "id,name,brand\n" + strings.Repeat("x,Pixel,Google\n", 100000)
+	r := &countingReader{r: strings.NewReader(input)}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	im := New(service.NewDeviceService(repository.NewMemoryRepository(repository.NewMemoryStore())))
+	_, err := im.Import(ctx, r, Options{Format: FormatCSV})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, r.n, len(input), "the rest of the file is not read")
+}
+
+func TestParseFormat(t *testing.T) {
+	for input, want := range map[string]Format{
+		"csv":                     FormatCSV,
+		"inventory.CSV":           FormatCSV,
+		"text/csv; charset=utf-8": FormatCSV,
+		"application/x-ndjson":    FormatNDJSON,
+		"devices.jsonl":           FormatNDJSON,
+	} {
+		got, err := ParseFormat(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+	_, err := ParseFormat("xlsx")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
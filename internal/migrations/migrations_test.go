@@ -0,0 +1,293 @@
+package migrations
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"device-api/internal/repository"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var ctx = context.Background()
+
+// testFiles are small SQLite migrations for testing the migrator itself.
+var testFiles = fstest.MapFS{
+	"0001_create_things.up.sql":   {Data: []byte("CREATE TABLE things (id text PRIMARY KEY);")},
+	"0001_create_things.down.sql": {Data: []byte("DROP TABLE things;")},
+	"0002_add_name.up.sql":        {Data: []byte("ALTER TABLE things ADD COLUMN name text;\nCREATE INDEX idx_things_name ON things (name);")},
+	"0002_add_name.down.sql":      {Data: []byte("DROP INDEX idx_things_name;\nALTER TABLE things DROP COLUMN name;")},
+	"README.md":                   {Data: []byte("ignored")},
+}
+
+func openSQLite(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+uuid.NewString()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func versions(migrations []Migration) []int {
+	out := []int{}
+	for _, m := range migrations {
+		out = append(out, m.Version)
+	}
+	return out
+}
+
+func TestUpDownAndStatus(t *testing.T) {
+	db := openSQLite(t)
+	m, err := New(db, testFiles)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, m.Check(ctx), ErrSchemaBehind)
+
+	done, err := m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, versions(done))
+	assert.NoError(t, m.Check(ctx))
+	assert.NoError(t, db.Exec("INSERT INTO things (id, name) VALUES ('a', 'A')").Error)
+
+	done, err = m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, done, "applied migrations are not run again")
+
+	done, err = m.Down(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2}, versions(done))
+	assert.Error(t, db.Exec("INSERT INTO things (id, name) VALUES ('b', 'B')").Error)
+
+	statuses, err := m.Status(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 2) {
+		assert.Equal(t, "0001_create_things", statuses[0].String())
+		assert.NotNil(t, statuses[0].AppliedAt)
+		assert.Nil(t, statuses[1].AppliedAt)
+	}
+	assert.ErrorIs(t, m.Check(ctx), ErrSchemaBehind)
+
+	done, err = m.Down(ctx, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, versions(done))
+	assert.False(t, db.Migrator().HasTable("things"))
+}
+
+func TestFailingMigrationIsRolledBack(t *testing.T) {
+	files := fstest.MapFS{
+		"0001_create_things.up.sql":   testFiles["0001_create_things.up.sql"],
+		"0001_create_things.down.sql": testFiles["0001_create_things.down.sql"],
+		"0002_broken.up.sql":          {Data: []byte("CREATE TABLE others (id text);\nALTER TABLE missing ADD COLUMN x text;")},
+		"0002_broken.down.sql":        {Data: []byte("DROP TABLE others;")},
+	}
+	db := openSQLite(t)
+	m, err := New(db, files)
+	assert.NoError(t, err)
+
+	done, err := m.Up(ctx)
+	assert.ErrorContains(t, err, "0002_broken")
+	assert.Equal(t, []int{1}, versions(done))
+	assert.False(t, db.Migrator().HasTable("others"))
+
+	statuses, _ := m.Status(ctx)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+}
+
+func TestNewRejectsIncompleteMigrations(t *testing.T) {
+	_, err := New(nil, fstest.MapFS{"0001_a.up.sql": {Data: []byte("SELECT 1;")}})
+	assert.ErrorContains(t, err, "0001_a")
+
+	_, err = New(nil, fstest.MapFS{
+		"0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+		"0001_b.down.sql": {Data: []byte("SELECT 1;")},
+	})
+	assert.Error(t, err)
+}
+
+func TestPostgresMigrations(t *testing.T) {
+	m, err := New(nil, Postgres())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m.migrations)
+	for i, migration := range m.migrations {
+		assert.Equal(t, i+1, migration.Version, "versions are consecutive")
+	}
+}
+
+func TestSQLiteMirrorsPostgres(t *testing.T) {
+	pg, err := New(nil, Postgres())
+	assert.NoError(t, err)
+	lite, err := New(nil, SQLite())
+	assert.NoError(t, err)
+	names := func(m *Migrator) []string {
+		out := []string{}
+		for _, migration := range m.migrations {
+			out = append(out, migration.String())
+		}
+		return out
+	}
+	assert.Equal(t, names(pg), names(lite))
+}
+
+func TestSQLiteRoundTrip(t *testing.T) {
+	testRoundTrip(t, openSQLite(t), SQLite())
+}
+
+func TestPostgresRoundTrip(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	testRoundTrip(t, openPostgres(t, dsn), Postgres())
+}
+
+// testRoundTrip applies every migration in fsys, rolls them all back and
+// applies them again, checking the schema after each pass.
+func testRoundTrip(t *testing.T, db *gorm.DB, fsys fs.FS) {
+	tables := []string{"devices", "reservations", "device_history_entries", "id_sequences", "webhooks", "webhook_deliveries", "outbox_messages"}
+	m, err := New(db, fsys)
+	assert.NoError(t, err)
+
+	for pass := 0; pass < 2; pass++ {
+		done, err := m.Up(ctx)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Len(t, done, len(m.migrations))
+		assert.NoError(t, m.Check(ctx))
+		for _, table := range tables {
+			assert.True(t, db.Migrator().HasTable(table), table)
+		}
+		assert.True(t, db.Migrator().HasIndex("devices", "idx_devices_listing"))
+		assert.True(t, db.Migrator().HasColumn("outbox_messages", "dead_lettered_at"))
+
+		// Rows inserted without a version start at the first one.
+		assert.NoError(t, db.Exec("INSERT INTO devices (id, name) VALUES ('a', 'A')").Error)
+		var version int64
+		assert.NoError(t, db.Raw("SELECT version FROM devices WHERE id = 'a'").Scan(&version).Error)
+		assert.Equal(t, int64(1), version)
+		var attempts int64
+		assert.NoError(t, db.Exec("INSERT INTO outbox_messages (device_id, event_type, payload) VALUES ('a', 'created', '{}')").Error)
+		assert.NoError(t, db.Raw("SELECT attempts FROM outbox_messages").Scan(&attempts).Error)
+		assert.Equal(t, int64(0), attempts)
+
+		done, err = m.Down(ctx, len(m.migrations))
+		assert.NoError(t, err)
+		assert.Len(t, done, len(m.migrations))
+		for _, table := range tables {
+			assert.False(t, db.Migrator().HasTable(table), table)
+		}
+	}
+}
+
+func TestSQLiteUpgradesBaselineSchema(t *testing.T) {
+	testBaselineUpgrade(t, openSQLite(t), SQLite())
+}
+
+func TestPostgresUpgradesBaselineSchema(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	testBaselineUpgrade(t, openPostgres(t, dsn), Postgres())
+}
+
+// baselineDevice is a device as the releases before migrations stored it.
+type baselineDevice struct {
+	ID        string `gorm:"primaryKey"`
+	Name      string
+	Brand     string
+	State     string
+	CreatedAt time.Time
+}
+
+func (baselineDevice) TableName() string {
+	return "devices"
+}
+
+// testBaselineUpgrade migrates a database that AutoMigrate set up before
+// migrations existed and changes its devices through the repository.
+func testBaselineUpgrade(t *testing.T, db *gorm.DB, fsys fs.FS) {
+	if err := db.AutoMigrate(&baselineDevice{}); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, db.Create([]baselineDevice{
+		{ID: "old-1", Name: "iPhone", Brand: "Apple", State: "available", CreatedAt: time.Now()},
+		{ID: "old-2", Name: "Pixel", Brand: "Google", State: "available", CreatedAt: time.Now()},
+	}).Error)
+
+	m, err := New(db, fsys)
+	assert.NoError(t, err)
+	if _, err := m.Up(ctx); !assert.NoError(t, err) {
+		return
+	}
+
+	repo := repository.NewPostgresRepository(db)
+	device, err := repo.FindByID(ctx, "old-1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, int64(1), device.Version)
+	assert.NoError(t, device.Checkout("alice", nil))
+	assert.NoError(t, repo.Update(ctx, device))
+	device, err = repo.FindByID(ctx, "old-1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "alice", device.Assignee)
+		assert.Equal(t, int64(2), device.Version)
+	}
+
+	assert.NoError(t, repo.Delete(ctx, "old-2", 1))
+	_, err = repo.FindByID(ctx, "old-2")
+	assert.ErrorIs(t, err, domain.ErrDeviceNotFound)
+}
+
+// openPostgres opens the dsn database with a schema of its own, which is
+// dropped when the test ends.
+func openPostgres(t *testing.T, dsn string) *gorm.DB {
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+	admin, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		sqlDB, _ := admin.DB()
+		sqlDB.Close()
+	})
+
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		dsn += separator + "search_path=" + schema
+	} else {
+		dsn += " search_path=" + schema
+	}
+	db, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
@@ -0,0 +1,83 @@
+-- The schema as created by GORM's AutoMigrate before migrations were
+-- introduced. Every statement tolerates existing objects, so databases set
+-- up that way adopt this migration.
+
+-- The first releases only stored these columns of devices; the others are
+-- added to existing tables, and existing rows start at version 1.
+CREATE TABLE IF NOT EXISTS devices (
+    id text PRIMARY KEY,
+    name text,
+    brand text,
+    state text,
+    created_at timestamptz
+);
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS assignee text;
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS checked_out_at timestamptz;
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS expected_return_at timestamptz;
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS idx_devices_deleted_at ON devices (deleted_at);
+
+CREATE TABLE IF NOT EXISTS reservations (
+    id text PRIMARY KEY,
+    device_id text,
+    reserved_by text,
+    starts_at timestamptz,
+    ends_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_reservations_device_id ON reservations (device_id);
+
+CREATE TABLE IF NOT EXISTS device_history_entries (
+    id bigserial PRIMARY KEY,
+    device_id text,
+    action text,
+    old_value text,
+    new_value text,
+    actor text,
+    occurred_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_device_history_entries_device_id ON device_history_entries (device_id);
+
+CREATE TABLE IF NOT EXISTS id_sequences (
+    name text PRIMARY KEY,
+    value bigint NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id text PRIMARY KEY,
+    url text,
+    secret text,
+    event_types text,
+    states text,
+    active boolean,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id text PRIMARY KEY,
+    webhook_id text,
+    event_type text,
+    device_id text,
+    payload text,
+    status text,
+    attempts bigint,
+    response_status bigint,
+    last_error text,
+    next_attempt_at timestamptz,
+    created_at timestamptz,
+    delivered_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id bigserial PRIMARY KEY,
+    device_id text,
+    event_type text,
+    payload text,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_device_id ON outbox_messages (device_id);
@@ -0,0 +1,3 @@
+-- Serves the default listing order, creation time then ID, of the devices
+-- that are not deleted, including its keyset pagination.
+CREATE INDEX idx_devices_listing ON devices (created_at, id) WHERE deleted_at IS NULL;
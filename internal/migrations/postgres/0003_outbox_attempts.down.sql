@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_outbox_messages_dead_lettered_at;
+ALTER TABLE outbox_messages
+    DROP COLUMN dead_lettered_at,
+    DROP COLUMN last_error,
+    DROP COLUMN attempts;
@@ -0,0 +1,7 @@
+-- Counts the failed attempts at relaying an outbox message and marks the
+-- messages the relay gave up on.
+ALTER TABLE outbox_messages
+    ADD COLUMN attempts bigint NOT NULL DEFAULT 0,
+    ADD COLUMN last_error text,
+    ADD COLUMN dead_lettered_at timestamptz;
+CREATE INDEX idx_outbox_messages_dead_lettered_at ON outbox_messages (dead_lettered_at);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS outbox_messages;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS id_sequences;
+DROP TABLE IF EXISTS device_history_entries;
+DROP TABLE IF EXISTS reservations;
+DROP TABLE IF EXISTS devices;
@@ -0,0 +1,83 @@
+-- The SQLite counterpart of postgres/0001_initial_schema, for tests. Times
+-- are datetime columns, the only type the driver reads back as time.
+
+-- SQLite has no ADD COLUMN IF NOT EXISTS, so the columns added after the
+-- first releases are always added, to the table those releases created or
+-- to a new one.
+CREATE TABLE IF NOT EXISTS devices (
+    id text PRIMARY KEY,
+    name text,
+    brand text,
+    state text,
+    created_at datetime
+);
+ALTER TABLE devices ADD COLUMN assignee text;
+ALTER TABLE devices ADD COLUMN checked_out_at datetime;
+ALTER TABLE devices ADD COLUMN expected_return_at datetime;
+ALTER TABLE devices ADD COLUMN deleted_at datetime;
+ALTER TABLE devices ADD COLUMN version integer NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS idx_devices_deleted_at ON devices (deleted_at);
+
+CREATE TABLE IF NOT EXISTS reservations (
+    id text PRIMARY KEY,
+    device_id text,
+    reserved_by text,
+    starts_at datetime,
+    ends_at datetime,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_reservations_device_id ON reservations (device_id);
+
+CREATE TABLE IF NOT EXISTS device_history_entries (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    device_id text,
+    action text,
+    old_value text,
+    new_value text,
+    actor text,
+    occurred_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_device_history_entries_device_id ON device_history_entries (device_id);
+
+CREATE TABLE IF NOT EXISTS id_sequences (
+    name text PRIMARY KEY,
+    value integer NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id text PRIMARY KEY,
+    url text,
+    secret text,
+    event_types text,
+    states text,
+    active boolean,
+    created_at datetime,
+    updated_at datetime
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id text PRIMARY KEY,
+    webhook_id text,
+    event_type text,
+    device_id text,
+    payload text,
+    status text,
+    attempts integer,
+    response_status integer,
+    last_error text,
+    next_attempt_at datetime,
+    created_at datetime,
+    delivered_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    device_id text,
+    event_type text,
+    payload text,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_device_id ON outbox_messages (device_id);
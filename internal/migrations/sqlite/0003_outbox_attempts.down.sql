@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_outbox_messages_dead_lettered_at;
+ALTER TABLE outbox_messages DROP COLUMN dead_lettered_at;
+ALTER TABLE outbox_messages DROP COLUMN last_error;
+ALTER TABLE outbox_messages DROP COLUMN attempts;
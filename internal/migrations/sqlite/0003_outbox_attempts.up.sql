@@ -0,0 +1,5 @@
+-- SQLite adds one column per statement.
+ALTER TABLE outbox_messages ADD COLUMN attempts integer NOT NULL DEFAULT 0;
+ALTER TABLE outbox_messages ADD COLUMN last_error text;
+ALTER TABLE outbox_messages ADD COLUMN dead_lettered_at datetime;
+CREATE INDEX idx_outbox_messages_dead_lettered_at ON outbox_messages (dead_lettered_at);
@@ -0,0 +1,178 @@
+// Package outbox relays the device events stored in the outbox to sinks.
+package outbox
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	DefaultPollInterval = 500 * time.Millisecond
+	DefaultMaxAttempts  = 10
+	batchSize           = 100
+)
+
+// Sink receives relayed events. Delivery is at least once: an event is sent
+// again, to every sink, when any sink failed it or the process stopped
+// before it was removed from the outbox. Sinks must tolerate duplicates.
+type Sink interface {
+	Send(ctx context.Context, event domain.DeviceEvent) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(ctx context.Context, event domain.DeviceEvent) error
+
+func (f SinkFunc) Send(ctx context.Context, event domain.DeviceEvent) error {
+	return f(ctx, event)
+}
+
+type Option func(*Relay)
+
+func WithPollInterval(interval time.Duration) Option {
+	return func(r *Relay) {
+		r.interval = interval
+	}
+}
+
+// WithMaxAttempts sets how often an event is tried before it is
+// dead-lettered: left in the outbox but no longer relayed.
+func WithMaxAttempts(n int) Option {
+	return func(r *Relay) {
+		r.maxAttempts = n
+	}
+}
+
+// WithUnitOfWork makes every batch of events a unit of work that starts by
+// locking the outbox, so several relays can run against one outbox: while
+// one of them relays a batch, the others skip their turn.
+func WithUnitOfWork(uow domain.IUnitOfWork) Option {
+	return func(r *Relay) {
+		r.uow = uow
+	}
+}
+
+// Relay drains the outbox into its sinks. Events of one device are relayed
+// in the order they were stored: when one fails, the later events of the
+// same device wait until it went through or was dead-lettered. Without a
+// unit of work only one relay may run against an outbox at a time.
+type Relay struct {
+	outbox      domain.IOutboxRepository
+	sinks       []Sink
+	interval    time.Duration
+	maxAttempts int
+	uow         domain.IUnitOfWork
+}
+
+func NewRelay(outbox domain.IOutboxRepository, sinks []Sink, opts ...Option) *Relay {
+	r := &Relay{outbox: outbox, sinks: sinks, interval: DefaultPollInterval, maxAttempts: DefaultMaxAttempts}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run relays events until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Drain(ctx); err != nil {
+			log.Printf("outbox: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain relays the pending events batch by batch and reports how many were
+// relayed. A failed event is left in the outbox, dead-lettered once it
+// failed maxAttempts times, and holds back the later events of its device
+// until the next Drain; the first failure is returned.
+func (r *Relay) Drain(ctx context.Context) (int, error) {
+	relayed := 0
+	var firstErr error
+	held := map[string]bool{}
+	for {
+		full, batch := false, 0
+		err := r.inTransaction(ctx, func(outbox domain.IOutboxRepository) error {
+			locked, err := outbox.Lock(ctx)
+			if err != nil || !locked {
+				return err
+			}
+			skip := make([]string, 0, len(held))
+			for deviceID := range held {
+				skip = append(skip, deviceID)
+			}
+			messages, err := outbox.FindPending(ctx, batchSize, skip)
+			if err != nil {
+				return err
+			}
+			full = len(messages) == batchSize
+
+			var ids []uint
+			for _, message := range messages {
+				if held[message.DeviceID] {
+					continue
+				}
+				err := r.relay(ctx, message)
+				if err == nil {
+					ids = append(ids, message.ID)
+					continue
+				}
+				if firstErr == nil {
+					firstErr = fmt.Errorf("relaying outbox message %d: %w", message.ID, err)
+				}
+				message.Fail(err, r.maxAttempts)
+				if err := outbox.RecordFailure(ctx, message); err != nil {
+					return err
+				}
+				if message.DeadLetteredAt == nil {
+					held[message.DeviceID] = true
+				} else {
+					log.Printf("outbox: dead-lettered message %d after %d attempts", message.ID, message.Attempts)
+				}
+			}
+			batch = len(ids)
+			return outbox.Remove(ctx, ids)
+		})
+		if err != nil {
+			return relayed, err
+		}
+		relayed += batch
+		// Every message of a full batch was relayed, dead-lettered or held
+		// back with its device, so the next batch holds new messages.
+		if !full || ctx.Err() != nil {
+			return relayed, firstErr
+		}
+	}
+}
+
+// inTransaction runs fn with the outbox of a new unit of work, or with the
+// relay's own outbox when it has none.
+func (r *Relay) inTransaction(ctx context.Context, fn func(outbox domain.IOutboxRepository) error) error {
+	if r.uow == nil {
+		return fn(r.outbox)
+	}
+	return r.uow.Do(ctx, func(repos domain.Repositories) error {
+		return fn(repos.Outbox)
+	})
+}
+
+func (r *Relay) relay(ctx context.Context, message *domain.OutboxMessage) error {
+	event, err := message.Event()
+	if err != nil {
+		return err
+	}
+	for _, sink := range r.sinks {
+		if err := sink.Send(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
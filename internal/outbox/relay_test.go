@@ -0,0 +1,173 @@
+package outbox
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryOutbox is an IOutboxRepository over a slice.
+type memoryOutbox struct {
+	messages []*domain.OutboxMessage
+}
+
+func (o *memoryOutbox) Append(_ context.Context, message *domain.OutboxMessage) error {
+	message.ID = uint(len(o.messages) + 1)
+	o.messages = append(o.messages, message)
+	return nil
+}
+
+func (o *memoryOutbox) Lock(context.Context) (bool, error) {
+	return true, nil
+}
+
+func (o *memoryOutbox) FindPending(_ context.Context, limit int, skip []string) ([]*domain.OutboxMessage, error) {
+	var pending []*domain.OutboxMessage
+	for _, message := range o.messages {
+		if len(pending) < limit && message.DeadLetteredAt == nil && !slices.Contains(skip, message.DeviceID) {
+			pending = append(pending, message)
+		}
+	}
+	return pending, nil
+}
+
+func (o *memoryOutbox) Remove(_ context.Context, ids []uint) error {
+	o.messages = slices.DeleteFunc(o.messages, func(m *domain.OutboxMessage) bool {
+		return slices.Contains(ids, m.ID)
+	})
+	return nil
+}
+
+func (o *memoryOutbox) RecordFailure(context.Context, *domain.OutboxMessage) error {
+	return nil
+}
+
+func (o *memoryOutbox) append(t *testing.T, deviceID string, action domain.HistoryAction) {
+	message, err := domain.NewOutboxMessage(domain.NewDeviceEvent(deviceID, action, nil, nil, ""))
+	assert.NoError(t, err)
+	o.Append(context.Background(), message)
+}
+
+func TestRelayKeepsOrderPerDevice(t *testing.T) {
+	store := &memoryOutbox{}
+	store.append(t, "a", domain.HistoryActionCreated)
+	store.append(t, "b", domain.HistoryActionCreated)
+	store.append(t, "a", domain.HistoryActionUpdated)
+	store.append(t, "b", domain.HistoryActionDeleted)
+
+	var sent []string
+	failing := "a"
+	sink := SinkFunc(func(_ context.Context, event domain.DeviceEvent) error {
+		if event.DeviceID == failing {
+			return errors.New("sink unavailable")
+		}
+		sent = append(sent, event.DeviceID+":"+string(event.Action))
+		return nil
+	})
+	relay := NewRelay(store, []Sink{sink})
+
+	// Device a is held back after its first event failed; b goes on.
+	relayed, err := relay.Drain(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 2, relayed)
+	assert.Equal(t, []string{"b:created", "b:deleted"}, sent)
+	assert.Len(t, store.messages, 2)
+
+	failing = ""
+	relayed, err = relay.Drain(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, relayed)
+	assert.Equal(t, []string{"b:created", "b:deleted", "a:created", "a:updated"}, sent)
+	assert.Empty(t, store.messages)
+}
+
+func TestRelayResendsToEverySinkAfterAFailure(t *testing.T) {
+	store := &memoryOutbox{}
+	store.append(t, "a", domain.HistoryActionCreated)
+
+	delivered := 0
+	first := SinkFunc(func(context.Context, domain.DeviceEvent) error {
+		delivered++
+		return nil
+	})
+	fail := true
+	second := SinkFunc(func(context.Context, domain.DeviceEvent) error {
+		if fail {
+			return errors.New("sink unavailable")
+		}
+		return nil
+	})
+	relay := NewRelay(store, []Sink{first, second})
+
+	_, err := relay.Drain(context.Background())
+	assert.Error(t, err)
+	fail = false
+	_, err = relay.Drain(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+	assert.Empty(t, store.messages)
+}
+
+func TestRelayDeadLettersAfterMaxAttempts(t *testing.T) {
+	store := &memoryOutbox{}
+	store.append(t, "a", domain.HistoryActionCreated)
+	store.messages[0].Payload = "not json"
+	store.append(t, "a", domain.HistoryActionUpdated)
+
+	var sent []string
+	sink := SinkFunc(func(_ context.Context, event domain.DeviceEvent) error {
+		sent = append(sent, event.DeviceID+":"+string(event.Action))
+		return nil
+	})
+	relay := NewRelay(store, []Sink{sink}, WithMaxAttempts(2))
+
+	relayed, err := relay.Drain(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 0, relayed)
+	assert.Equal(t, 1, store.messages[0].Attempts)
+	assert.Nil(t, store.messages[0].DeadLetteredAt)
+
+	// The second failure dead-letters the message and lets the next event
+	// of the device through.
+	relayed, err = relay.Drain(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 1, relayed)
+	assert.Equal(t, []string{"a:updated"}, sent)
+	if assert.Len(t, store.messages, 1) {
+		assert.NotNil(t, store.messages[0].DeadLetteredAt)
+		assert.NotEmpty(t, store.messages[0].LastError)
+	}
+
+	relayed, err = relay.Drain(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, relayed)
+}
+
+func TestRelayGetsPastAFullBatchOfHeldEvents(t *testing.T) {
+	store := &memoryOutbox{}
+	for range batchSize + 10 {
+		store.append(t, "a", domain.HistoryActionUpdated)
+	}
+	store.append(t, "b", domain.HistoryActionCreated)
+
+	var sent []string
+	sink := SinkFunc(func(_ context.Context, event domain.DeviceEvent) error {
+		if event.DeviceID == "a" {
+			return errors.New("sink unavailable")
+		}
+		sent = append(sent, event.DeviceID+":"+string(event.Action))
+		return nil
+	})
+	relay := NewRelay(store, []Sink{sink})
+
+	relayed, err := relay.Drain(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 1, relayed)
+	assert.Equal(t, []string{"b:created"}, sent)
+	assert.Equal(t, 1, store.messages[0].Attempts, "only the first event of a held device is tried")
+	assert.Zero(t, store.messages[1].Attempts)
+}
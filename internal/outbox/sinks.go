@@ -0,0 +1,35 @@
+package outbox
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// PublisherSink hands events to an in-process publisher such as the events
+// broker. It never fails.
+func PublisherSink(publisher domain.IEventPublisher) Sink {
+	return SinkFunc(func(_ context.Context, event domain.DeviceEvent) error {
+		publisher.Publish(event)
+		return nil
+	})
+}
+
+// WriterSink writes every event as one line of JSON, e.g. to stdout or an
+// append-only file.
+type WriterSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{enc: json.NewEncoder(w)}
+}
+
+func (s *WriterSink) Send(_ context.Context, event domain.DeviceEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(event)
+}
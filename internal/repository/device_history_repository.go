@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"device-api/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type PostgresDeviceHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresDeviceHistoryRepository(db *gorm.DB) *PostgresDeviceHistoryRepository {
+	return &PostgresDeviceHistoryRepository{db: db}
+}
+
+func (r *PostgresDeviceHistoryRepository) Append(ctx context.Context, entry *domain.DeviceHistoryEntry) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *PostgresDeviceHistoryRepository) FindByDevice(ctx context.Context, deviceID string, limit, offset int) ([]*domain.DeviceHistoryEntry, int64, error) {
+	var total int64
+	query := r.db.WithContext(ctx).Model(&domain.DeviceHistoryEntry{}).Where("device_id = ?", deviceID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []*domain.DeviceHistoryEntry
+	result := r.db.WithContext(ctx).
+		Where("device_id = ?", deviceID).
+		Order("id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&entries)
+	return entries, total, result.Error
+}
@@ -0,0 +1,173 @@
+package repository_test
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"device-api/internal/migrations"
+	"device-api/internal/repository"
+	"device-api/internal/repository/repositorytest"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestPostgresRepositoryConformance(t *testing.T) {
+	gormDatabases(t, func(t *testing.T, open func(t *testing.T) *gorm.DB) {
+		repositorytest.TestDeviceRepository(t, func(t *testing.T) domain.IDeviceRepository {
+			return repository.NewPostgresRepository(open(t))
+		})
+	})
+}
+
+func TestMemoryRepositoryConformance(t *testing.T) {
+	repositorytest.TestDeviceRepository(t, func(t *testing.T) domain.IDeviceRepository {
+		return repository.NewMemoryRepository(repository.NewMemoryStore())
+	})
+}
+
+func TestPostgresReservationRepositoryConformance(t *testing.T) {
+	gormDatabases(t, func(t *testing.T, open func(t *testing.T) *gorm.DB) {
+		repositorytest.TestReservationRepository(t, func(t *testing.T) domain.IReservationRepository {
+			return repository.NewPostgresReservationRepository(open(t))
+		})
+	})
+}
+
+func TestMemoryReservationRepositoryConformance(t *testing.T) {
+	repositorytest.TestReservationRepository(t, func(t *testing.T) domain.IReservationRepository {
+		return repository.NewMemoryReservationRepository(repository.NewMemoryStore())
+	})
+}
+
+func TestPostgresDeviceHistoryRepositoryConformance(t *testing.T) {
+	gormDatabases(t, func(t *testing.T, open func(t *testing.T) *gorm.DB) {
+		repositorytest.TestDeviceHistoryRepository(t, func(t *testing.T) domain.IDeviceHistoryRepository {
+			return repository.NewPostgresDeviceHistoryRepository(open(t))
+		})
+	})
+}
+
+func TestMemoryDeviceHistoryRepositoryConformance(t *testing.T) {
+	repositorytest.TestDeviceHistoryRepository(t, func(t *testing.T) domain.IDeviceHistoryRepository {
+		return repository.NewMemoryDeviceHistoryRepository(repository.NewMemoryStore())
+	})
+}
+
+func TestUnitOfWorkConformance(t *testing.T) {
+	gormDatabases(t, func(t *testing.T, open func(t *testing.T) *gorm.DB) {
+		repositorytest.TestUnitOfWork(t, func(t *testing.T) (domain.IUnitOfWork, domain.Repositories) {
+			db := open(t)
+			return repository.NewUnitOfWork(db), domain.Repositories{
+				Devices:      repository.NewPostgresRepository(db),
+				Reservations: repository.NewPostgresReservationRepository(db),
+				History:      repository.NewPostgresDeviceHistoryRepository(db),
+			}
+		})
+	})
+}
+
+func TestMemoryUnitOfWorkConformance(t *testing.T) {
+	repositorytest.TestUnitOfWork(t, func(t *testing.T) (domain.IUnitOfWork, domain.Repositories) {
+		store := repository.NewMemoryStore()
+		return repository.NewMemoryUnitOfWork(store), domain.Repositories{
+			Devices:      repository.NewMemoryRepository(store),
+			Reservations: repository.NewMemoryReservationRepository(store),
+			History:      repository.NewMemoryDeviceHistoryRepository(store),
+		}
+	})
+}
+
+// gormDatabases runs the tests of the GORM repositories on SQLite and, when
+// TEST_DATABASE_URL names one, on PostgreSQL. SQLite alone cannot catch
+// every difference from the memory repositories: it compares text
+// byte-wise and its LIKE ignores ASCII case, where PostgreSQL follows the
+// collation of the database.
+func gormDatabases(t *testing.T, run func(t *testing.T, open func(t *testing.T) *gorm.DB)) {
+	t.Run("sqlite", func(t *testing.T) {
+		run(t, openSQLite)
+	})
+	t.Run("postgres", func(t *testing.T) {
+		if os.Getenv("TEST_DATABASE_URL") == "" {
+			t.Skip("TEST_DATABASE_URL is not set")
+		}
+		run(t, openPostgres)
+	})
+}
+
+// openSQLite opens a fresh in-memory database. Its single connection keeps
+// the database alive and serializes writers, which shared-cache SQLite
+// would otherwise reject with "table is locked".
+func openSQLite(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+uuid.NewString()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	migrate(t, db, migrations.SQLite())
+	return db
+}
+
+// openPostgres migrates a schema of its own in the TEST_DATABASE_URL
+// database and drops it when the test ends.
+func openPostgres(t *testing.T) *gorm.DB {
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	admin, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		sqlDB, _ := admin.DB()
+		sqlDB.Close()
+	})
+
+	db, err := gorm.Open(postgres.Open(withSearchPath(dsn, schema)), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	migrate(t, db, migrations.Postgres())
+	return db
+}
+
+// migrate applies the migrations in fsys, so that the tests run against the
+// schema the service is deployed with.
+func migrate(t *testing.T, db *gorm.DB, fsys fs.FS) {
+	migrator, err := migrations.New(db, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// withSearchPath points the connections of dsn, a URL or a key/value
+// connection string, at schema.
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		return dsn + separator + "search_path=" + schema
+	}
+	return dsn + " search_path=" + schema
+}
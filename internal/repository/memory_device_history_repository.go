@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"device-api/internal/domain"
+)
+
+// MemoryDeviceHistoryRepository keeps history entries in a MemoryStore, in
+// the order they were appended. Entries are numbered from 1 like the rows
+// of PostgresDeviceHistoryRepository.
+type MemoryDeviceHistoryRepository struct {
+	memoryAccess
+}
+
+func NewMemoryDeviceHistoryRepository(store *MemoryStore) *MemoryDeviceHistoryRepository {
+	return &MemoryDeviceHistoryRepository{memoryAccess: memoryAccess{store: store}}
+}
+
+func (r *MemoryDeviceHistoryRepository) Append(ctx context.Context, entry *domain.DeviceHistoryEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	defer r.lock()()
+	entry.ID = uint(len(r.store.history) + 1)
+	copied := *entry
+	r.store.history = append(r.store.history, &copied)
+	return nil
+}
+
+func (r *MemoryDeviceHistoryRepository) FindByDevice(ctx context.Context, deviceID string, limit, offset int) ([]*domain.DeviceHistoryEntry, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	defer r.rlock()()
+	matching := []*domain.DeviceHistoryEntry{}
+	for i := len(r.store.history) - 1; i >= 0; i-- {
+		if entry := r.store.history[i]; entry.DeviceID == deviceID {
+			copied := *entry
+			matching = append(matching, &copied)
+		}
+	}
+
+	total := int64(len(matching))
+	matching = matching[min(max(offset, 0), len(matching)):]
+	if limit >= 0 && limit < len(matching) {
+		matching = matching[:limit]
+	}
+	return matching, total, nil
+}
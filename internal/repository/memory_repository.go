@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"slices"
+	"time"
+)
+
+// MemoryRepository keeps devices in a MemoryStore. It behaves like
+// PostgresRepository, down to versions and soft deletion, and is safe for
+// concurrent use, but everything is lost when the process exits. Stored
+// devices are copied on the way in and out, so callers never share them.
+type MemoryRepository struct {
+	memoryAccess
+	unscoped bool
+}
+
+func NewMemoryRepository(store *MemoryStore) *MemoryRepository {
+	return &MemoryRepository{memoryAccess: memoryAccess{store: store}}
+}
+
+func (r *MemoryRepository) Unscoped() domain.IDeviceRepository {
+	return &MemoryRepository{memoryAccess: r.memoryAccess, unscoped: true}
+}
+
+// visible returns the stored device id unless this repository cannot see
+// it. The caller must hold the lock.
+func (r *MemoryRepository) visible(id string) (*domain.Device, bool) {
+	device, ok := r.store.devices[id]
+	if !ok || (device.IsDeleted() && !r.unscoped) {
+		return nil, false
+	}
+	return device, true
+}
+
+// Save inserts a copy of device. Any device already stored under the same
+// ID, including a soft deleted one, makes it fail with
+// ErrDeviceAlreadyExists.
+func (r *MemoryRepository) Save(ctx context.Context, device *domain.Device) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	defer r.lock()()
+	if _, ok := r.store.devices[device.ID]; ok {
+		return domain.ErrDeviceAlreadyExists
+	}
+	if device.CreatedAt.IsZero() {
+		device.CreatedAt = time.Now()
+	}
+	if device.Version == 0 {
+		device.Version = 1
+	}
+	r.changingDevice(device.ID)
+	r.store.devices[device.ID] = copyDevice(device)
+	return nil
+}
+
+func (r *MemoryRepository) FindByID(ctx context.Context, id string) (*domain.Device, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	defer r.rlock()()
+	device, ok := r.visible(id)
+	if !ok {
+		return nil, domain.ErrDeviceNotFound
+	}
+	return copyDevice(device), nil
+}
+
+func (r *MemoryRepository) Find(ctx context.Context, q domain.DeviceQuery) (*domain.DevicePage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cursor, err := q.CursorValues()
+	if err != nil {
+		return nil, err
+	}
+	keys := q.OrderKeys()
+	var after *domain.Device
+	if cursor != nil {
+		after = cursorDevice(keys, cursor)
+	}
+
+	unlock := r.rlock()
+	var matching []*domain.Device
+	for id := range r.store.devices {
+		if device, ok := r.visible(id); ok && q.Criteria.Matches(device) {
+			matching = append(matching, copyDevice(device))
+		}
+	}
+	unlock()
+
+	page := &domain.DevicePage{Items: []*domain.Device{}}
+	if q.IncludeTotal {
+		total := int64(len(matching))
+		page.Total = &total
+	}
+	slices.SortFunc(matching, func(a, b *domain.Device) int {
+		return domain.CompareDevices(keys, a, b)
+	})
+	for _, device := range matching {
+		if after == nil || domain.CompareDevices(keys, device, after) > 0 {
+			page.Items = append(page.Items, device)
+		}
+	}
+	if q.Limit > 0 && len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		page.NextCursor = q.CursorAfter(page.Items[q.Limit-1])
+	}
+	return page, nil
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, id string, version int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	defer r.lock()()
+	device, ok := r.visible(id)
+	if !ok {
+		return domain.ErrDeviceNotFound
+	}
+	if device.Version != version {
+		return domain.ErrVersionConflict
+	}
+	r.changingDevice(id)
+	if r.unscoped {
+		delete(r.store.devices, id)
+		return nil
+	}
+	now := time.Now()
+	device.DeletedAt = &now
+	device.Version++
+	return nil
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, device *domain.Device) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	defer r.lock()()
+	stored, ok := r.visible(device.ID)
+	if !ok {
+		return domain.ErrDeviceNotFound
+	}
+	if stored.Version != device.Version {
+		return domain.ErrVersionConflict
+	}
+	r.changingDevice(device.ID)
+	device.Version++
+	updated := copyDevice(device)
+	// The ID and creation time are never written, as in PostgresRepository.
+	updated.CreatedAt = stored.CreatedAt
+	r.store.devices[device.ID] = updated
+	return nil
+}
+
+func (r *MemoryRepository) Restore(ctx context.Context, id string, version int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	defer r.lock()()
+	device, ok := r.store.devices[id]
+	if !ok || !device.IsDeleted() {
+		return domain.ErrDeviceNotFound
+	}
+	if device.Version != version {
+		return domain.ErrVersionConflict
+	}
+	r.changingDevice(id)
+	device.DeletedAt = nil
+	device.Version++
+	return nil
+}
+
+func (r *MemoryRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	defer r.lock()()
+	ids := []string{}
+	for id, device := range r.store.devices {
+		if device.IsDeleted() && device.DeletedAt.Before(cutoff) {
+			ids = append(ids, id)
+			r.changingDevice(id)
+			delete(r.store.devices, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// copyDevice copies device deeply enough that neither copy sees later
+// changes to the other.
+func copyDevice(device *domain.Device) *domain.Device {
+	copied := *device
+	copied.CheckedOutAt = copyTime(device.CheckedOutAt)
+	copied.ExpectedReturnAt = copyTime(device.ExpectedReturnAt)
+	copied.DeletedAt = copyTime(device.DeletedAt)
+	return &copied
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := *t
+	return &copied
+}
+
+// cursorDevice builds a device holding the cursor values of the order keys,
+// so that it can be compared with stored devices.
+func cursorDevice(keys []domain.DeviceSort, values []interface{}) *domain.Device {
+	device := &domain.Device{}
+	for i, key := range keys {
+		switch key.Field {
+		case domain.SortByCreatedAt:
+			device.CreatedAt = values[i].(time.Time)
+		case domain.SortByName:
+			device.Name = values[i].(string)
+		case domain.SortByBrand:
+			device.Brand = values[i].(string)
+		case domain.SortByState:
+			device.State = domain.DeviceState(values[i].(string))
+		default:
+			device.ID = values[i].(string)
+		}
+	}
+	return device
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRepositoryCopiesDevices(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository(NewMemoryStore())
+
+	device := domain.NewDevice("copy-1", "Phone", "BrandA")
+	assert.NoError(t, repo.Save(ctx, device))
+	device.Name = "Changed after saving"
+
+	found, err := repo.FindByID(ctx, "copy-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Phone", found.Name)
+
+	returnAt := time.Now().Add(time.Hour)
+	assert.NoError(t, found.Checkout("alice", &returnAt))
+	assert.NoError(t, repo.Update(ctx, found))
+	*found.ExpectedReturnAt = time.Time{}
+
+	stored, _ := repo.FindByID(ctx, "copy-1")
+	assert.False(t, stored.ExpectedReturnAt.IsZero())
+}
+
+func TestMemoryRepositoryHonoursContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewMemoryRepository(NewMemoryStore()).FindByID(ctx, "any")
+	assert.ErrorIs(t, err, context.Canceled)
+}
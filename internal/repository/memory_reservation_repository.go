@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"slices"
+	"time"
+)
+
+// MemoryReservationRepository keeps reservations in a MemoryStore. Stored
+// reservations are copied on the way in and out.
+type MemoryReservationRepository struct {
+	memoryAccess
+}
+
+func NewMemoryReservationRepository(store *MemoryStore) *MemoryReservationRepository {
+	return &MemoryReservationRepository{memoryAccess: memoryAccess{store: store}}
+}
+
+func (r *MemoryReservationRepository) Save(ctx context.Context, reservation *domain.Reservation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	defer r.lock()()
+	copied := *reservation
+	r.changingReservation(reservation.ID)
+	r.store.reservations[reservation.ID] = &copied
+	return nil
+}
+
+func (r *MemoryReservationRepository) FindByID(ctx context.Context, id string) (*domain.Reservation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	defer r.rlock()()
+	reservation, ok := r.store.reservations[id]
+	if !ok {
+		return nil, domain.ErrReservationNotFound
+	}
+	copied := *reservation
+	return &copied, nil
+}
+
+func (r *MemoryReservationRepository) FindByDevice(ctx context.Context, deviceID string, endingAfter time.Time) ([]*domain.Reservation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	defer r.rlock()()
+	reservations := []*domain.Reservation{}
+	for _, reservation := range r.store.reservations {
+		if reservation.DeviceID == deviceID && reservation.EndsAt.After(endingAfter) {
+			copied := *reservation
+			reservations = append(reservations, &copied)
+		}
+	}
+	slices.SortFunc(reservations, func(a, b *domain.Reservation) int {
+		return a.StartsAt.Compare(b.StartsAt)
+	})
+	return reservations, nil
+}
+
+func (r *MemoryReservationRepository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	defer r.lock()()
+	if _, ok := r.store.reservations[id]; !ok {
+		return domain.ErrReservationNotFound
+	}
+	r.changingReservation(id)
+	delete(r.store.reservations, id)
+	return nil
+}
+
+// LockDevice has nothing to lock: a MemoryUnitOfWork holds the whole store
+// until it ends.
+func (r *MemoryReservationRepository) LockDevice(ctx context.Context, deviceID string) error {
+	return ctx.Err()
+}
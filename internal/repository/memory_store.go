@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"sync"
+)
+
+// MemoryStore holds the data of the memory repositories. Repositories built
+// on the same store see each other's writes, and a MemoryUnitOfWork over
+// the store makes them atomic.
+type MemoryStore struct {
+	mu           sync.RWMutex
+	devices      map[string]*domain.Device
+	reservations map[string]*domain.Reservation
+	history      []*domain.DeviceHistoryEntry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		devices:      map[string]*domain.Device{},
+		reservations: map[string]*domain.Reservation{},
+	}
+}
+
+// memoryAccess is how a memory repository reaches its store. A unit of work
+// holds the store lock from start to end, so the repositories it hands out
+// have held set and do not take the lock again. They record what they
+// change in undo.
+type memoryAccess struct {
+	store *MemoryStore
+	held  bool
+	undo  *memoryUndo
+}
+
+// lock takes the store lock for writing and returns the function that
+// releases it.
+func (a memoryAccess) lock() (unlock func()) {
+	if a.held {
+		return func() {}
+	}
+	a.store.mu.Lock()
+	return a.store.mu.Unlock
+}
+
+// rlock takes the store lock for reading and returns the function that
+// releases it.
+func (a memoryAccess) rlock() (unlock func()) {
+	if a.held {
+		return func() {}
+	}
+	a.store.mu.RLock()
+	return a.store.mu.RUnlock
+}
+
+// memoryUndo holds the entries of a store a unit of work changed, as they
+// were before it did, so that only those are put back when it fails. A nil
+// entry was absent. Devices are changed in place, so they are copied;
+// reservations are replaced rather than changed and history only grows.
+type memoryUndo struct {
+	devices      map[string]*domain.Device
+	reservations map[string]*domain.Reservation
+	history      int
+}
+
+func (s *MemoryStore) newUndo() *memoryUndo {
+	return &memoryUndo{
+		devices:      map[string]*domain.Device{},
+		reservations: map[string]*domain.Reservation{},
+		history:      len(s.history),
+	}
+}
+
+func (s *MemoryStore) rollback(undo *memoryUndo) {
+	for id, device := range undo.devices {
+		if device == nil {
+			delete(s.devices, id)
+		} else {
+			s.devices[id] = device
+		}
+	}
+	for id, reservation := range undo.reservations {
+		if reservation == nil {
+			delete(s.reservations, id)
+		} else {
+			s.reservations[id] = reservation
+		}
+	}
+	s.history = s.history[:undo.history]
+}
+
+// changingDevice records device id before its first change in a unit of
+// work. The caller must hold the lock.
+func (a memoryAccess) changingDevice(id string) {
+	if a.undo == nil {
+		return
+	}
+	if _, ok := a.undo.devices[id]; ok {
+		return
+	}
+	var before *domain.Device
+	if device, ok := a.store.devices[id]; ok {
+		before = copyDevice(device)
+	}
+	a.undo.devices[id] = before
+}
+
+// changingReservation records reservation id before its first change in a
+// unit of work. The caller must hold the lock.
+func (a memoryAccess) changingReservation(id string) {
+	if a.undo == nil {
+		return
+	}
+	if _, ok := a.undo.reservations[id]; !ok {
+		a.undo.reservations[id] = a.store.reservations[id]
+	}
+}
+
+// MemoryUnitOfWork runs work against a MemoryStore atomically. It holds the
+// store lock while the work runs, so units of work and single repository
+// calls are serialised, and restores the store when the work fails. There
+// is no memory outbox: Repositories.Outbox is nil.
+type MemoryUnitOfWork struct {
+	store *MemoryStore
+}
+
+func NewMemoryUnitOfWork(store *MemoryStore) *MemoryUnitOfWork {
+	return &MemoryUnitOfWork{store: store}
+}
+
+func (u *MemoryUnitOfWork) Do(ctx context.Context, fn func(repos domain.Repositories) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+
+	undo := u.store.newUndo()
+	committed := false
+	defer func() {
+		if !committed {
+			u.store.rollback(undo)
+		}
+	}()
+
+	access := memoryAccess{store: u.store, held: true, undo: undo}
+	err := fn(domain.Repositories{
+		Devices:      &MemoryRepository{memoryAccess: access},
+		Reservations: &MemoryReservationRepository{memoryAccess: access},
+		History:      &MemoryDeviceHistoryRepository{memoryAccess: access},
+	})
+	if err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
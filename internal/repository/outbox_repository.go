@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"device-api/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type PostgresOutboxRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresOutboxRepository(db *gorm.DB) *PostgresOutboxRepository {
+	return &PostgresOutboxRepository{db: db}
+}
+
+func (r *PostgresOutboxRepository) Append(ctx context.Context, message *domain.OutboxMessage) error {
+	return r.db.WithContext(ctx).Create(message).Error
+}
+
+// outboxLockKey is the PostgreSQL advisory lock held by the relay draining
+// the outbox.
+const outboxLockKey = 0x6f7574626f78
+
+// Lock takes a transaction level advisory lock. A single lock rather than
+// row locks keeps the events of a device in order: relays skipping each
+// other's rows would pass later events of a device on before earlier ones.
+// Other databases are not shared between processes, so Lock always
+// succeeds there.
+func (r *PostgresOutboxRepository) Lock(ctx context.Context) (bool, error) {
+	if r.db.Dialector.Name() != "postgres" {
+		return true, nil
+	}
+	var locked bool
+	err := r.db.WithContext(ctx).Raw("SELECT pg_try_advisory_xact_lock(?)", outboxLockKey).Scan(&locked).Error
+	return locked, err
+}
+
+func (r *PostgresOutboxRepository) FindPending(ctx context.Context, limit int, skip []string) ([]*domain.OutboxMessage, error) {
+	var messages []*domain.OutboxMessage
+	query := r.db.WithContext(ctx).Where("dead_lettered_at IS NULL")
+	if len(skip) > 0 {
+		query = query.Where("device_id NOT IN ?", skip)
+	}
+	result := query.Order("id").Limit(limit).Find(&messages)
+	return messages, result.Error
+}
+
+func (r *PostgresOutboxRepository) Remove(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Delete(&domain.OutboxMessage{}, ids).Error
+}
+
+func (r *PostgresOutboxRepository) RecordFailure(ctx context.Context, message *domain.OutboxMessage) error {
+	return r.db.WithContext(ctx).Model(message).Select("attempts", "last_error", "dead_lettered_at").Updates(message).Error
+}
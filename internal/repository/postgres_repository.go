@@ -1,24 +1,45 @@
 package repository
 
 import (
+	"context"
 	"device-api/internal/domain"
 	"errors"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PostgresRepository struct {
-	db *gorm.DB
+	db       *gorm.DB
+	unscoped bool
 }
 
 func NewPostgresRepository(db *gorm.DB) *PostgresRepository {
 	return &PostgresRepository{db: db}
 }
 
-func (r *PostgresRepository) Save(device *domain.Device) error {
-	result := r.db.Create(device)
+func (r *PostgresRepository) Unscoped() domain.IDeviceRepository {
+	return &PostgresRepository{db: r.db, unscoped: true}
+}
+
+// devices returns a query over the devices visible to this repository.
+func (r *PostgresRepository) devices(ctx context.Context) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&domain.Device{})
+	if !r.unscoped {
+		query = query.Where("deleted_at IS NULL")
+	}
+	return query
+}
+
+// Save inserts a new device in a single statement. Any device already stored
+// under the same ID, including a soft deleted one, makes it fail with
+// ErrDeviceAlreadyExists.
+func (r *PostgresRepository) Save(ctx context.Context, device *domain.Device) error {
+	result := r.db.WithContext(ctx).Create(device)
 	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		if isDuplicateKey(r.db, result.Error) {
 			return domain.ErrDeviceAlreadyExists
 		}
 		return result.Error
@@ -26,9 +47,9 @@ func (r *PostgresRepository) Save(device *domain.Device) error {
 	return nil
 }
 
-func (r *PostgresRepository) FindByID(id string) (*domain.Device, error) {
+func (r *PostgresRepository) FindByID(ctx context.Context, id string) (*domain.Device, error) {
 	var device domain.Device
-	result := r.db.First(&device, "id = ?", id)
+	result := r.devices(ctx).First(&device, "id = ?", id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, domain.ErrDeviceNotFound
@@ -38,36 +59,173 @@ func (r *PostgresRepository) FindByID(id string) (*domain.Device, error) {
 	return &device, nil
 }
 
-func (r *PostgresRepository) FindAll() ([]*domain.Device, error) {
-	var devices []*domain.Device
-	result := r.db.Find(&devices)
-	return devices, result.Error
+func (r *PostgresRepository) Find(ctx context.Context, q domain.DeviceQuery) (*domain.DevicePage, error) {
+	cursor, err := q.CursorValues()
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.DevicePage{}
+	if q.IncludeTotal {
+		var total int64
+		if err := applyCriteria(r.devices(ctx), q.Criteria).Count(&total).Error; err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	keys := q.OrderKeys()
+	query := applyCriteria(r.devices(ctx), q.Criteria)
+	if cursor != nil {
+		query = applyKeyset(query, keys, cursor)
+	}
+	for _, key := range keys {
+		query = query.Order(clause.OrderByColumn{
+			Column: clause.Column{Name: string(key.Field)},
+			Desc:   key.Descending,
+		})
+	}
+	if q.Limit > 0 {
+		// Fetch one extra row to learn whether another page follows.
+		query = query.Limit(q.Limit + 1)
+	}
+
+	if err := query.Find(&page.Items).Error; err != nil {
+		return nil, err
+	}
+	if q.Limit > 0 && len(page.Items) > q.Limit {
+		page.Items = page.Items[:q.Limit]
+		page.NextCursor = q.CursorAfter(page.Items[q.Limit-1])
+	}
+	return page, nil
 }
 
-func (r *PostgresRepository) FindByBrand(brand string) ([]*domain.Device, error) {
-	var devices []*domain.Device
-	result := r.db.Where("brand = ?", brand).Find(&devices)
-	return devices, result.Error
+func (r *PostgresRepository) Delete(ctx context.Context, id string, version int64) error {
+	var result *gorm.DB
+	if r.unscoped {
+		result = r.db.WithContext(ctx).Delete(&domain.Device{}, "id = ? AND version = ?", id, version)
+	} else {
+		result = r.devices(ctx).Where("id = ? AND version = ?", id, version).Updates(map[string]interface{}{
+			"deleted_at": time.Now(),
+			"version":    gorm.Expr("version + 1"),
+		})
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := r.FindByID(ctx, id); err != nil {
+			return err
+		}
+		return domain.ErrVersionConflict
+	}
+	return nil
 }
 
-func (r *PostgresRepository) FindByState(state domain.DeviceState) ([]*domain.Device, error) {
-	var devices []*domain.Device
-	result := r.db.Where("state = ?", state).Find(&devices)
-	return devices, result.Error
+func (r *PostgresRepository) Update(ctx context.Context, device *domain.Device) error {
+	expected := device.Version
+	device.Version = expected + 1
+	result := r.devices(ctx).
+		Where("id = ? AND version = ?", device.ID, expected).
+		Select("*").
+		Omit("id", "created_at").
+		Updates(device)
+	if result.Error == nil && result.RowsAffected == 1 {
+		return nil
+	}
+
+	device.Version = expected
+	if result.Error != nil {
+		return result.Error
+	}
+	if _, err := r.FindByID(ctx, device.ID); err != nil {
+		return err
+	}
+	return domain.ErrVersionConflict
 }
 
-func (r *PostgresRepository) Delete(id string) error {
-	result := r.db.Delete(&domain.Device{}, "id = ?", id)
-    if result.Error != nil {
-        return result.Error
-    }
-    if result.RowsAffected == 0 {
-        return domain.ErrDeviceNotFound
-    }
+func (r *PostgresRepository) Restore(ctx context.Context, id string, version int64) error {
+	result := r.db.WithContext(ctx).Model(&domain.Device{}).
+		Where("id = ? AND deleted_at IS NOT NULL AND version = ?", id, version).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		device, err := r.Unscoped().FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !device.IsDeleted() {
+			return domain.ErrDeviceNotFound
+		}
+		return domain.ErrVersionConflict
+	}
 	return nil
 }
 
-func (r *PostgresRepository) Update(device *domain.Device) error {
-	result := r.db.Save(device)
-	return result.Error
+// PurgeDeletedBefore deletes and lists the devices in one statement, so a
+// device restored concurrently is neither removed nor reported.
+func (r *PostgresRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) ([]string, error) {
+	var purged []domain.Device
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Delete(&purged).Error
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(purged))
+	for i, device := range purged {
+		ids[i] = device.ID
+	}
+	return ids, nil
 }
+
+func applyCriteria(query *gorm.DB, criteria domain.DeviceCriteria) *gorm.DB {
+	if len(criteria.Brands) > 0 {
+		query = query.Where("brand IN ?", criteria.Brands)
+	}
+	if len(criteria.States) > 0 {
+		query = query.Where("state IN ?", criteria.States)
+	}
+	if criteria.NameContains != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(criteria.NameContains)) + "%"
+		query = query.Where(`LOWER(name) LIKE ? ESCAPE '\'`, pattern)
+	}
+	if criteria.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *criteria.CreatedFrom)
+	}
+	if criteria.CreatedTo != nil {
+		query = query.Where("created_at < ?", *criteria.CreatedTo)
+	}
+	return query
+}
+
+// applyKeyset restricts query to the rows that sort after cursor, expanding
+// (k1, k2, ...) > (v1, v2, ...) per key so each one can have its own
+// direction.
+func applyKeyset(query *gorm.DB, keys []domain.DeviceSort, cursor []interface{}) *gorm.DB {
+	var conditions []string
+	var args []interface{}
+	for i, key := range keys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, string(keys[j].Field)+" = ?")
+			args = append(args, cursor[j])
+		}
+		op := " > ?"
+		if key.Descending {
+			op = " < ?"
+		}
+		parts = append(parts, string(key.Field)+op)
+		args = append(args, cursor[i])
+		conditions = append(conditions, "("+strings.Join(parts, " AND ")+")")
+	}
+	return query.Where(strings.Join(conditions, " OR "), args...)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
@@ -0,0 +1,82 @@
+package repositorytest
+
+import (
+	"device-api/internal/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDeviceHistoryRepository runs the conformance tests of
+// domain.IDeviceHistoryRepository. newRepository must return an empty
+// repository every time it is called.
+func TestDeviceHistoryRepository(t *testing.T, newRepository func(t *testing.T) domain.IDeviceHistoryRepository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo domain.IDeviceHistoryRepository)
+	}{
+		{"AppendAndFind", testHistoryAppendAndFind},
+		{"Pagination", testHistoryPagination},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository(t))
+		})
+	}
+}
+
+func appendEntry(t *testing.T, repo domain.IDeviceHistoryRepository, deviceID string, action domain.HistoryAction) *domain.DeviceHistoryEntry {
+	t.Helper()
+	entry := domain.NewDeviceHistoryEntry(deviceID, action, nil, &domain.DeviceSnapshot{Name: "Pixel", State: domain.DeviceStateAvailable}, "alice")
+	if !assert.NoError(t, repo.Append(ctx, entry)) {
+		t.FailNow()
+	}
+	return entry
+}
+
+func actions(entries []*domain.DeviceHistoryEntry) []domain.HistoryAction {
+	out := []domain.HistoryAction{}
+	for _, entry := range entries {
+		out = append(out, entry.Action)
+	}
+	return out
+}
+
+func testHistoryAppendAndFind(t *testing.T, repo domain.IDeviceHistoryRepository) {
+	created := appendEntry(t, repo, "device-1", domain.HistoryActionCreated)
+	appendEntry(t, repo, "device-2", domain.HistoryActionCreated)
+	updated := appendEntry(t, repo, "device-1", domain.HistoryActionUpdated)
+	assert.NotZero(t, created.ID)
+	assert.Greater(t, updated.ID, created.ID, "entries are numbered in the order they were appended")
+
+	entries, total, err := repo.FindByDevice(ctx, "device-1", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, []domain.HistoryAction{domain.HistoryActionUpdated, domain.HistoryActionCreated}, actions(entries), "newest first")
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "alice", entries[1].Actor)
+		assert.Nil(t, entries[1].OldValue)
+		assert.Equal(t, &domain.DeviceSnapshot{Name: "Pixel", State: domain.DeviceStateAvailable}, entries[1].NewValue)
+	}
+
+	entries, total, err = repo.FindByDevice(ctx, "device-missing", 10, 0)
+	assert.NoError(t, err)
+	assert.Zero(t, total)
+	assert.Empty(t, entries)
+}
+
+func testHistoryPagination(t *testing.T, repo domain.IDeviceHistoryRepository) {
+	appendEntry(t, repo, "device-1", domain.HistoryActionCreated)
+	appendEntry(t, repo, "device-1", domain.HistoryActionUpdated)
+	appendEntry(t, repo, "device-1", domain.HistoryActionCheckedOut)
+	appendEntry(t, repo, "device-1", domain.HistoryActionCheckedIn)
+
+	entries, total, err := repo.FindByDevice(ctx, "device-1", 2, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), total, "the total ignores limit and offset")
+	assert.Equal(t, []domain.HistoryAction{domain.HistoryActionCheckedOut, domain.HistoryActionUpdated}, actions(entries))
+
+	entries, _, err = repo.FindByDevice(ctx, "device-1", 2, 4)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
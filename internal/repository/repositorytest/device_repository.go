@@ -0,0 +1,406 @@
+// Package repositorytest checks that repositories behave as the domain
+// interfaces describe them. The GORM repositories only prove PostgreSQL
+// semantics when the suites run on PostgreSQL: on SQLite, text ordering
+// and LIKE follow SQLite's rules, not the database collation.
+package repositorytest
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDeviceRepository runs the conformance tests of domain.IDeviceRepository.
+// newRepository must return an empty repository every time it is called;
+// every test starts with one of its own.
+func TestDeviceRepository(t *testing.T, newRepository func(t *testing.T) domain.IDeviceRepository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo domain.IDeviceRepository)
+	}{
+		{"SaveAndFind", testSaveAndFind},
+		{"DuplicateID", testDuplicateID},
+		{"Update", testUpdate},
+		{"UpdateConflict", testUpdateConflict},
+		{"SoftDelete", testSoftDelete},
+		{"DeleteNotFound", testDeleteNotFound},
+		{"DeleteConflict", testDeleteConflict},
+		{"UnscopedDelete", testUnscopedDelete},
+		{"Restore", testRestore},
+		{"RestoreConflict", testRestoreConflict},
+		{"PurgeDeletedBefore", testPurgeDeletedBefore},
+		{"FilterSemantics", testFilterSemantics},
+		{"Ordering", testOrdering},
+		{"Pagination", testPagination},
+		{"ConcurrentSaves", testConcurrentSaves},
+		{"ConcurrentUpdates", testConcurrentUpdates},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository(t))
+		})
+	}
+}
+
+var ctx = context.Background()
+
+// epoch is the creation time of the devices saved by save; the offset makes
+// the tests independent of the clock and of timestamp precision.
+var epoch = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func save(t *testing.T, repo domain.IDeviceRepository, id, name, brand string, state domain.DeviceState, created time.Duration) *domain.Device {
+	t.Helper()
+	device := domain.NewDevice(id, name, brand)
+	device.State = state
+	device.CreatedAt = epoch.Add(created)
+	if !assert.NoError(t, repo.Save(ctx, device)) {
+		t.FailNow()
+	}
+	return device
+}
+
+func find(t *testing.T, repo domain.IDeviceRepository, id string) *domain.Device {
+	t.Helper()
+	device, err := repo.FindByID(ctx, id)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return device
+}
+
+func ids(devices []*domain.Device) []string {
+	out := []string{}
+	for _, device := range devices {
+		out = append(out, device.ID)
+	}
+	return out
+}
+
+func testSaveAndFind(t *testing.T, repo domain.IDeviceRepository) {
+	returnAt := epoch.Add(48 * time.Hour)
+	device := domain.NewDevice("crud-1", "Pixel", "Google")
+	device.CreatedAt = epoch
+	device.State = domain.DeviceStateInUse
+	device.Assignee = "alice"
+	device.ExpectedReturnAt = &returnAt
+	assert.NoError(t, repo.Save(ctx, device))
+
+	found := find(t, repo, "crud-1")
+	assert.Equal(t, "Pixel", found.Name)
+	assert.Equal(t, "Google", found.Brand)
+	assert.Equal(t, domain.DeviceStateInUse, found.State)
+	assert.Equal(t, "alice", found.Assignee)
+	assert.True(t, returnAt.Equal(*found.ExpectedReturnAt))
+	assert.True(t, epoch.Equal(found.CreatedAt))
+	assert.Nil(t, found.DeletedAt)
+	assert.Equal(t, int64(1), found.Version)
+
+	_, err := repo.FindByID(ctx, "crud-missing")
+	assert.ErrorIs(t, err, domain.ErrDeviceNotFound)
+}
+
+func testDuplicateID(t *testing.T, repo domain.IDeviceRepository) {
+	save(t, repo, "dup-1", "Pixel", "Google", domain.DeviceStateAvailable, 0)
+	assert.ErrorIs(t, repo.Save(ctx, domain.NewDevice("dup-1", "Other", "Other")), domain.ErrDeviceAlreadyExists)
+	assert.Equal(t, "Pixel", find(t, repo, "dup-1").Name)
+
+	// Soft deleted devices keep their ID.
+	assert.NoError(t, repo.Delete(ctx, "dup-1", 1))
+	assert.ErrorIs(t, repo.Save(ctx, domain.NewDevice("dup-1", "Other", "Other")), domain.ErrDeviceAlreadyExists)
+}
+
+func testUpdate(t *testing.T, repo domain.IDeviceRepository) {
+	save(t, repo, "upd-1", "Pixel", "Google", domain.DeviceStateAvailable, 0)
+
+	device := find(t, repo, "upd-1")
+	device.Name = "Pixel 9"
+	device.CreatedAt = epoch.Add(time.Hour)
+	assert.NoError(t, repo.Update(ctx, device))
+	assert.Equal(t, int64(2), device.Version)
+
+	stored := find(t, repo, "upd-1")
+	assert.Equal(t, "Pixel 9", stored.Name)
+	assert.Equal(t, int64(2), stored.Version)
+	assert.True(t, epoch.Equal(stored.CreatedAt), "the creation time is never updated")
+
+	missing := domain.NewDevice("upd-missing", "Pixel", "Google")
+	assert.ErrorIs(t, repo.Update(ctx, missing), domain.ErrDeviceNotFound)
+}
+
+func testUpdateConflict(t *testing.T, repo domain.IDeviceRepository) {
+	save(t, repo, "conflict-1", "Pixel", "Google", domain.DeviceStateAvailable, 0)
+
+	first := find(t, repo, "conflict-1")
+	second := find(t, repo, "conflict-1")
+	first.Name = "First"
+	second.Name = "Second"
+	assert.NoError(t, repo.Update(ctx, first))
+	assert.ErrorIs(t, repo.Update(ctx, second), domain.ErrVersionConflict)
+	assert.Equal(t, int64(1), second.Version, "a rejected update keeps the version")
+	assert.Equal(t, "First", find(t, repo, "conflict-1").Name)
+}
+
+func testSoftDelete(t *testing.T, repo domain.IDeviceRepository) {
+	save(t, repo, "del-1", "Pixel", "Google", domain.DeviceStateAvailable, 0)
+	assert.NoError(t, repo.Delete(ctx, "del-1", 1))
+
+	_, err := repo.FindByID(ctx, "del-1")
+	assert.ErrorIs(t, err, domain.ErrDeviceNotFound)
+	page, err := repo.Find(ctx, domain.DeviceQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items)
+
+	deleted, err := repo.Unscoped().FindByID(ctx, "del-1")
+	assert.NoError(t, err)
+	assert.NotNil(t, deleted.DeletedAt)
+	assert.Equal(t, int64(2), deleted.Version)
+	page, err = repo.Unscoped().Find(ctx, domain.DeviceQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"del-1"}, ids(page.Items))
+
+	stale := domain.NewDevice("del-1", "Pixel", "Google")
+	stale.Version = 2
+	assert.ErrorIs(t, repo.Update(ctx, stale), domain.ErrDeviceNotFound, "deleted devices cannot be updated")
+}
+
+func testDeleteNotFound(t *testing.T, repo domain.IDeviceRepository) {
+	assert.ErrorIs(t, repo.Delete(ctx, "del-missing", 1), domain.ErrDeviceNotFound)
+
+	save(t, repo, "del-twice", "Pixel", "Google", domain.DeviceStateAvailable, 0)
+	assert.NoError(t, repo.Delete(ctx, "del-twice", 1))
+	assert.ErrorIs(t, repo.Delete(ctx, "del-twice", 2), domain.ErrDeviceNotFound)
+}
+
+func testDeleteConflict(t *testing.T, repo domain.IDeviceRepository) {
+	save(t, repo, "del-stale", "Pixel", "Google", domain.DeviceStateAvailable, 0)
+	device := find(t, repo, "del-stale")
+	device.Name = "Pixel 9"
+	assert.NoError(t, repo.Update(ctx, device))
+
+	assert.ErrorIs(t, repo.Delete(ctx, "del-stale", 1), domain.ErrVersionConflict)
+	assert.Equal(t, "Pixel 9", find(t, repo, "del-stale").Name, "a stale delete leaves the device alone")
+	assert.ErrorIs(t, repo.Unscoped().Delete(ctx, "del-stale", 1), domain.ErrVersionConflict)
+	find(t, repo, "del-stale")
+}
+
+func testUnscopedDelete(t *testing.T, repo domain.IDeviceRepository) {
+	save(t, repo, "hard-1", "Pixel", "Google", domain.DeviceStateAvailable, 0)
+	assert.NoError(t, repo.Unscoped().Delete(ctx, "hard-1", 1))
+
+	_, err := repo.Unscoped().FindByID(ctx, "hard-1")
+	assert.ErrorIs(t, err, domain.ErrDeviceNotFound)
+	assert.ErrorIs(t, repo.Unscoped().Delete(ctx, "hard-1", 1), domain.ErrDeviceNotFound)
+	// The ID is free again.
+	assert.NoError(t, repo.Save(ctx, domain.NewDevice("hard-1", "Pixel", "Google")))
+}
+
+func testRestore(t *testing.T, repo domain.IDeviceRepository) {
+	save(t, repo, "restore-1", "Pixel", "Google", domain.DeviceStateAvailable, 0)
+	assert.ErrorIs(t, repo.Restore(ctx, "restore-1", 1), domain.ErrDeviceNotFound, "only deleted devices can be restored")
+	assert.ErrorIs(t, repo.Restore(ctx, "restore-missing", 1), domain.ErrDeviceNotFound)
+
+	assert.NoError(t, repo.Delete(ctx, "restore-1", 1))
+	assert.NoError(t, repo.Restore(ctx, "restore-1", 2))
+	restored := find(t, repo, "restore-1")
+	assert.Nil(t, restored.DeletedAt)
+	assert.Equal(t, int64(3), restored.Version)
+}
+
+func testRestoreConflict(t *testing.T, repo domain.IDeviceRepository) {
+	save(t, repo, "restore-stale", "Pixel", "Google", domain.DeviceStateAvailable, 0)
+	assert.NoError(t, repo.Delete(ctx, "restore-stale", 1))
+
+	assert.ErrorIs(t, repo.Restore(ctx, "restore-stale", 1), domain.ErrVersionConflict)
+	deleted, err := repo.Unscoped().FindByID(ctx, "restore-stale")
+	assert.NoError(t, err)
+	assert.NotNil(t, deleted.DeletedAt, "a stale restore leaves the device deleted")
+}
+
+func testPurgeDeletedBefore(t *testing.T, repo domain.IDeviceRepository) {
+	save(t, repo, "purge-1", "Pixel", "Google", domain.DeviceStateAvailable, 0)
+	save(t, repo, "purge-2", "Pixel", "Google", domain.DeviceStateAvailable, 0)
+	save(t, repo, "purge-kept", "Pixel", "Google", domain.DeviceStateAvailable, 0)
+	assert.NoError(t, repo.Delete(ctx, "purge-1", 1))
+	assert.NoError(t, repo.Delete(ctx, "purge-2", 1))
+
+	purged, err := repo.PurgeDeletedBefore(ctx, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, purged, "devices deleted after the cutoff are kept")
+
+	purged, err = repo.PurgeDeletedBefore(ctx, time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"purge-1", "purge-2"}, purged)
+	_, err = repo.Unscoped().FindByID(ctx, "purge-1")
+	assert.ErrorIs(t, err, domain.ErrDeviceNotFound)
+	find(t, repo, "purge-kept")
+}
+
+func testFilterSemantics(t *testing.T, repo domain.IDeviceRepository) {
+	var devices []*domain.Device
+	for i, d := range []struct {
+		name, brand string
+		state       domain.DeviceState
+	}{
+		{"Pixel 8", "Google", domain.DeviceStateAvailable},
+		{"pixel tablet", "Google", domain.DeviceStateInUse},
+		{"iPhone 15", "Apple", domain.DeviceStateInUse},
+		{"iPad", "Apple", domain.DeviceStateInactive},
+		{"Galaxy 50%_off", "Samsung", domain.DeviceStateAvailable},
+		{"Galaxy 50 off", "Samsung", domain.DeviceStateAvailable},
+		{`Back\slash`, "Samsung", domain.DeviceStateInactive},
+	} {
+		devices = append(devices, save(t, repo, fmt.Sprintf("filter-%d", i), d.name, d.brand, d.state, time.Duration(i)*time.Hour))
+	}
+	from, to := epoch.Add(2*time.Hour), epoch.Add(5*time.Hour)
+
+	for _, criteria := range []domain.DeviceCriteria{
+		{},
+		{Brands: []string{"Google"}},
+		{Brands: []string{"Google", "Apple"}},
+		{States: []domain.DeviceState{domain.DeviceStateInUse}},
+		{States: []domain.DeviceState{domain.DeviceStateAvailable, domain.DeviceStateInactive}},
+		{Brands: []string{"Apple"}, States: []domain.DeviceState{domain.DeviceStateInUse}},
+		{NameContains: "PIXEL"},
+		{NameContains: "50%_"},
+		{NameContains: "_"},
+		{NameContains: `\`},
+		{CreatedFrom: &from},
+		{CreatedTo: &to},
+		{CreatedFrom: &from, CreatedTo: &to},
+		{Brands: []string{"Nokia"}},
+	} {
+		var want []string
+		for _, device := range devices {
+			if criteria.Matches(device) {
+				want = append(want, device.ID)
+			}
+		}
+		page, err := repo.Find(ctx, domain.DeviceQuery{Criteria: criteria, IncludeTotal: true})
+		if assert.NoError(t, err) {
+			assert.ElementsMatch(t, want, ids(page.Items), "criteria %+v", criteria)
+			if assert.NotNil(t, page.Total) {
+				assert.Equal(t, int64(len(want)), *page.Total, "criteria %+v", criteria)
+			}
+		}
+	}
+}
+
+func testOrdering(t *testing.T, repo domain.IDeviceRepository) {
+	save(t, repo, "order-c", "Beta", "Apple", domain.DeviceStateAvailable, 2*time.Hour)
+	save(t, repo, "order-a", "Alpha", "Google", domain.DeviceStateInUse, 0)
+	save(t, repo, "order-b", "Beta", "Google", domain.DeviceStateAvailable, time.Hour)
+
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{"", []string{"order-a", "order-b", "order-c"}},
+		{"-created_at", []string{"order-c", "order-b", "order-a"}},
+		{"name", []string{"order-a", "order-b", "order-c"}},
+		{"-name", []string{"order-b", "order-c", "order-a"}},
+		{"brand,-name", []string{"order-c", "order-b", "order-a"}},
+		{"state,-id", []string{"order-c", "order-b", "order-a"}},
+	}
+	for _, tt := range tests {
+		sort, err := domain.ParseDeviceSort(tt.sort)
+		assert.NoError(t, err)
+		page, err := repo.Find(ctx, domain.DeviceQuery{Sort: sort})
+		if assert.NoError(t, err, tt.sort) {
+			assert.Equal(t, tt.want, ids(page.Items), "sort %q", tt.sort)
+			assert.Empty(t, page.NextCursor)
+		}
+	}
+}
+
+func testPagination(t *testing.T, repo domain.IDeviceRepository) {
+	var want []string
+	for i := 0; i < 7; i++ {
+		id := fmt.Sprintf("page-%d", i)
+		// Pairs of devices share a name, so the ID has to break ties.
+		save(t, repo, id, fmt.Sprintf("Device %d", i/2), "Google", domain.DeviceStateAvailable, 0)
+		want = append(want, id)
+	}
+
+	sort, _ := domain.ParseDeviceSort("name")
+	query := domain.DeviceQuery{Sort: sort, Limit: 3, IncludeTotal: true}
+	var got []string
+	for pages := 0; ; pages++ {
+		if !assert.Less(t, pages, 3, "too many pages") {
+			return
+		}
+		page, err := repo.Find(ctx, query)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.LessOrEqual(t, len(page.Items), 3)
+		assert.Equal(t, int64(7), *page.Total, "the total ignores the cursor")
+		got = append(got, ids(page.Items)...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assert.Equal(t, want, got)
+
+	_, err := repo.Find(ctx, domain.DeviceQuery{Cursor: "garbage"})
+	assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+}
+
+func testConcurrentSaves(t *testing.T, repo domain.IDeviceRepository) {
+	const writers = 8
+	errs := make([]error, writers)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = repo.Save(ctx, domain.NewDevice("race-1", fmt.Sprintf("Writer %d", i), "Google"))
+		}()
+	}
+	wg.Wait()
+
+	saved := 0
+	for _, err := range errs {
+		if err == nil {
+			saved++
+		} else {
+			assert.ErrorIs(t, err, domain.ErrDeviceAlreadyExists)
+		}
+	}
+	assert.Equal(t, 1, saved)
+}
+
+func testConcurrentUpdates(t *testing.T, repo domain.IDeviceRepository) {
+	save(t, repo, "race-2", "Pixel", "Google", domain.DeviceStateAvailable, 0)
+
+	const writers = 8
+	errs := make([]error, writers)
+	var wg sync.WaitGroup
+	for i := range errs {
+		device := find(t, repo, "race-2")
+		device.Name = fmt.Sprintf("Writer %d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = repo.Update(ctx, device)
+		}()
+	}
+	wg.Wait()
+
+	updated := 0
+	for _, err := range errs {
+		if err == nil {
+			updated++
+		} else if !errors.Is(err, domain.ErrVersionConflict) {
+			t.Errorf("want ErrVersionConflict, got %v", err)
+		}
+	}
+	assert.Equal(t, 1, updated, "only one writer may update a version")
+	assert.Equal(t, int64(2), find(t, repo, "race-2").Version)
+}
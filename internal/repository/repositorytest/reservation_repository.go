@@ -0,0 +1,105 @@
+package repositorytest
+
+import (
+	"device-api/internal/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReservationRepository runs the conformance tests of
+// domain.IReservationRepository. newRepository must return an empty
+// repository every time it is called.
+func TestReservationRepository(t *testing.T, newRepository func(t *testing.T) domain.IReservationRepository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo domain.IReservationRepository)
+	}{
+		{"SaveAndFind", testReservationSaveAndFind},
+		{"FindByDevice", testReservationFindByDevice},
+		{"Delete", testReservationDelete},
+		{"LockDevice", testReservationLockDevice},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository(t))
+		})
+	}
+}
+
+func reserve(t *testing.T, repo domain.IReservationRepository, id, deviceID string, starts, ends time.Duration) {
+	t.Helper()
+	reservation := &domain.Reservation{
+		ID:         id,
+		DeviceID:   deviceID,
+		ReservedBy: "alice",
+		StartsAt:   epoch.Add(starts),
+		EndsAt:     epoch.Add(ends),
+		CreatedAt:  epoch,
+	}
+	if !assert.NoError(t, repo.Save(ctx, reservation)) {
+		t.FailNow()
+	}
+}
+
+func reservationIDs(reservations []*domain.Reservation) []string {
+	out := []string{}
+	for _, reservation := range reservations {
+		out = append(out, reservation.ID)
+	}
+	return out
+}
+
+func testReservationSaveAndFind(t *testing.T, repo domain.IReservationRepository) {
+	reserve(t, repo, "res-1", "device-1", time.Hour, 2*time.Hour)
+
+	found, err := repo.FindByID(ctx, "res-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "device-1", found.DeviceID)
+	assert.Equal(t, "alice", found.ReservedBy)
+	assert.True(t, epoch.Add(time.Hour).Equal(found.StartsAt))
+	assert.True(t, epoch.Add(2*time.Hour).Equal(found.EndsAt))
+
+	_, err = repo.FindByID(ctx, "res-missing")
+	assert.ErrorIs(t, err, domain.ErrReservationNotFound)
+}
+
+func testReservationFindByDevice(t *testing.T, repo domain.IReservationRepository) {
+	reserve(t, repo, "res-late", "device-1", 5*time.Hour, 6*time.Hour)
+	reserve(t, repo, "res-early", "device-1", time.Hour, 2*time.Hour)
+	reserve(t, repo, "res-ended", "device-1", -2*time.Hour, -time.Hour)
+	reserve(t, repo, "res-other", "device-2", time.Hour, 2*time.Hour)
+
+	found, err := repo.FindByDevice(ctx, "device-1", epoch)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"res-early", "res-late"}, reservationIDs(found), "ordered by start, ended ones left out")
+
+	found, err = repo.FindByDevice(ctx, "device-1", epoch.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"res-late"}, reservationIDs(found), "a reservation ending at endingAfter has ended")
+
+	found, err = repo.FindByDevice(ctx, "device-1", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"res-ended", "res-early", "res-late"}, reservationIDs(found))
+
+	found, err = repo.FindByDevice(ctx, "device-missing", time.Time{})
+	assert.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+func testReservationDelete(t *testing.T, repo domain.IReservationRepository) {
+	reserve(t, repo, "res-1", "device-1", time.Hour, 2*time.Hour)
+
+	assert.NoError(t, repo.Delete(ctx, "res-1"))
+	_, err := repo.FindByID(ctx, "res-1")
+	assert.ErrorIs(t, err, domain.ErrReservationNotFound)
+	assert.ErrorIs(t, repo.Delete(ctx, "res-1"), domain.ErrReservationNotFound)
+}
+
+func testReservationLockDevice(t *testing.T, repo domain.IReservationRepository) {
+	// Outside a unit of work the lock is released straight away, so taking
+	// it twice does not block.
+	assert.NoError(t, repo.LockDevice(ctx, "device-1"))
+	assert.NoError(t, repo.LockDevice(ctx, "device-1"))
+}
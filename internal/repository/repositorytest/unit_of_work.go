@@ -0,0 +1,117 @@
+package repositorytest
+
+import (
+	"device-api/internal/domain"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestUnitOfWork runs the conformance tests of domain.IUnitOfWork over the
+// device, reservation and history repositories. newStorage must return a
+// unit of work over empty storage, together with repositories that read the
+// same storage outside any unit of work.
+func TestUnitOfWork(t *testing.T, newStorage func(t *testing.T) (domain.IUnitOfWork, domain.Repositories)) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, uow domain.IUnitOfWork, repos domain.Repositories)
+	}{
+		{"Commit", testUnitOfWorkCommit},
+		{"Rollback", testUnitOfWorkRollback},
+		{"RollbackRestoresChanges", testUnitOfWorkRollbackRestoresChanges},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uow, repos := newStorage(t)
+			tt.test(t, uow, repos)
+		})
+	}
+}
+
+var errAbort = errors.New("abort")
+
+// writeAll makes one write through every repository of a unit of work.
+func writeAll(t *testing.T, repos domain.Repositories, id string) error {
+	t.Helper()
+	device := domain.NewDevice(id, "Pixel", "Google")
+	device.CreatedAt = epoch
+	if err := repos.Devices.Save(ctx, device); err != nil {
+		return err
+	}
+	reservation := &domain.Reservation{ID: id + "-res", DeviceID: id, ReservedBy: "alice", StartsAt: epoch, EndsAt: epoch.Add(time.Hour)}
+	if err := repos.Reservations.Save(ctx, reservation); err != nil {
+		return err
+	}
+	return repos.History.Append(ctx, domain.NewDeviceHistoryEntry(id, domain.HistoryActionCreated, nil, domain.SnapshotOf(device), "alice"))
+}
+
+func testUnitOfWorkCommit(t *testing.T, uow domain.IUnitOfWork, repos domain.Repositories) {
+	err := uow.Do(ctx, func(tx domain.Repositories) error {
+		if err := writeAll(t, tx, "uow-1"); err != nil {
+			return err
+		}
+		// Writes are visible inside the unit of work before it commits.
+		_, err := tx.Devices.FindByID(ctx, "uow-1")
+		return err
+	})
+	assert.NoError(t, err)
+
+	find(t, repos.Devices, "uow-1")
+	_, err = repos.Reservations.FindByID(ctx, "uow-1-res")
+	assert.NoError(t, err)
+	_, total, err := repos.History.FindByDevice(ctx, "uow-1", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+}
+
+func testUnitOfWorkRollback(t *testing.T, uow domain.IUnitOfWork, repos domain.Repositories) {
+	err := uow.Do(ctx, func(tx domain.Repositories) error {
+		if err := writeAll(t, tx, "uow-1"); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+
+	_, err = repos.Devices.Unscoped().FindByID(ctx, "uow-1")
+	assert.ErrorIs(t, err, domain.ErrDeviceNotFound)
+	_, err = repos.Reservations.FindByID(ctx, "uow-1-res")
+	assert.ErrorIs(t, err, domain.ErrReservationNotFound)
+	_, total, err := repos.History.FindByDevice(ctx, "uow-1", 10, 0)
+	assert.NoError(t, err)
+	assert.Zero(t, total)
+}
+
+func testUnitOfWorkRollbackRestoresChanges(t *testing.T, uow domain.IUnitOfWork, repos domain.Repositories) {
+	assert.NoError(t, writeAll(t, repos, "uow-1"))
+	assert.NoError(t, writeAll(t, repos, "uow-2"))
+
+	err := uow.Do(ctx, func(tx domain.Repositories) error {
+		device := find(t, tx.Devices, "uow-1")
+		device.Name = "Changed"
+		if err := tx.Devices.Update(ctx, device); err != nil {
+			return err
+		}
+		device.Name = "Changed again"
+		if err := tx.Devices.Update(ctx, device); err != nil {
+			return err
+		}
+		if err := tx.Devices.Delete(ctx, "uow-2", 1); err != nil {
+			return err
+		}
+		if err := tx.Reservations.Delete(ctx, "uow-1-res"); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+
+	device := find(t, repos.Devices, "uow-1")
+	assert.Equal(t, "Pixel", device.Name)
+	assert.Equal(t, int64(1), device.Version)
+	find(t, repos.Devices, "uow-2")
+	_, err = repos.Reservations.FindByID(ctx, "uow-1-res")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PostgresReservationRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresReservationRepository(db *gorm.DB) *PostgresReservationRepository {
+	return &PostgresReservationRepository{db: db}
+}
+
+func (r *PostgresReservationRepository) Save(ctx context.Context, reservation *domain.Reservation) error {
+	return r.db.WithContext(ctx).Create(reservation).Error
+}
+
+func (r *PostgresReservationRepository) FindByID(ctx context.Context, id string) (*domain.Reservation, error) {
+	var reservation domain.Reservation
+	result := r.db.WithContext(ctx).First(&reservation, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrReservationNotFound
+		}
+		return nil, result.Error
+	}
+	return &reservation, nil
+}
+
+func (r *PostgresReservationRepository) FindByDevice(ctx context.Context, deviceID string, endingAfter time.Time) ([]*domain.Reservation, error) {
+	var reservations []*domain.Reservation
+	result := r.db.WithContext(ctx).
+		Where("device_id = ? AND ends_at > ?", deviceID, endingAfter).
+		Order("starts_at").
+		Find(&reservations)
+	return reservations, result.Error
+}
+
+func (r *PostgresReservationRepository) Delete(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Delete(&domain.Reservation{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrReservationNotFound
+	}
+	return nil
+}
+
+// LockDevice locks the device row with SELECT ... FOR UPDATE. SQLite has no
+// row locks; it serialises write transactions instead.
+func (r *PostgresReservationRepository) LockDevice(ctx context.Context, deviceID string) error {
+	var ids []string
+	return r.db.WithContext(ctx).
+		Model(&domain.Device{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", deviceID).
+		Pluck("id", &ids).Error
+}
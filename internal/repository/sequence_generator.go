@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IDSequence stores the last value handed out by a named SequenceGenerator.
+type IDSequence struct {
+	Name  string `gorm:"primaryKey"`
+	Value int64  `gorm:"not null"`
+}
+
+// SequenceGenerator hands out prefixed, zero padded sequential IDs such as
+// DEV-000123. The counter is kept in the database so IDs stay unique across
+// restarts and replicas.
+type SequenceGenerator struct {
+	db     *gorm.DB
+	name   string
+	prefix string
+	width  int
+}
+
+func NewSequenceGenerator(db *gorm.DB, name, prefix string, width int) *SequenceGenerator {
+	return &SequenceGenerator{db: db, name: name, prefix: prefix, width: width}
+}
+
+func (g *SequenceGenerator) NewID(ctx context.Context) (string, error) {
+	var value int64
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seed := IDSequence{Name: g.name}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seed).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&IDSequence{}).
+			Where("name = ?", g.name).
+			Update("value", gorm.Expr("value + 1")).Error; err != nil {
+			return err
+		}
+		return tx.Model(&IDSequence{}).
+			Where("name = ?", g.name).
+			Pluck("value", &value).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%0*d", g.prefix, g.width, value), nil
+}
+
+// Reserved reports whether id is the prefix followed by digits only. Any
+// such ID may be generated, once the counter grows past the padding too.
+func (g *SequenceGenerator) Reserved(id string) bool {
+	digits, ok := strings.CutPrefix(id, g.prefix)
+	if !ok || digits == "" {
+		return false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
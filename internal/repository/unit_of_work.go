@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"device-api/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// UnitOfWork runs work in a database transaction.
+type UnitOfWork struct {
+	db *gorm.DB
+}
+
+func NewUnitOfWork(db *gorm.DB) *UnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+func (u *UnitOfWork) Do(ctx context.Context, fn func(repos domain.Repositories) error) error {
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(domain.Repositories{
+			Devices:      NewPostgresRepository(tx),
+			Reservations: NewPostgresReservationRepository(tx),
+			History:      NewPostgresDeviceHistoryRepository(tx),
+			Outbox:       NewPostgresOutboxRepository(tx),
+		})
+	})
+}
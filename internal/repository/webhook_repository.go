@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PostgresWebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresWebhookRepository(db *gorm.DB) *PostgresWebhookRepository {
+	return &PostgresWebhookRepository{db: db}
+}
+
+func (r *PostgresWebhookRepository) Save(ctx context.Context, webhook *domain.Webhook) error {
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+func (r *PostgresWebhookRepository) FindByID(ctx context.Context, id string) (*domain.Webhook, error) {
+	var webhook domain.Webhook
+	result := r.db.WithContext(ctx).First(&webhook, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrWebhookNotFound
+		}
+		return nil, result.Error
+	}
+	return &webhook, nil
+}
+
+func (r *PostgresWebhookRepository) FindAll(ctx context.Context) ([]*domain.Webhook, error) {
+	var webhooks []*domain.Webhook
+	result := r.db.WithContext(ctx).Order("created_at, id").Find(&webhooks)
+	return webhooks, result.Error
+}
+
+func (r *PostgresWebhookRepository) Update(ctx context.Context, webhook *domain.Webhook) error {
+	result := r.db.WithContext(ctx).Model(webhook).Select("*").Omit("id", "created_at").Updates(webhook)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *PostgresWebhookRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&domain.Webhook{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrWebhookNotFound
+		}
+		return tx.Delete(&domain.WebhookDelivery{}, "webhook_id = ?", id).Error
+	})
+}
+
+type PostgresWebhookDeliveryRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresWebhookDeliveryRepository(db *gorm.DB) *PostgresWebhookDeliveryRepository {
+	return &PostgresWebhookDeliveryRepository{db: db}
+}
+
+func (r *PostgresWebhookDeliveryRepository) Save(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Create(delivery).Error
+}
+
+func (r *PostgresWebhookDeliveryRepository) FindByID(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	result := r.db.WithContext(ctx).First(&delivery, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrDeliveryNotFound
+		}
+		return nil, result.Error
+	}
+	return &delivery, nil
+}
+
+func (r *PostgresWebhookDeliveryRepository) Update(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	result := r.db.WithContext(ctx).Model(delivery).Select("*").Omit("id", "created_at").Updates(delivery)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func (r *PostgresWebhookDeliveryRepository) FindByWebhook(ctx context.Context, webhookID string, status domain.DeliveryStatus, limit, offset int) ([]*domain.WebhookDelivery, int64, error) {
+	scope := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("webhook_id = ?", webhookID)
+		if status != "" {
+			db = db.Where("status = ?", status)
+		}
+		return db
+	}
+
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&domain.WebhookDelivery{}).Scopes(scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []*domain.WebhookDelivery
+	result := r.db.WithContext(ctx).
+		Scopes(scope).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&deliveries)
+	return deliveries, total, result.Error
+}
+
+func (r *PostgresWebhookDeliveryRepository) FindByStatus(ctx context.Context, status domain.DeliveryStatus, limit int) ([]*domain.WebhookDelivery, error) {
+	var deliveries []*domain.WebhookDelivery
+	result := r.db.WithContext(ctx).
+		Where("status = ?", status).
+		Order("created_at, id").
+		Limit(limit).
+		Find(&deliveries)
+	return deliveries, result.Error
+}
+
+// ClaimDue locks the due rows, skipping those another transaction holds,
+// and pushes their next attempt forward before committing.
+func (r *PostgresWebhookDeliveryRepository) ClaimDue(ctx context.Context, now, until time.Time, limit int) ([]*domain.WebhookDelivery, error) {
+	var deliveries []*domain.WebhookDelivery
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", domain.DeliveryStatusPending, now).
+			Order("next_attempt_at, created_at, id").
+			Limit(limit).
+			Find(&deliveries)
+		if result.Error != nil || len(deliveries) == 0 {
+			return result.Error
+		}
+		ids := make([]string, len(deliveries))
+		for i, delivery := range deliveries {
+			ids[i] = delivery.ID
+			delivery.NextAttemptAt = until
+		}
+		return tx.Model(&domain.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", until).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
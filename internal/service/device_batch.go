@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"device-api/internal/domain"
+)
+
+// MaxBatchSize is the largest number of items accepted in one batch.
+const MaxBatchSize = 1000
+
+// BatchMode decides what happens to a batch when one of its items fails.
+type BatchMode string
+
+const (
+	// BatchAtomic applies every item of a batch, or none of them if one
+	// fails. It needs a unit of work, see WithUnitOfWork.
+	BatchAtomic BatchMode = "atomic"
+	// BatchBestEffort applies every item that succeeds on its own.
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+func (m BatchMode) IsValid() bool {
+	return m == BatchAtomic || m == BatchBestEffort
+}
+
+// BatchResult is the outcome of one item of a batch. Device is nil for
+// deletions and failed items. In an atomic batch that failed, every item but
+// the failing one reports domain.ErrBatchAborted.
+type BatchResult struct {
+	Device *domain.Device
+	Err    error
+}
+
+type DeviceCreate struct {
+	ID    string
+	Name  string
+	Brand string
+}
+
+type DeviceUpdate struct {
+	ID string
+	// Version, when not zero, is the version the device must still have.
+	Version int64
+	Change  func(*domain.DeviceAttributes) error
+}
+
+type DeviceDelete struct {
+	ID      string
+	Version int64
+}
+
+// CreateDevices creates devices following the same rules as CreateDevice.
+func (s *DeviceService) CreateDevices(ctx context.Context, items []DeviceCreate, mode BatchMode) ([]BatchResult, error) {
+	if err := s.checkBatch(len(items), mode); err != nil {
+		return nil, err
+	}
+
+	// As in CreateDevice, IDs are generated before the unit of work starts.
+	ids := make([]string, len(items))
+	idErrs := make([]error, len(items))
+	for i, item := range items {
+		ids[i], idErrs[i] = s.deviceID(ctx, item.ID)
+	}
+
+	return s.runBatch(ctx, len(items), mode, func(tx *DeviceService, i int) (*domain.Device, error) {
+		if idErrs[i] != nil {
+			return nil, idErrs[i]
+		}
+		return tx.createDevice(ctx, ids[i], domain.DeviceAttributes{Name: items[i].Name, Brand: items[i].Brand})
+	})
+}
+
+// UpdateDevices modifies devices following the same rules as ModifyDevice.
+func (s *DeviceService) UpdateDevices(ctx context.Context, items []DeviceUpdate, mode BatchMode) ([]BatchResult, error) {
+	if err := s.checkBatch(len(items), mode); err != nil {
+		return nil, err
+	}
+	return s.runBatch(ctx, len(items), mode, func(tx *DeviceService, i int) (*domain.Device, error) {
+		return tx.ifVersion(items[i].Version).ModifyDevice(ctx, items[i].ID, items[i].Change)
+	})
+}
+
+// DeleteDevices soft deletes devices following the same rules as
+// DeleteDevice.
+func (s *DeviceService) DeleteDevices(ctx context.Context, items []DeviceDelete, mode BatchMode) ([]BatchResult, error) {
+	if err := s.checkBatch(len(items), mode); err != nil {
+		return nil, err
+	}
+	return s.runBatch(ctx, len(items), mode, func(tx *DeviceService, i int) (*domain.Device, error) {
+		return nil, tx.ifVersion(items[i].Version).DeleteDevice(ctx, items[i].ID)
+	})
+}
+
+func (s *DeviceService) checkBatch(size int, mode BatchMode) error {
+	if !mode.IsValid() {
+		return domain.ErrInvalidBatchMode
+	}
+	if mode == BatchAtomic && s.uow == nil {
+		return domain.ErrAtomicBatchUnavailable
+	}
+	if size > MaxBatchSize {
+		return domain.ErrBatchTooLarge
+	}
+	return nil
+}
+
+func (s *DeviceService) ifVersion(version int64) *DeviceService {
+	if version == 0 {
+		return s
+	}
+	return s.IfMatch(version)
+}
+
+// runBatch applies apply to every item of a batch of size items. Best effort
+// batches give every item a unit of work of its own; atomic batches share
+// one and stop at the first failure.
+func (s *DeviceService) runBatch(ctx context.Context, size int, mode BatchMode, apply func(tx *DeviceService, i int) (*domain.Device, error)) ([]BatchResult, error) {
+	results := make([]BatchResult, size)
+
+	if mode == BatchBestEffort {
+		for i := range results {
+			results[i].Device, results[i].Err = s.deviceInTransaction(ctx, func(tx *DeviceService) (*domain.Device, error) {
+				return apply(tx, i)
+			})
+		}
+		return results, nil
+	}
+
+	failed := -1
+	err := s.inTransaction(ctx, func(tx *DeviceService) error {
+		for i := range results {
+			device, err := apply(tx, i)
+			if err != nil {
+				failed = i
+				return err
+			}
+			results[i].Device = device
+		}
+		return nil
+	})
+	if err == nil {
+		return results, nil
+	}
+	if failed < 0 {
+		// Every item succeeded but the unit of work could not commit.
+		return nil, err
+	}
+	for i := range results {
+		results[i] = BatchResult{Err: domain.ErrBatchAborted}
+	}
+	results[failed].Err = err
+	return results, nil
+}
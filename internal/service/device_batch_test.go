@@ -0,0 +1,58 @@
+package service_test
+
+import (
+	"device-api/internal/domain"
+	"device-api/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateDevices(t *testing.T) {
+	t.Run("best_effort_applies_valid_items", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		mockRepo.On("Save", mock.Anything).Return(nil)
+
+		results, err := svc.CreateDevices(ctx, []service.DeviceCreate{
+			{ID: "1", Name: "Pixel", Brand: "Google"},
+			{ID: "2", Name: "iPhone"},
+		}, service.BatchBestEffort)
+		assert.NoError(t, err)
+		assert.NoError(t, results[0].Err)
+		assert.Equal(t, "1", results[0].Device.ID)
+		assert.ErrorIs(t, results[1].Err, domain.ErrDeviceBrandRequired)
+		mockRepo.AssertNumberOfCalls(t, "Save", 1)
+	})
+
+	t.Run("atomic_aborts_other_items", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		uow := &fakeUnitOfWork{repos: domain.Repositories{Devices: mockRepo}}
+		svc := service.NewDeviceService(mockRepo, service.WithUnitOfWork(uow))
+		mockRepo.On("Save", mock.Anything).Return(nil)
+
+		results, err := svc.CreateDevices(ctx, []service.DeviceCreate{
+			{ID: "1", Name: "Pixel", Brand: "Google"},
+			{ID: "2", Name: "iPhone"},
+			{ID: "3", Name: "Galaxy", Brand: "Samsung"},
+		}, service.BatchAtomic)
+		assert.NoError(t, err)
+		assert.True(t, uow.rolledBack)
+		assert.ErrorIs(t, results[0].Err, domain.ErrBatchAborted)
+		assert.ErrorIs(t, results[1].Err, domain.ErrDeviceBrandRequired)
+		assert.ErrorIs(t, results[2].Err, domain.ErrBatchAborted)
+	})
+
+	t.Run("atomic_needs_unit_of_work", func(t *testing.T) {
+		svc := service.NewDeviceService(new(MockRepository))
+		_, err := svc.CreateDevices(ctx, []service.DeviceCreate{{ID: "1", Name: "Pixel", Brand: "Google"}}, service.BatchAtomic)
+		assert.ErrorIs(t, err, domain.ErrAtomicBatchUnavailable)
+	})
+
+	t.Run("too_large", func(t *testing.T) {
+		svc := service.NewDeviceService(new(MockRepository))
+		_, err := svc.CreateDevices(ctx, make([]service.DeviceCreate, service.MaxBatchSize+1), service.BatchBestEffort)
+		assert.ErrorIs(t, err, domain.ErrBatchTooLarge)
+	})
+}
@@ -0,0 +1,76 @@
+package service_test
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"device-api/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockHistoryRepository is a mock implementation of domain.IDeviceHistoryRepository
+type MockHistoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockHistoryRepository) Append(ctx context.Context, entry *domain.DeviceHistoryEntry) error {
+	args := m.Called(entry)
+	return args.Error(0)
+}
+
+func (m *MockHistoryRepository) FindByDevice(ctx context.Context, deviceID string, limit, offset int) ([]*domain.DeviceHistoryEntry, int64, error) {
+	args := m.Called(deviceID, limit, offset)
+	return args.Get(0).([]*domain.DeviceHistoryEntry), args.Get(1).(int64), args.Error(2)
+}
+
+func TestHistoryRecording(t *testing.T) {
+	t.Run("state_change_records_old_and_new", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		history := new(MockHistoryRepository)
+		svc := service.NewDeviceService(mockRepo, service.WithHistory(history))
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+		mockRepo.On("Update", mock.AnythingOfType("*domain.Device")).Return(nil)
+		history.On("Append", mock.MatchedBy(func(e *domain.DeviceHistoryEntry) bool {
+			return e.DeviceID == "123" &&
+				e.Action == domain.HistoryActionStateChanged &&
+				e.OldValue.State == domain.DeviceStateAvailable &&
+				e.NewValue.State == domain.DeviceStateInUse &&
+				e.Actor == "alice"
+		})).Return(nil)
+
+		_, err := svc.As("alice").UpdateDeviceState(ctx, "123", domain.DeviceStateInUse)
+		assert.NoError(t, err)
+		history.AssertExpectations(t)
+	})
+
+	t.Run("no_op_update_is_not_recorded", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		history := new(MockHistoryRepository)
+		svc := service.NewDeviceService(mockRepo, service.WithHistory(history))
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+		mockRepo.On("Update", mock.AnythingOfType("*domain.Device")).Return(nil)
+
+		_, err := svc.UpdateDevice(ctx, "123", "Pixel", "Google")
+		assert.NoError(t, err)
+		history.AssertNotCalled(t, "Append", mock.Anything)
+	})
+
+	t.Run("delete_records_last_snapshot", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		history := new(MockHistoryRepository)
+		svc := service.NewDeviceService(mockRepo, service.WithHistory(history))
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+		mockRepo.On("Delete", "123", int64(1)).Return(nil)
+		history.On("Append", mock.MatchedBy(func(e *domain.DeviceHistoryEntry) bool {
+			return e.Action == domain.HistoryActionDeleted && e.OldValue.Name == "Pixel" && e.NewValue == nil
+		})).Return(nil)
+
+		assert.NoError(t, svc.DeleteDevice(ctx, "123"))
+		history.AssertExpectations(t)
+	})
+}
@@ -1,86 +1,606 @@
 package service
 
 import (
+	"context"
 	"device-api/internal/domain"
+	"errors"
+	"time"
 )
 
 type DeviceService struct {
-	repo domain.IDeviceRepository
+	repo         domain.IDeviceRepository
+	reservations domain.IReservationRepository
+	history      domain.IDeviceHistoryRepository
+	retention    time.Duration
+	actor        string
+	ifMatch      []int64
+	ids          domain.IDGenerator
+	clientIDs    bool
+	uow          domain.IUnitOfWork
+	events       domain.IEventPublisher
+	outbox       domain.IOutboxRepository
+	// pending collects the events of the current unit of work until it
+	// commits.
+	pending *[]domain.DeviceEvent
 }
 
-func NewDeviceService(repo domain.IDeviceRepository) *DeviceService {
-	return &DeviceService{repo: repo}
+// DefaultDeletedRetention is how long soft deleted devices are kept before
+// PurgeDeletedDevices removes them for good.
+const DefaultDeletedRetention = 30 * 24 * time.Hour
+
+// Option configures optional collaborators of a DeviceService.
+type Option func(*DeviceService)
+
+// WithReservations makes checkouts honour reservations stored in repo.
+func WithReservations(repo domain.IReservationRepository) Option {
+	return func(s *DeviceService) {
+		s.reservations = repo
+	}
 }
 
-func (s *DeviceService) CreateDevice(id, name, brand string) (*domain.Device, error) {
-    existing, _ := s.repo.FindByID(id)
-    if existing != nil {
-        return nil, domain.ErrDeviceAlreadyExists
-    }
+// WithHistory records every change made through the service in repo.
+func WithHistory(repo domain.IDeviceHistoryRepository) Option {
+	return func(s *DeviceService) {
+		s.history = repo
+	}
+}
 
-	device := domain.NewDevice(id, name, brand)
-	err := s.repo.Save(device)
-	if err != nil {
-		return nil, err
+// WithDeletedRetention sets how long soft deleted devices are kept.
+func WithDeletedRetention(retention time.Duration) Option {
+	return func(s *DeviceService) {
+		s.retention = retention
+	}
+}
+
+// WithIDGenerator makes the service generate IDs for devices created
+// without one.
+func WithIDGenerator(ids domain.IDGenerator) Option {
+	return func(s *DeviceService) {
+		s.ids = ids
+	}
+}
+
+// WithClientIDs sets whether callers may choose the ID of a new device.
+// Client IDs are allowed by default.
+func WithClientIDs(allowed bool) Option {
+	return func(s *DeviceService) {
+		s.clientIDs = allowed
+	}
+}
+
+// WithUnitOfWork makes every change, together with its history entry, commit
+// or roll back as a whole.
+func WithUnitOfWork(uow domain.IUnitOfWork) Option {
+	return func(s *DeviceService) {
+		s.uow = uow
+	}
+}
+
+// WithEvents publishes an event for every change once it is committed.
+func WithEvents(publisher domain.IEventPublisher) Option {
+	return func(s *DeviceService) {
+		s.events = publisher
 	}
-	return device, nil
 }
 
-func (s *DeviceService) GetDevice(id string) (*domain.Device, error) {
-	return s.repo.FindByID(id)
+// WithOutbox stores the event of every change in repo, in the unit of work
+// of the change, for a relay to publish. It takes the place of WithEvents.
+func WithOutbox(repo domain.IOutboxRepository) Option {
+	return func(s *DeviceService) {
+		s.outbox = repo
+	}
 }
 
-func (s *DeviceService) ListAllDevices() ([]*domain.Device, error) {
-	return s.repo.FindAll()
+func NewDeviceService(repo domain.IDeviceRepository, opts ...Option) *DeviceService {
+	s := &DeviceService{repo: repo, retention: DefaultDeletedRetention, clientIDs: true}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-func (s *DeviceService) ListDevicesByBrand(brand string) ([]*domain.Device, error) {
-	return s.repo.FindByBrand(brand)
+// As returns a copy of the service that attributes changes to actor.
+func (s *DeviceService) As(actor string) *DeviceService {
+	scoped := *s
+	scoped.actor = actor
+	return &scoped
 }
 
-func (s *DeviceService) ListDevicesByState(state domain.DeviceState) ([]*domain.Device, error) {
-	return s.repo.FindByState(state)
+// IfMatch returns a copy of the service whose writes fail with
+// ErrPreconditionFailed unless the device is at one of versions.
+func (s *DeviceService) IfMatch(versions ...int64) *DeviceService {
+	scoped := *s
+	scoped.ifMatch = append([]int64{}, versions...)
+	return &scoped
 }
 
-func (s *DeviceService) UpdateDevice(id string, name, brand string) (*domain.Device, error) {
-	device, err := s.repo.FindByID(id)
+// IncludingDeleted returns a copy of the service whose reads also return
+// soft deleted devices.
+func (s *DeviceService) IncludingDeleted() *DeviceService {
+	scoped := *s
+	scoped.repo = s.repo.Unscoped()
+	return &scoped
+}
+
+// CreateDevice stores a new device. When id is empty one is generated with
+// the configured IDGenerator.
+func (s *DeviceService) CreateDevice(ctx context.Context, id, name, brand string) (*domain.Device, error) {
+	id, err := s.deviceID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := device.UpdateDetails(name, brand); err != nil {
+	// IDs are generated outside the unit of work: a generator may need a
+	// transaction of its own.
+	return s.deviceInTransaction(ctx, func(tx *DeviceService) (*domain.Device, error) {
+		return tx.createDevice(ctx, id, domain.DeviceAttributes{Name: name, Brand: brand})
+	})
+}
+
+// createDevice stores a new device with attrs. An empty state leaves the
+// device available.
+func (s *DeviceService) createDevice(ctx context.Context, id string, attrs domain.DeviceAttributes) (*domain.Device, error) {
+	device := domain.NewDevice(id, attrs.Name, attrs.Brand)
+	if attrs.State == "" {
+		attrs.State = device.State
+	}
+	if err := device.Apply(attrs); err != nil {
 		return nil, err
 	}
-
-	if err := s.repo.Update(device); err != nil {
+	if err := s.repo.Save(ctx, device); err != nil {
+		return nil, err
+	}
+	if err := s.record(ctx, id, domain.HistoryActionCreated, nil, device); err != nil {
 		return nil, err
 	}
 	return device, nil
 }
 
-func (s *DeviceService) UpdateDeviceState(id string, state domain.DeviceState) (*domain.Device, error) {
-    device, err := s.repo.FindByID(id)
-    if err != nil {
-        return nil, err
-    }
-    
-    device.UpdateState(state)
-    if err := s.repo.Update(device); err != nil {
-        return nil, err
-    }
-    return device, nil
+func (s *DeviceService) deviceID(ctx context.Context, requested string) (string, error) {
+	if requested != "" {
+		if !s.clientIDs {
+			return "", domain.ErrClientIDNotAllowed
+		}
+		// Otherwise the generator could later hand out an ID a client took.
+		if reserver, ok := s.ids.(domain.IDReserver); ok && reserver.Reserved(requested) {
+			return "", domain.ErrDeviceIDReserved
+		}
+		return requested, nil
+	}
+	if s.ids == nil {
+		return "", domain.ErrDeviceIDRequired
+	}
+	return s.ids.NewID(ctx)
+}
+
+func (s *DeviceService) GetDevice(ctx context.Context, id string) (*domain.Device, error) {
+	return s.repo.FindByID(ctx, id)
 }
 
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// ListDevices returns one page of devices. A missing limit defaults to
+// DefaultPageSize and larger ones are capped at MaxPageSize.
+func (s *DeviceService) ListDevices(ctx context.Context, query domain.DeviceQuery) (*domain.DevicePage, error) {
+	if err := query.Criteria.Validate(); err != nil {
+		return nil, err
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultPageSize
+	}
+	if query.Limit > MaxPageSize {
+		query.Limit = MaxPageSize
+	}
+	return s.repo.Find(ctx, query)
+}
+
+// exportPageSize is how many devices ExportDevices reads at a time.
+const exportPageSize = 500
+
+// ExportDevices passes every device matching query to fn, in query order.
+// Devices are read page by page, so the catalogue is never held in memory
+// as a whole. Limit, Cursor and IncludeTotal of query are ignored.
+func (s *DeviceService) ExportDevices(ctx context.Context, query domain.DeviceQuery, fn func(*domain.Device) error) error {
+	if err := query.Criteria.Validate(); err != nil {
+		return err
+	}
+	query.Limit = exportPageSize
+	query.Cursor = ""
+	query.IncludeTotal = false
+
+	for {
+		page, err := s.repo.Find(ctx, query)
+		if err != nil {
+			return err
+		}
+		for _, device := range page.Items {
+			if err := fn(device); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		query.Cursor = page.NextCursor
+	}
+}
+
+func (s *DeviceService) UpdateDevice(ctx context.Context, id string, name, brand string) (*domain.Device, error) {
+	return s.deviceInTransaction(ctx, func(tx *DeviceService) (*domain.Device, error) {
+		device, err := tx.repo.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.checkPrecondition(device); err != nil {
+			return nil, err
+		}
+		before := domain.SnapshotOf(device)
+
+		if err := device.UpdateDetails(name, brand); err != nil {
+			return nil, err
+		}
+
+		if err := tx.repo.Update(ctx, device); err != nil {
+			return nil, err
+		}
+		if err := tx.recordChange(ctx, id, domain.HistoryActionUpdated, before, device); err != nil {
+			return nil, err
+		}
+		return device, nil
+	})
+}
+
+func (s *DeviceService) UpdateDeviceState(ctx context.Context, id string, state domain.DeviceState) (*domain.Device, error) {
+	return s.deviceInTransaction(ctx, func(tx *DeviceService) (*domain.Device, error) {
+		device, err := tx.repo.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.checkPrecondition(device); err != nil {
+			return nil, err
+		}
+		before := domain.SnapshotOf(device)
+
+		if err := device.UpdateState(state); err != nil {
+			return nil, err
+		}
+		if err := tx.repo.Update(ctx, device); err != nil {
+			return nil, err
+		}
+		if err := tx.recordChange(ctx, id, domain.HistoryActionStateChanged, before, device); err != nil {
+			return nil, err
+		}
+		return device, nil
+	})
+}
+
+// ReplaceDevice sets all client editable attributes of a device at once.
+func (s *DeviceService) ReplaceDevice(ctx context.Context, id string, attrs domain.DeviceAttributes) (*domain.Device, error) {
+	return s.ModifyDevice(ctx, id, func(current *domain.DeviceAttributes) error {
+		*current = attrs
+		return nil
+	})
+}
+
+// ModifyDevice lets change edit the current attributes of a device and
+// applies the result with Device.Apply, writing the device once. Nothing is
+// written when change fails or leaves the attributes as they were.
+func (s *DeviceService) ModifyDevice(ctx context.Context, id string, change func(*domain.DeviceAttributes) error) (*domain.Device, error) {
+	return s.deviceInTransaction(ctx, func(tx *DeviceService) (*domain.Device, error) {
+		device, err := tx.repo.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.checkPrecondition(device); err != nil {
+			return nil, err
+		}
+		before := domain.SnapshotOf(device)
+
+		attrs := device.Attributes()
+		if err := change(&attrs); err != nil {
+			return nil, err
+		}
+		if attrs == device.Attributes() {
+			return device, nil
+		}
+		if err := device.Apply(attrs); err != nil {
+			return nil, err
+		}
+		if err := tx.repo.Update(ctx, device); err != nil {
+			return nil, err
+		}
 
-func (s *DeviceService) DeleteDevice(id string) error {
-	device, err := s.repo.FindByID(id)
+		action := domain.HistoryActionUpdated
+		if before.Name == device.Name && before.Brand == device.Brand {
+			action = domain.HistoryActionStateChanged
+		}
+		if err := tx.recordChange(ctx, id, action, before, device); err != nil {
+			return nil, err
+		}
+		return device, nil
+	})
+}
+
+// UpsertDevice updates the device with the given ID, or creates it when
+// there is none. An empty ID always creates a device with a generated ID and
+// an empty state leaves the state as it is. It reports whether the device
+// was created.
+func (s *DeviceService) UpsertDevice(ctx context.Context, id string, attrs domain.DeviceAttributes) (*domain.Device, bool, error) {
+	if id != "" {
+		device, err := s.ModifyDevice(ctx, id, func(current *domain.DeviceAttributes) error {
+			if attrs.State == "" {
+				attrs.State = current.State
+			}
+			*current = attrs
+			return nil
+		})
+		if !errors.Is(err, domain.ErrDeviceNotFound) {
+			return device, false, err
+		}
+	}
+
+	id, err := s.deviceID(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	device, err := s.deviceInTransaction(ctx, func(tx *DeviceService) (*domain.Device, error) {
+		return tx.createDevice(ctx, id, attrs)
+	})
+	return device, err == nil, err
+}
+
+func (s *DeviceService) CheckoutDevice(ctx context.Context, id, assignee string, expectedReturnAt *time.Time) (*domain.Device, error) {
+	return s.deviceInTransaction(ctx, func(tx *DeviceService) (*domain.Device, error) {
+		device, err := tx.repo.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.checkPrecondition(device); err != nil {
+			return nil, err
+		}
+		before := domain.SnapshotOf(device)
+
+		if err := tx.checkReservations(ctx, id, assignee, expectedReturnAt); err != nil {
+			return nil, err
+		}
+		if err := device.Checkout(assignee, expectedReturnAt); err != nil {
+			return nil, err
+		}
+		if err := tx.repo.Update(ctx, device); err != nil {
+			return nil, err
+		}
+		if err := tx.recordChange(ctx, id, domain.HistoryActionCheckedOut, before, device); err != nil {
+			return nil, err
+		}
+		return device, nil
+	})
+}
+
+func (s *DeviceService) checkReservations(ctx context.Context, id, assignee string, until *time.Time) error {
+	if s.reservations == nil {
+		return nil
+	}
+
+	now := time.Now()
+	reservations, err := s.reservations.FindByDevice(ctx, id, now)
+	if err != nil {
+		return err
+	}
+	for _, reservation := range reservations {
+		if reservation.BlocksCheckout(assignee, now, until) {
+			return domain.ErrDeviceReserved
+		}
+	}
+	return nil
+}
+
+func (s *DeviceService) CheckinDevice(ctx context.Context, id string) (*domain.Device, error) {
+	return s.deviceInTransaction(ctx, func(tx *DeviceService) (*domain.Device, error) {
+		device, err := tx.repo.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.checkPrecondition(device); err != nil {
+			return nil, err
+		}
+		before := domain.SnapshotOf(device)
+
+		if err := device.CheckIn(); err != nil {
+			return nil, err
+		}
+		if err := tx.repo.Update(ctx, device); err != nil {
+			return nil, err
+		}
+		if err := tx.recordChange(ctx, id, domain.HistoryActionCheckedIn, before, device); err != nil {
+			return nil, err
+		}
+		return device, nil
+	})
+}
+
+func (s *DeviceService) DeleteDevice(ctx context.Context, id string) error {
+	return s.inTransaction(ctx, func(tx *DeviceService) error {
+		device, err := tx.repo.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.checkPrecondition(device); err != nil {
+			return err
+		}
+		if err := device.CanBeDeleted(); err != nil {
+			return err
+		}
+
+		if err := tx.repo.Delete(ctx, id, device.Version); err != nil {
+			return err
+		}
+		return tx.record(ctx, id, domain.HistoryActionDeleted, domain.SnapshotOf(device), nil)
+	})
+}
+
+func (s *DeviceService) RestoreDevice(ctx context.Context, id string) (*domain.Device, error) {
+	return s.deviceInTransaction(ctx, func(tx *DeviceService) (*domain.Device, error) {
+		device, err := tx.repo.Unscoped().FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if !device.IsDeleted() {
+			return nil, domain.ErrDeviceNotDeleted
+		}
+		if err := tx.checkPrecondition(device); err != nil {
+			return nil, err
+		}
+
+		if err := tx.repo.Restore(ctx, id, device.Version); err != nil {
+			return nil, err
+		}
+		device.DeletedAt = nil
+		device.Version++
+		if err := tx.record(ctx, id, domain.HistoryActionRestored, nil, device); err != nil {
+			return nil, err
+		}
+		return device, nil
+	})
+}
+
+// PurgeDeletedDevices permanently removes devices that were soft deleted
+// longer ago than the configured retention and returns their IDs.
+func (s *DeviceService) PurgeDeletedDevices(ctx context.Context) ([]string, error) {
+	var ids []string
+	err := s.inTransaction(ctx, func(tx *DeviceService) error {
+		var err error
+		ids, err = tx.repo.PurgeDeletedBefore(ctx, time.Now().Add(-tx.retention))
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := tx.record(ctx, id, domain.HistoryActionPurged, nil, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// DeviceHistory returns one page of a device's change history, newest first,
+// and the total number of entries. History outlives the device itself, so
+// ErrDeviceNotFound is only returned when neither exists.
+func (s *DeviceService) DeviceHistory(ctx context.Context, id string, limit, offset int) ([]*domain.DeviceHistoryEntry, int64, error) {
+	if s.history == nil {
+		if _, err := s.repo.Unscoped().FindByID(ctx, id); err != nil {
+			return nil, 0, err
+		}
+		return []*domain.DeviceHistoryEntry{}, 0, nil
+	}
+
+	entries, total, err := s.history.FindByDevice(ctx, id, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		if _, err := s.repo.Unscoped().FindByID(ctx, id); err != nil {
+			return nil, 0, err
+		}
+	}
+	return entries, total, nil
+}
+
+// inTransaction runs fn with a copy of the service whose repositories take
+// part in one unit of work. Without a unit of work fn runs on s itself. The
+// copy has no unit of work of its own, so service methods called on it join
+// the current one.
+func (s *DeviceService) inTransaction(ctx context.Context, fn func(tx *DeviceService) error) error {
+	if s.uow == nil {
+		return fn(s)
+	}
+	var events []domain.DeviceEvent
+	err := s.uow.Do(ctx, func(repos domain.Repositories) error {
+		tx := *s
+		tx.uow = nil
+		tx.pending = &events
+		tx.repo = repos.Devices
+		if s.reservations != nil {
+			tx.reservations = repos.Reservations
+		}
+		if s.history != nil {
+			tx.history = repos.History
+		}
+		if s.outbox != nil {
+			tx.outbox = repos.Outbox
+		}
+		return fn(&tx)
+	})
 	if err != nil {
 		return err
 	}
+	for _, event := range events {
+		s.events.Publish(event)
+	}
+	return nil
+}
 
-	if err := device.CanBeDeleted(); err != nil {
+func (s *DeviceService) deviceInTransaction(ctx context.Context, fn func(tx *DeviceService) (*domain.Device, error)) (*domain.Device, error) {
+	var device *domain.Device
+	err := s.inTransaction(ctx, func(tx *DeviceService) error {
+		var err error
+		device, err = fn(tx)
 		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+func (s *DeviceService) checkPrecondition(device *domain.Device) error {
+	if s.ifMatch == nil {
+		return nil
+	}
+	for _, version := range s.ifMatch {
+		if version == device.Version {
+			return nil
+		}
 	}
+	return domain.ErrPreconditionFailed
+}
 
-	return s.repo.Delete(id)
+func (s *DeviceService) record(ctx context.Context, id string, action domain.HistoryAction, before *domain.DeviceSnapshot, after *domain.Device) error {
+	if s.history != nil {
+		entry := domain.NewDeviceHistoryEntry(id, action, before, domain.SnapshotOf(after), s.actor)
+		if err := s.history.Append(ctx, entry); err != nil {
+			return err
+		}
+	}
+	if s.outbox != nil {
+		message, err := domain.NewOutboxMessage(domain.NewDeviceEvent(id, action, before, after, s.actor))
+		if err != nil {
+			return err
+		}
+		return s.outbox.Append(ctx, message)
+	}
+	if s.events != nil {
+		event := domain.NewDeviceEvent(id, action, before, after, s.actor)
+		if s.pending != nil {
+			*s.pending = append(*s.pending, event)
+		} else {
+			s.events.Publish(event)
+		}
+	}
+	return nil
+}
+
+// recordChange records an update only if it actually changed the device.
+func (s *DeviceService) recordChange(ctx context.Context, id string, action domain.HistoryAction, before *domain.DeviceSnapshot, after *domain.Device) error {
+	if *before == *domain.SnapshotOf(after) {
+		return nil
+	}
+	return s.record(ctx, id, action, before, after)
 }
@@ -1,25 +1,32 @@
 package service_test
 
 import (
+	"context"
 	"device-api/internal/domain"
 	"device-api/internal/service"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+// ctx is the context of the service calls made by the tests.
+var ctx = context.Background()
+
 // MockRepository is a mock implementation of domain.IDeviceRepository
 type MockRepository struct {
 	mock.Mock
 }
 
-func (m *MockRepository) Save(device *domain.Device) error {
+func (m *MockRepository) Save(ctx context.Context, device *domain.Device) error {
 	args := m.Called(device)
 	return args.Error(0)
 }
 
-func (m *MockRepository) FindByID(id string) (*domain.Device, error) {
+func (m *MockRepository) FindByID(ctx context.Context, id string) (*domain.Device, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -27,39 +34,46 @@ func (m *MockRepository) FindByID(id string) (*domain.Device, error) {
 	return args.Get(0).(*domain.Device), args.Error(1)
 }
 
-func (m *MockRepository) FindAll() ([]*domain.Device, error) {
-	args := m.Called()
-	return args.Get(0).([]*domain.Device), args.Error(1)
+func (m *MockRepository) Find(ctx context.Context, query domain.DeviceQuery) (*domain.DevicePage, error) {
+	args := m.Called(query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.DevicePage), args.Error(1)
 }
 
-func (m *MockRepository) FindByBrand(brand string) ([]*domain.Device, error) {
-	args := m.Called(brand)
-	return args.Get(0).([]*domain.Device), args.Error(1)
+func (m *MockRepository) Delete(ctx context.Context, id string, version int64) error {
+	args := m.Called(id, version)
+	return args.Error(0)
 }
 
-func (m *MockRepository) FindByState(state domain.DeviceState) ([]*domain.Device, error) {
-	args := m.Called(state)
-	return args.Get(0).([]*domain.Device), args.Error(1)
+func (m *MockRepository) Update(ctx context.Context, device *domain.Device) error {
+	args := m.Called(device)
+	return args.Error(0)
 }
 
-func (m *MockRepository) Delete(id string) error {
-	args := m.Called(id)
+func (m *MockRepository) Restore(ctx context.Context, id string, version int64) error {
+	args := m.Called(id, version)
 	return args.Error(0)
 }
 
-func (m *MockRepository) Update(device *domain.Device) error {
-	args := m.Called(device)
-	return args.Error(0)
+func (m *MockRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) ([]string, error) {
+	args := m.Called(cutoff)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// Unscoped returns the mock itself; deletion scoping is not modelled.
+func (m *MockRepository) Unscoped() domain.IDeviceRepository {
+	return m
 }
 
 func TestCreateDevice(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
         mockRepo := new(MockRepository)
         svc := service.NewDeviceService(mockRepo)
-		mockRepo.On("FindByID", "123").Return(nil, domain.ErrDeviceNotFound)
 		mockRepo.On("Save", mock.AnythingOfType("*domain.Device")).Return(nil)
 
-		device, err := svc.CreateDevice("123", "Pixel", "Google")
+		device, err := svc.CreateDevice(ctx, "123", "Pixel", "Google")
 		assert.NoError(t, err)
 		assert.Equal(t, "123", device.ID)
 		assert.Equal(t, "Pixel", device.Name)
@@ -70,11 +84,105 @@ func TestCreateDevice(t *testing.T) {
 	t.Run("already exists", func(t *testing.T) {
         mockRepo := new(MockRepository)
         svc := service.NewDeviceService(mockRepo)
-		existing := &domain.Device{ID: "123"}
-		mockRepo.On("FindByID", "123").Return(existing, nil)
+		mockRepo.On("Save", mock.AnythingOfType("*domain.Device")).Return(domain.ErrDeviceAlreadyExists)
 
-		_, err := svc.CreateDevice("123", "Pixel", "Google")
+		_, err := svc.CreateDevice(ctx, "123", "Pixel", "Google")
 		assert.ErrorIs(t, err, domain.ErrDeviceAlreadyExists)
+		mockRepo.AssertNotCalled(t, "FindByID", mock.Anything)
+	})
+}
+
+type stubIDGenerator struct {
+	id string
+}
+
+func (g stubIDGenerator) NewID(ctx context.Context) (string, error) {
+	return g.id, nil
+}
+
+// Reserved reserves the IDs sharing the prefix of the stub ID.
+func (g stubIDGenerator) Reserved(id string) bool {
+	return strings.HasPrefix(id, "DEV-")
+}
+
+func TestCreateDeviceIDPolicy(t *testing.T) {
+	t.Run("success_generated_id", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo, service.WithIDGenerator(stubIDGenerator{id: "DEV-000001"}))
+		mockRepo.On("Save", mock.AnythingOfType("*domain.Device")).Return(nil)
+
+		device, err := svc.CreateDevice(ctx, "", "Pixel", "Google")
+		assert.NoError(t, err)
+		assert.Equal(t, "DEV-000001", device.ID)
+	})
+
+	t.Run("fail_no_id_without_generator", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+
+		_, err := svc.CreateDevice(ctx, "", "Pixel", "Google")
+		assert.ErrorIs(t, err, domain.ErrDeviceIDRequired)
+		mockRepo.AssertNotCalled(t, "Save", mock.Anything)
+	})
+
+	t.Run("fail_client_id_not_allowed", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(
+			mockRepo,
+			service.WithIDGenerator(stubIDGenerator{id: "DEV-000001"}),
+			service.WithClientIDs(false),
+		)
+
+		_, err := svc.CreateDevice(ctx, "123", "Pixel", "Google")
+		assert.ErrorIs(t, err, domain.ErrClientIDNotAllowed)
+		mockRepo.AssertNotCalled(t, "Save", mock.Anything)
+	})
+
+	t.Run("fail_reserved_client_id", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo, service.WithIDGenerator(stubIDGenerator{id: "DEV-000001"}))
+
+		_, err := svc.CreateDevice(ctx, "DEV-000002", "Pixel", "Google")
+		assert.ErrorIs(t, err, domain.ErrDeviceIDReserved)
+		_, err = svc.DryRun().CreateDevice(ctx, "DEV-000002", "Pixel", "Google")
+		assert.ErrorIs(t, err, domain.ErrDeviceIDReserved)
+		mockRepo.AssertNotCalled(t, "Save", mock.Anything)
+	})
+}
+
+func TestListDevices(t *testing.T) {
+	t.Run("passes_query_to_repository", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		criteria := domain.DeviceCriteria{
+			Brands: []string{"Apple"},
+			States: []domain.DeviceState{domain.DeviceStateAvailable, domain.DeviceStateInUse},
+		}
+		mockRepo.On("Find", domain.DeviceQuery{Criteria: criteria, Limit: service.DefaultPageSize}).
+			Return(&domain.DevicePage{Items: []*domain.Device{domain.NewDevice("1", "iPhone", "Apple")}}, nil)
+
+		page, err := svc.ListDevices(ctx, domain.DeviceQuery{Criteria: criteria})
+		assert.NoError(t, err)
+		assert.Len(t, page.Items, 1)
+	})
+
+	t.Run("caps_page_size", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		mockRepo.On("Find", domain.DeviceQuery{Limit: service.MaxPageSize}).Return(&domain.DevicePage{}, nil)
+
+		_, err := svc.ListDevices(ctx, domain.DeviceQuery{Limit: 10000})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("fail_unknown_state", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+
+		_, err := svc.ListDevices(ctx, domain.DeviceQuery{Criteria: domain.DeviceCriteria{States: []domain.DeviceState{"banana"}}})
+		assert.ErrorIs(t, err, domain.ErrInvalidFilter)
+		mockRepo.AssertNotCalled(t, "Find", mock.Anything)
 	})
 }
 
@@ -88,7 +196,7 @@ func TestUpdateDevice(t *testing.T) {
 			return d.Name == "New" && d.Brand == "NewBrand"
 		})).Return(nil)
 
-		updated, err := svc.UpdateDevice("123", "New", "NewBrand")
+		updated, err := svc.UpdateDevice(ctx, "123", "New", "NewBrand")
 		assert.NoError(t, err)
 		assert.Equal(t, "New", updated.Name)
 	})
@@ -100,7 +208,7 @@ func TestUpdateDevice(t *testing.T) {
         existing.State = domain.DeviceStateInUse
 		mockRepo.On("FindByID", "123").Return(existing, nil)
         
-		_, err := svc.UpdateDevice("123", "New", "NewBrand")
+		_, err := svc.UpdateDevice(ctx, "123", "New", "NewBrand")
 		assert.ErrorIs(t, err, domain.ErrDeviceInUse)
 	})
 }
@@ -111,9 +219,9 @@ func TestDeleteDevice(t *testing.T) {
         svc := service.NewDeviceService(mockRepo)
         existing := domain.NewDevice("123", "Old", "OldBrand")
         mockRepo.On("FindByID", "123").Return(existing, nil)
-        mockRepo.On("Delete", "123").Return(nil)
+        mockRepo.On("Delete", "123", int64(1)).Return(nil)
         
-        err := svc.DeleteDevice("123")
+        err := svc.DeleteDevice(ctx, "123")
         assert.NoError(t, err)
     })
     
@@ -124,7 +232,490 @@ func TestDeleteDevice(t *testing.T) {
         existing.State = domain.DeviceStateInUse
         mockRepo.On("FindByID", "123").Return(existing, nil)
         
-        err := svc.DeleteDevice("123")
+        err := svc.DeleteDevice(ctx, "123")
         assert.ErrorIs(t, err, domain.ErrDeviceInUse)
     })
 }
+
+func TestUpdateDeviceState(t *testing.T) {
+	t.Run("success_take_into_use", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+		mockRepo.On("Update", mock.MatchedBy(func(d *domain.Device) bool {
+			return d.State == domain.DeviceStateInUse
+		})).Return(nil)
+
+		updated, err := svc.UpdateDeviceState(ctx, "123", domain.DeviceStateInUse)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.DeviceStateInUse, updated.State)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("fail_inactive_to_in_use", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		existing.State = domain.DeviceStateInactive
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+
+		_, err := svc.UpdateDeviceState(ctx, "123", domain.DeviceStateInUse)
+		assert.ErrorIs(t, err, domain.ErrInvalidDeviceState)
+
+		var transitionErr *domain.StateTransitionError
+		assert.ErrorAs(t, err, &transitionErr)
+		assert.Equal(t, []domain.DeviceState{domain.DeviceStateAvailable}, transitionErr.Allowed)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("fail_unknown_state", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+
+		_, err := svc.UpdateDeviceState(ctx, "123", domain.DeviceState("banana"))
+		assert.ErrorIs(t, err, domain.ErrInvalidDeviceState)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+}
+
+func TestReplaceDevice(t *testing.T) {
+	t.Run("success_release_and_rename", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		existing.State = domain.DeviceStateInUse
+		existing.Assignee = "alice"
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+		mockRepo.On("Update", mock.MatchedBy(func(d *domain.Device) bool {
+			return d.Name == "Pixel 9" && d.State == domain.DeviceStateAvailable && d.Assignee == ""
+		})).Return(nil).Once()
+
+		updated, err := svc.ReplaceDevice(ctx, "123", domain.DeviceAttributes{Name: "Pixel 9", Brand: "Google", State: domain.DeviceStateAvailable})
+		assert.NoError(t, err)
+		assert.Equal(t, "Pixel 9", updated.Name)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("fail_rename_while_in_use_leaves_device", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		existing.State = domain.DeviceStateInUse
+		existing.Assignee = "alice"
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+
+		_, err := svc.ReplaceDevice(ctx, "123", domain.DeviceAttributes{Name: "Pixel 9", Brand: "Google", State: domain.DeviceStateInUse})
+		assert.ErrorIs(t, err, domain.ErrDeviceInUse)
+		assert.Equal(t, "Pixel", existing.Name)
+		assert.Equal(t, "alice", existing.Assignee)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("fail_cleared_brand", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		mockRepo.On("FindByID", "123").Return(domain.NewDevice("123", "Pixel", "Google"), nil)
+
+		_, err := svc.ReplaceDevice(ctx, "123", domain.DeviceAttributes{Name: "Pixel", State: domain.DeviceStateAvailable})
+		assert.ErrorIs(t, err, domain.ErrDeviceBrandRequired)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("unchanged_is_not_written", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		mockRepo.On("FindByID", "123").Return(domain.NewDevice("123", "Pixel", "Google"), nil)
+
+		_, err := svc.ReplaceDevice(ctx, "123", domain.DeviceAttributes{Name: "Pixel", Brand: "Google", State: domain.DeviceStateAvailable})
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+}
+
+// fakeUnitOfWork hands its repositories to the work and remembers whether
+// the work failed, which a real unit of work would roll back.
+type fakeUnitOfWork struct {
+	repos      domain.Repositories
+	rolledBack bool
+}
+
+func (u *fakeUnitOfWork) Do(ctx context.Context, fn func(repos domain.Repositories) error) error {
+	err := fn(u.repos)
+	u.rolledBack = err != nil
+	return err
+}
+
+func TestUnitOfWork(t *testing.T) {
+	t.Run("writes_go_through_the_unit_of_work", func(t *testing.T) {
+		outside, inside := new(MockRepository), new(MockRepository)
+		uow := &fakeUnitOfWork{repos: domain.Repositories{Devices: inside}}
+		svc := service.NewDeviceService(outside, service.WithUnitOfWork(uow))
+		inside.On("FindByID", "123").Return(domain.NewDevice("123", "Pixel", "Google"), nil)
+		inside.On("Update", mock.Anything).Return(nil)
+
+		_, err := svc.ReplaceDevice(ctx, "123", domain.DeviceAttributes{Name: "Pixel 9", Brand: "Google", State: domain.DeviceStateAvailable})
+		assert.NoError(t, err)
+		assert.False(t, uow.rolledBack)
+		inside.AssertExpectations(t)
+		outside.AssertNotCalled(t, "FindByID", mock.Anything)
+	})
+
+	t.Run("failed_history_rolls_back", func(t *testing.T) {
+		repo, history := new(MockRepository), new(MockHistoryRepository)
+		uow := &fakeUnitOfWork{repos: domain.Repositories{Devices: repo, History: history}}
+		svc := service.NewDeviceService(repo, service.WithHistory(history), service.WithUnitOfWork(uow))
+		repo.On("FindByID", "123").Return(domain.NewDevice("123", "Pixel", "Google"), nil)
+		repo.On("Update", mock.Anything).Return(nil)
+		history.On("Append", mock.Anything).Return(errors.New("history unavailable"))
+
+		_, err := svc.UpdateDeviceState(ctx, "123", domain.DeviceStateInactive)
+		assert.Error(t, err)
+		assert.True(t, uow.rolledBack)
+	})
+}
+
+// recordingPublisher keeps the events published to it.
+type recordingPublisher struct {
+	events []domain.DeviceEvent
+}
+
+func (p *recordingPublisher) Publish(event domain.DeviceEvent) {
+	p.events = append(p.events, event)
+}
+
+func TestEvents(t *testing.T) {
+	t.Run("published_after_commit", func(t *testing.T) {
+		repo, events := new(MockRepository), new(recordingPublisher)
+		uow := &fakeUnitOfWork{repos: domain.Repositories{Devices: repo}}
+		svc := service.NewDeviceService(repo, service.WithUnitOfWork(uow), service.WithEvents(events))
+		repo.On("FindByID", "123").Return(domain.NewDevice("123", "Pixel", "Google"), nil)
+		repo.On("Update", mock.Anything).Return(nil)
+
+		_, err := svc.As("alice").UpdateDeviceState(ctx, "123", domain.DeviceStateInactive)
+		assert.NoError(t, err)
+		if assert.Len(t, events.events, 1) {
+			event := events.events[0]
+			assert.Equal(t, domain.HistoryActionStateChanged, event.Action)
+			assert.Equal(t, domain.DeviceStateAvailable, event.Previous.State)
+			assert.Equal(t, domain.DeviceStateInactive, event.Device.State)
+			assert.Equal(t, "alice", event.Actor)
+		}
+	})
+
+	t.Run("dropped_on_rollback", func(t *testing.T) {
+		repo, history, events := new(MockRepository), new(MockHistoryRepository), new(recordingPublisher)
+		uow := &fakeUnitOfWork{repos: domain.Repositories{Devices: repo, History: history}}
+		svc := service.NewDeviceService(repo, service.WithHistory(history), service.WithUnitOfWork(uow), service.WithEvents(events))
+		repo.On("FindByID", "123").Return(domain.NewDevice("123", "Pixel", "Google"), nil)
+		repo.On("Update", mock.Anything).Return(nil)
+		history.On("Append", mock.Anything).Return(errors.New("history unavailable"))
+
+		_, err := svc.UpdateDeviceState(ctx, "123", domain.DeviceStateInactive)
+		assert.Error(t, err)
+		assert.Empty(t, events.events)
+	})
+}
+
+// recordingOutbox keeps the messages appended to it.
+type recordingOutbox struct {
+	messages []*domain.OutboxMessage
+}
+
+func (o *recordingOutbox) Append(ctx context.Context, message *domain.OutboxMessage) error {
+	o.messages = append(o.messages, message)
+	return nil
+}
+
+func (o *recordingOutbox) Lock(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+func (o *recordingOutbox) FindPending(ctx context.Context, limit int, skip []string) ([]*domain.OutboxMessage, error) {
+	return o.messages, nil
+}
+
+func (o *recordingOutbox) Remove(ctx context.Context, ids []uint) error {
+	return nil
+}
+
+func (o *recordingOutbox) RecordFailure(ctx context.Context, message *domain.OutboxMessage) error {
+	return nil
+}
+
+func TestOutbox(t *testing.T) {
+	repo, outside, inside, events := new(MockRepository), new(recordingOutbox), new(recordingOutbox), new(recordingPublisher)
+	uow := &fakeUnitOfWork{repos: domain.Repositories{Devices: repo, Outbox: inside}}
+	svc := service.NewDeviceService(repo, service.WithUnitOfWork(uow), service.WithOutbox(outside), service.WithEvents(events))
+	repo.On("FindByID", "123").Return(domain.NewDevice("123", "Pixel", "Google"), nil)
+	repo.On("Update", mock.Anything).Return(nil)
+
+	_, err := svc.UpdateDeviceState(ctx, "123", domain.DeviceStateInactive)
+	assert.NoError(t, err)
+	assert.Empty(t, outside.messages)
+	assert.Empty(t, events.events, "the outbox takes the place of direct publishing")
+	if assert.Len(t, inside.messages, 1) {
+		event, err := inside.messages[0].Event()
+		assert.NoError(t, err)
+		assert.Equal(t, domain.HistoryActionStateChanged, event.Action)
+		assert.Equal(t, domain.DeviceStateInactive, event.Device.State)
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	t.Run("success_matching_version", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		existing := domain.NewDevice("123", "Old", "OldBrand")
+		existing.Version = 3
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+		mockRepo.On("Update", mock.AnythingOfType("*domain.Device")).Return(nil)
+
+		_, err := svc.IfMatch(2, 3).UpdateDevice(ctx, "123", "New", "NewBrand")
+		assert.NoError(t, err)
+	})
+
+	t.Run("fail_stale_version", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		existing := domain.NewDevice("123", "Old", "OldBrand")
+		existing.Version = 3
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+
+		err := svc.IfMatch(2).DeleteDevice(ctx, "123")
+		assert.ErrorIs(t, err, domain.ErrPreconditionFailed)
+		mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("fail_concurrent_write", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		existing := domain.NewDevice("123", "Old", "OldBrand")
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+		mockRepo.On("Update", mock.AnythingOfType("*domain.Device")).Return(domain.ErrVersionConflict)
+
+		_, err := svc.UpdateDeviceState(ctx, "123", domain.DeviceStateInUse)
+		assert.ErrorIs(t, err, domain.ErrVersionConflict)
+	})
+}
+
+func TestRestoreDevice(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		deletedAt := time.Now().Add(-time.Hour)
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		existing.DeletedAt = &deletedAt
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+		mockRepo.On("Restore", "123", int64(1)).Return(nil)
+
+		device, err := svc.RestoreDevice(ctx, "123")
+		assert.NoError(t, err)
+		assert.False(t, device.IsDeleted())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("fail_not_deleted", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		mockRepo.On("FindByID", "123").Return(domain.NewDevice("123", "Pixel", "Google"), nil)
+
+		_, err := svc.RestoreDevice(ctx, "123")
+		assert.ErrorIs(t, err, domain.ErrDeviceNotDeleted)
+		mockRepo.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything)
+	})
+}
+
+func TestDeleteAndRestoreWriteTheVersionRead(t *testing.T) {
+	t.Run("fail_delete_stale_version", func(t *testing.T) {
+		mockRepo, history := new(MockRepository), new(MockHistoryRepository)
+		svc := service.NewDeviceService(mockRepo, service.WithHistory(history))
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		existing.Version = 4
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+		mockRepo.On("Delete", "123", int64(4)).Return(domain.ErrVersionConflict)
+
+		err := svc.IfMatch(4).DeleteDevice(ctx, "123")
+		assert.ErrorIs(t, err, domain.ErrVersionConflict)
+		history.AssertNotCalled(t, "Append", mock.Anything)
+	})
+
+	t.Run("fail_restore_stale_version", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		deletedAt := time.Now().Add(-time.Hour)
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		existing.DeletedAt = &deletedAt
+		existing.Version = 2
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+		mockRepo.On("Restore", "123", int64(2)).Return(domain.ErrVersionConflict)
+
+		_, err := svc.RestoreDevice(ctx, "123")
+		assert.ErrorIs(t, err, domain.ErrVersionConflict)
+	})
+}
+
+func TestPurgeDeletedDevices(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := service.NewDeviceService(mockRepo, service.WithDeletedRetention(time.Hour))
+	mockRepo.On("PurgeDeletedBefore", mock.MatchedBy(func(cutoff time.Time) bool {
+		return time.Since(cutoff) >= time.Hour && time.Since(cutoff) < time.Hour+time.Minute
+	})).Return([]string{"1", "2"}, nil)
+
+	ids, err := svc.PurgeDeletedDevices(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, ids)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCheckoutDevice(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+		mockRepo.On("Update", mock.MatchedBy(func(d *domain.Device) bool {
+			return d.State == domain.DeviceStateInUse && d.Assignee == "alice"
+		})).Return(nil)
+
+		returnAt := time.Now().Add(24 * time.Hour)
+		device, err := svc.CheckoutDevice(ctx, "123", "alice", &returnAt)
+		assert.NoError(t, err)
+		assert.Equal(t, "alice", device.Assignee)
+		assert.NotNil(t, device.CheckedOutAt)
+		assert.Equal(t, &returnAt, device.ExpectedReturnAt)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("fail_already_in_use", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		existing.State = domain.DeviceStateInUse
+		existing.Assignee = "bob"
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+
+		_, err := svc.CheckoutDevice(ctx, "123", "alice", nil)
+		assert.ErrorIs(t, err, domain.ErrDeviceInUse)
+		assert.Equal(t, "bob", existing.Assignee)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("fail_inactive", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		existing.State = domain.DeviceStateInactive
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+
+		_, err := svc.CheckoutDevice(ctx, "123", "alice", nil)
+		assert.ErrorIs(t, err, domain.ErrDeviceInactive)
+	})
+
+	t.Run("fail_return_in_past", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+
+		returnAt := time.Now().Add(-time.Hour)
+		_, err := svc.CheckoutDevice(ctx, "123", "alice", &returnAt)
+		assert.ErrorIs(t, err, domain.ErrInvalidReturnTime)
+		assert.Equal(t, domain.DeviceStateAvailable, existing.State)
+	})
+}
+
+func TestCheckinDevice(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		assert.NoError(t, existing.Checkout("alice", nil))
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+		mockRepo.On("Update", mock.AnythingOfType("*domain.Device")).Return(nil)
+
+		device, err := svc.CheckinDevice(ctx, "123")
+		assert.NoError(t, err)
+		assert.Equal(t, domain.DeviceStateAvailable, device.State)
+		assert.Empty(t, device.Assignee)
+		assert.Nil(t, device.CheckedOutAt)
+	})
+
+	t.Run("fail_not_checked_out", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+
+		_, err := svc.CheckinDevice(ctx, "123")
+		assert.ErrorIs(t, err, domain.ErrDeviceNotCheckedOut)
+	})
+}
+
+func TestUpsertDevice(t *testing.T) {
+	t.Run("updates_existing_device", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		existing := domain.NewDevice("123", "Pixel", "Google")
+		existing.State = domain.DeviceStateInactive
+		mockRepo.On("FindByID", "123").Return(existing, nil)
+		mockRepo.On("Update", mock.Anything).Return(nil)
+
+		device, created, err := svc.UpsertDevice(ctx, "123", domain.DeviceAttributes{Name: "Pixel 8", Brand: "Google"})
+		assert.NoError(t, err)
+		assert.False(t, created)
+		assert.Equal(t, "Pixel 8", device.Name)
+		assert.Equal(t, domain.DeviceStateInactive, device.State)
+	})
+
+	t.Run("creates_missing_device", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		svc := service.NewDeviceService(mockRepo)
+		mockRepo.On("FindByID", "123").Return(nil, domain.ErrDeviceNotFound)
+		mockRepo.On("Save", mock.MatchedBy(func(d *domain.Device) bool {
+			return d.ID == "123" && d.State == domain.DeviceStateInactive
+		})).Return(nil)
+
+		_, created, err := svc.UpsertDevice(ctx, "123", domain.DeviceAttributes{Name: "Pixel", Brand: "Google", State: domain.DeviceStateInactive})
+		assert.NoError(t, err)
+		assert.True(t, created)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestDryRun(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := service.NewDeviceService(mockRepo).DryRun()
+	mockRepo.On("FindByID", "123").Return(nil, domain.ErrDeviceNotFound)
+
+	_, err := svc.CreateDevice(ctx, "123", "Pixel", "Google")
+	assert.NoError(t, err)
+	_, err = svc.CreateDevice(ctx, "123", "Pixel", "Google")
+	assert.ErrorIs(t, err, domain.ErrDeviceAlreadyExists)
+
+	device, err := svc.UpdateDeviceState(ctx, "123", domain.DeviceStateInUse)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), device.Version)
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestExportDevices(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := service.NewDeviceService(mockRepo)
+	mockRepo.On("Find", mock.MatchedBy(func(q domain.DeviceQuery) bool { return q.Cursor == "" })).
+		Return(&domain.DevicePage{Items: []*domain.Device{domain.NewDevice("1", "Pixel", "Google")}, NextCursor: "next"}, nil)
+	mockRepo.On("Find", mock.MatchedBy(func(q domain.DeviceQuery) bool { return q.Cursor == "next" })).
+		Return(&domain.DevicePage{Items: []*domain.Device{domain.NewDevice("2", "iPhone", "Apple")}}, nil)
+
+	var ids []string
+	err := svc.ExportDevices(ctx, domain.DeviceQuery{Limit: 1, Cursor: "ignored"}, func(d *domain.Device) error {
+		ids = append(ids, d.ID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, ids)
+	mockRepo.AssertNumberOfCalls(t, "Find", 2)
+}
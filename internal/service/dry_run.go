@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"strconv"
+	"time"
+)
+
+// DryRun returns a copy of the service that checks every rule but writes
+// nothing, records no history and publishes no events. Devices it would
+// have created, changed or deleted are remembered, so a sequence of calls on
+// the copy behaves as it would for real. Listings are not affected by these
+// pending changes.
+func (s *DeviceService) DryRun() *DeviceService {
+	scoped := *s
+	scoped.repo = &dryRunRepository{
+		IDeviceRepository: s.repo,
+		pending:           map[string]*domain.Device{},
+	}
+	scoped.history = nil
+	scoped.events = nil
+	scoped.outbox = nil
+	scoped.uow = nil
+	if s.ids != nil {
+		// Real generators may consume IDs, e.g. from a sequence.
+		scoped.ids = &placeholderIDs{real: s.ids}
+	}
+	return &scoped
+}
+
+// dryRunRepository keeps writes in memory on top of a real repository.
+type dryRunRepository struct {
+	domain.IDeviceRepository
+	pending  map[string]*domain.Device
+	unscoped bool
+}
+
+func (r *dryRunRepository) FindByID(ctx context.Context, id string) (*domain.Device, error) {
+	if device, ok := r.pending[id]; ok {
+		if device.IsDeleted() && !r.unscoped {
+			return nil, domain.ErrDeviceNotFound
+		}
+		copied := *device
+		return &copied, nil
+	}
+	return r.IDeviceRepository.FindByID(ctx, id)
+}
+
+func (r *dryRunRepository) Save(ctx context.Context, device *domain.Device) error {
+	if _, ok := r.pending[device.ID]; ok {
+		return domain.ErrDeviceAlreadyExists
+	}
+	if _, err := r.IDeviceRepository.Unscoped().FindByID(ctx, device.ID); err == nil {
+		return domain.ErrDeviceAlreadyExists
+	}
+	r.keep(device)
+	return nil
+}
+
+func (r *dryRunRepository) Update(ctx context.Context, device *domain.Device) error {
+	device.Version++
+	r.keep(device)
+	return nil
+}
+
+func (r *dryRunRepository) Delete(ctx context.Context, id string, version int64) error {
+	device, err := r.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if device.Version != version {
+		return domain.ErrVersionConflict
+	}
+	now := time.Now()
+	device.DeletedAt = &now
+	device.Version++
+	r.keep(device)
+	return nil
+}
+
+func (r *dryRunRepository) Restore(ctx context.Context, id string, version int64) error {
+	if device, ok := r.pending[id]; ok {
+		device.DeletedAt = nil
+		device.Version++
+	}
+	return nil
+}
+
+func (r *dryRunRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) ([]string, error) {
+	return nil, nil
+}
+
+func (r *dryRunRepository) Unscoped() domain.IDeviceRepository {
+	return &dryRunRepository{
+		IDeviceRepository: r.IDeviceRepository.Unscoped(),
+		pending:           r.pending,
+		unscoped:          true,
+	}
+}
+
+func (r *dryRunRepository) keep(device *domain.Device) {
+	copied := *device
+	r.pending[device.ID] = &copied
+}
+
+// placeholderIDs stands in for the ID generator during a dry run. It
+// reserves the IDs the real generator reserves.
+type placeholderIDs struct {
+	n    int
+	real domain.IDGenerator
+}
+
+func (g *placeholderIDs) Reserved(id string) bool {
+	reserver, ok := g.real.(domain.IDReserver)
+	return ok && reserver.Reserved(id)
+}
+
+func (g *placeholderIDs) NewID(context.Context) (string, error) {
+	g.n++
+	return "generated-" + strconv.Itoa(g.n), nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReservationService struct {
+	reservations domain.IReservationRepository
+	devices      domain.IDeviceRepository
+	uow          domain.IUnitOfWork
+}
+
+// ReservationOption configures optional collaborators of a
+// ReservationService.
+type ReservationOption func(*ReservationService)
+
+// WithReservationUnitOfWork makes Reserve check for overlaps and save the
+// reservation in one unit of work, so concurrent overlapping reservations
+// cannot both be saved.
+func WithReservationUnitOfWork(uow domain.IUnitOfWork) ReservationOption {
+	return func(s *ReservationService) {
+		s.uow = uow
+	}
+}
+
+func NewReservationService(reservations domain.IReservationRepository, devices domain.IDeviceRepository, opts ...ReservationOption) *ReservationService {
+	s := &ReservationService{reservations: reservations, devices: devices}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *ReservationService) Reserve(ctx context.Context, deviceID, reservedBy string, startsAt, endsAt time.Time) (*domain.Reservation, error) {
+	var reservation *domain.Reservation
+	err := s.inTransaction(ctx, func(tx *ReservationService) error {
+		if err := tx.reservations.LockDevice(ctx, deviceID); err != nil {
+			return err
+		}
+		device, err := tx.devices.FindByID(ctx, deviceID)
+		if err != nil {
+			return err
+		}
+		if device.State == domain.DeviceStateInactive {
+			return domain.ErrDeviceInactive
+		}
+
+		reservation, err = domain.NewReservation(uuid.NewString(), deviceID, reservedBy, startsAt, endsAt)
+		if err != nil {
+			return err
+		}
+
+		existing, err := tx.reservations.FindByDevice(ctx, deviceID, startsAt)
+		if err != nil {
+			return err
+		}
+		for _, other := range existing {
+			if other.Overlaps(startsAt, endsAt) {
+				return domain.ErrReservationConflict
+			}
+		}
+		return tx.reservations.Save(ctx, reservation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// inTransaction runs fn with a copy of the service whose repositories take
+// part in the unit of work, or with the service itself when it has none.
+func (s *ReservationService) inTransaction(ctx context.Context, fn func(tx *ReservationService) error) error {
+	if s.uow == nil {
+		return fn(s)
+	}
+	return s.uow.Do(ctx, func(repos domain.Repositories) error {
+		return fn(&ReservationService{reservations: repos.Reservations, devices: repos.Devices})
+	})
+}
+
+// ListReservations returns the current and upcoming reservations of a device,
+// or every reservation when includePast is set.
+func (s *ReservationService) ListReservations(ctx context.Context, deviceID string, includePast bool) ([]*domain.Reservation, error) {
+	if _, err := s.devices.FindByID(ctx, deviceID); err != nil {
+		return nil, err
+	}
+
+	endingAfter := time.Now()
+	if includePast {
+		endingAfter = time.Time{}
+	}
+	return s.reservations.FindByDevice(ctx, deviceID, endingAfter)
+}
+
+func (s *ReservationService) GetReservation(ctx context.Context, deviceID, id string) (*domain.Reservation, error) {
+	reservation, err := s.reservations.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if reservation.DeviceID != deviceID {
+		return nil, domain.ErrReservationNotFound
+	}
+	return reservation, nil
+}
+
+func (s *ReservationService) CancelReservation(ctx context.Context, deviceID, id string) error {
+	if _, err := s.GetReservation(ctx, deviceID, id); err != nil {
+		return err
+	}
+	return s.reservations.Delete(ctx, id)
+}
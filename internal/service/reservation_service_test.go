@@ -0,0 +1,167 @@
+package service_test
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"device-api/internal/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockReservationRepository is a mock implementation of domain.IReservationRepository
+type MockReservationRepository struct {
+	mock.Mock
+}
+
+func (m *MockReservationRepository) Save(ctx context.Context, reservation *domain.Reservation) error {
+	args := m.Called(reservation)
+	return args.Error(0)
+}
+
+func (m *MockReservationRepository) FindByID(ctx context.Context, id string) (*domain.Reservation, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Reservation), args.Error(1)
+}
+
+func (m *MockReservationRepository) FindByDevice(ctx context.Context, deviceID string, endingAfter time.Time) ([]*domain.Reservation, error) {
+	args := m.Called(deviceID, endingAfter)
+	return args.Get(0).([]*domain.Reservation), args.Error(1)
+}
+
+func (m *MockReservationRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockReservationRepository) LockDevice(ctx context.Context, deviceID string) error {
+	args := m.Called(deviceID)
+	return args.Error(0)
+}
+
+func TestReserve(t *testing.T) {
+	start := time.Now().Add(time.Hour)
+	end := start.Add(2 * time.Hour)
+
+	t.Run("success", func(t *testing.T) {
+		devices := new(MockRepository)
+		reservations := new(MockReservationRepository)
+		svc := service.NewReservationService(reservations, devices)
+		reservations.On("LockDevice", "123").Return(nil)
+		devices.On("FindByID", "123").Return(domain.NewDevice("123", "Pixel", "Google"), nil)
+		reservations.On("FindByDevice", "123", start).Return([]*domain.Reservation{
+			{ID: "r0", DeviceID: "123", ReservedBy: "bob", StartsAt: end, EndsAt: end.Add(time.Hour)},
+		}, nil)
+		reservations.On("Save", mock.AnythingOfType("*domain.Reservation")).Return(nil)
+
+		reservation, err := svc.Reserve(ctx, "123", "alice", start, end)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, reservation.ID)
+		assert.Equal(t, "alice", reservation.ReservedBy)
+		reservations.AssertExpectations(t)
+	})
+
+	t.Run("fail_overlap", func(t *testing.T) {
+		devices := new(MockRepository)
+		reservations := new(MockReservationRepository)
+		svc := service.NewReservationService(reservations, devices)
+		reservations.On("LockDevice", "123").Return(nil)
+		devices.On("FindByID", "123").Return(domain.NewDevice("123", "Pixel", "Google"), nil)
+		reservations.On("FindByDevice", "123", start).Return([]*domain.Reservation{
+			{ID: "r0", DeviceID: "123", ReservedBy: "bob", StartsAt: start.Add(time.Hour), EndsAt: end.Add(time.Hour)},
+		}, nil)
+
+		_, err := svc.Reserve(ctx, "123", "alice", start, end)
+		assert.ErrorIs(t, err, domain.ErrReservationConflict)
+		reservations.AssertNotCalled(t, "Save", mock.Anything)
+	})
+
+	t.Run("fail_invalid_window", func(t *testing.T) {
+		devices := new(MockRepository)
+		reservations := new(MockReservationRepository)
+		svc := service.NewReservationService(reservations, devices)
+		reservations.On("LockDevice", "123").Return(nil)
+		devices.On("FindByID", "123").Return(domain.NewDevice("123", "Pixel", "Google"), nil)
+
+		_, err := svc.Reserve(ctx, "123", "alice", end, start)
+		assert.ErrorIs(t, err, domain.ErrInvalidReservationWindow)
+	})
+}
+
+func TestCheckoutHonoursReservations(t *testing.T) {
+	active := &domain.Reservation{
+		ID:         "r1",
+		DeviceID:   "123",
+		ReservedBy: "alice",
+		StartsAt:   time.Now().Add(-time.Hour),
+		EndsAt:     time.Now().Add(time.Hour),
+	}
+
+	t.Run("fail_reserved_by_other", func(t *testing.T) {
+		devices := new(MockRepository)
+		reservations := new(MockReservationRepository)
+		svc := service.NewDeviceService(devices, service.WithReservations(reservations))
+		devices.On("FindByID", "123").Return(domain.NewDevice("123", "Pixel", "Google"), nil)
+		reservations.On("FindByDevice", "123", mock.AnythingOfType("time.Time")).Return([]*domain.Reservation{active}, nil)
+
+		_, err := svc.CheckoutDevice(ctx, "123", "bob", nil)
+		assert.ErrorIs(t, err, domain.ErrDeviceReserved)
+		devices.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("success_reserving_user", func(t *testing.T) {
+		devices := new(MockRepository)
+		reservations := new(MockReservationRepository)
+		svc := service.NewDeviceService(devices, service.WithReservations(reservations))
+		devices.On("FindByID", "123").Return(domain.NewDevice("123", "Pixel", "Google"), nil)
+		devices.On("Update", mock.AnythingOfType("*domain.Device")).Return(nil)
+		reservations.On("FindByDevice", "123", mock.AnythingOfType("time.Time")).Return([]*domain.Reservation{active}, nil)
+
+		device, err := svc.CheckoutDevice(ctx, "123", "alice", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "alice", device.Assignee)
+	})
+
+	t.Run("fail_return_after_upcoming_reservation", func(t *testing.T) {
+		devices := new(MockRepository)
+		reservations := new(MockReservationRepository)
+		svc := service.NewDeviceService(devices, service.WithReservations(reservations))
+		upcoming := &domain.Reservation{
+			ID:         "r2",
+			DeviceID:   "123",
+			ReservedBy: "alice",
+			StartsAt:   time.Now().Add(2 * time.Hour),
+			EndsAt:     time.Now().Add(3 * time.Hour),
+		}
+		devices.On("FindByID", "123").Return(domain.NewDevice("123", "Pixel", "Google"), nil)
+		reservations.On("FindByDevice", "123", mock.AnythingOfType("time.Time")).Return([]*domain.Reservation{upcoming}, nil)
+
+		returnAt := time.Now().Add(4 * time.Hour)
+		_, err := svc.CheckoutDevice(ctx, "123", "bob", &returnAt)
+		assert.ErrorIs(t, err, domain.ErrDeviceReserved)
+	})
+
+	t.Run("fail_open_ended_before_upcoming_reservation", func(t *testing.T) {
+		devices := new(MockRepository)
+		reservations := new(MockReservationRepository)
+		svc := service.NewDeviceService(devices, service.WithReservations(reservations))
+		upcoming := &domain.Reservation{
+			ID:         "r3",
+			DeviceID:   "123",
+			ReservedBy: "alice",
+			StartsAt:   time.Now().Add(24 * time.Hour),
+			EndsAt:     time.Now().Add(25 * time.Hour),
+		}
+		devices.On("FindByID", "123").Return(domain.NewDevice("123", "Pixel", "Google"), nil)
+		reservations.On("FindByDevice", "123", mock.AnythingOfType("time.Time")).Return([]*domain.Reservation{upcoming}, nil)
+
+		_, err := svc.CheckoutDevice(ctx, "123", "bob", nil)
+		assert.ErrorIs(t, err, domain.ErrDeviceReserved)
+		devices.AssertNotCalled(t, "Update", mock.Anything)
+	})
+}
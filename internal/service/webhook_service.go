@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"device-api/internal/domain"
+	"device-api/internal/webhook"
+	"encoding/hex"
+
+	"github.com/google/uuid"
+)
+
+// MaxDeadLetters caps the number of deliveries DeadLetters returns.
+const MaxDeadLetters = 500
+
+type WebhookService struct {
+	webhooks   domain.IWebhookRepository
+	deliveries domain.IWebhookDeliveryRepository
+	targets    webhook.TargetPolicy
+}
+
+// WebhookOption configures optional settings of a WebhookService.
+type WebhookOption func(*WebhookService)
+
+// WithWebhookTargetPolicy sets the addresses webhook URLs may point at. By
+// default loopback, private and link-local addresses are refused.
+func WithWebhookTargetPolicy(policy webhook.TargetPolicy) WebhookOption {
+	return func(s *WebhookService) {
+		s.targets = policy
+	}
+}
+
+func NewWebhookService(webhooks domain.IWebhookRepository, deliveries domain.IWebhookDeliveryRepository, opts ...WebhookOption) *WebhookService {
+	s := &WebhookService{webhooks: webhooks, deliveries: deliveries}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CreateWebhook registers a webhook. Payloads are signed with secret, or
+// with a random one when it is empty; the caller should hand it to the
+// receiver, since it is not shown again.
+func (s *WebhookService) CreateWebhook(ctx context.Context, sub domain.WebhookSubscription, secret string) (*domain.Webhook, error) {
+	if secret == "" {
+		var err error
+		if secret, err = newSecret(); err != nil {
+			return nil, err
+		}
+	}
+	hook, err := domain.NewWebhook(uuid.NewString(), secret, sub)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.targets.CheckURL(ctx, hook.URL); err != nil {
+		return nil, err
+	}
+	if err := s.webhooks.Save(ctx, hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+func newSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func (s *WebhookService) GetWebhook(ctx context.Context, id string) (*domain.Webhook, error) {
+	return s.webhooks.FindByID(ctx, id)
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context) ([]*domain.Webhook, error) {
+	return s.webhooks.FindAll(ctx)
+}
+
+// UpdateWebhook replaces the settings of a webhook. Its secret is kept.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id string, sub domain.WebhookSubscription) (*domain.Webhook, error) {
+	hook, err := s.webhooks.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := hook.Apply(sub); err != nil {
+		return nil, err
+	}
+	if err := s.targets.CheckURL(ctx, hook.URL); err != nil {
+		return nil, err
+	}
+	if err := s.webhooks.Update(ctx, hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// DeleteWebhook removes a webhook and its delivery log. Deliveries that are
+// still pending are dropped.
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id string) error {
+	return s.webhooks.Delete(ctx, id)
+}
+
+// Deliveries returns one page of the delivery log of a webhook, newest
+// first, and the total number of deliveries. An empty status lists all.
+func (s *WebhookService) Deliveries(ctx context.Context, webhookID string, status domain.DeliveryStatus, limit, offset int) ([]*domain.WebhookDelivery, int64, error) {
+	if _, err := s.webhooks.FindByID(ctx, webhookID); err != nil {
+		return nil, 0, err
+	}
+	return s.deliveries.FindByWebhook(ctx, webhookID, status, limit, offset)
+}
+
+// DeadLetters returns the oldest deliveries, across all webhooks, that ran
+// out of attempts.
+func (s *WebhookService) DeadLetters(ctx context.Context, limit int) ([]*domain.WebhookDelivery, error) {
+	if limit <= 0 || limit > MaxDeadLetters {
+		limit = MaxDeadLetters
+	}
+	return s.deliveries.FindByStatus(ctx, domain.DeliveryStatusDead, limit)
+}
+
+// Redeliver queues a delivery of the webhook to be sent again right away,
+// with a fresh set of attempts.
+func (s *WebhookService) Redeliver(ctx context.Context, webhookID, deliveryID string) (*domain.WebhookDelivery, error) {
+	delivery, err := s.deliveries.FindByID(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.WebhookID != webhookID {
+		return nil, domain.ErrDeliveryNotFound
+	}
+	delivery.Redeliver()
+	if err := s.deliveries.Update(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
@@ -0,0 +1,282 @@
+// Package webhook delivers device events to subscribed HTTP endpoints.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"device-api/internal/domain"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Headers sent with every delivery. The signature is the hex encoded
+// HMAC-SHA256, keyed with the webhook secret, of the timestamp header, a dot
+// and the body; receivers should recompute it and reject stale timestamps.
+const (
+	HeaderWebhookID = "X-Webhook-ID"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	DefaultMaxAttempts = 8
+	DefaultBackoff     = 30 * time.Second
+	DefaultMaxBackoff  = time.Hour
+
+	pollInterval    = time.Second
+	deliveryTimeout = 10 * time.Second
+	// claimLease is how long claimed deliveries are kept from other
+	// dispatchers. Deliveries not attempted before it ends are left for the
+	// next claim, as is one whose dispatcher stops before recording it.
+	claimLease   = 5 * time.Minute
+	dueBatchSize = 100
+)
+
+// Sign returns the value of HeaderSignature for payload sent at timestamp.
+func Sign(secret string, timestamp time.Time, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type Option func(*Dispatcher)
+
+// WithMaxAttempts sets how often a delivery is tried before it is moved to
+// the dead-letter list.
+func WithMaxAttempts(n int) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = n
+	}
+}
+
+// WithBackoff sets the wait after the first failed attempt, which doubles
+// with every further failure up to max.
+func WithBackoff(base, max time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.backoff = base
+		d.maxBackoff = max
+	}
+}
+
+// WithTargetPolicy sets the addresses deliveries may be sent to. It has no
+// effect on a client set with WithHTTPClient.
+func WithTargetPolicy(policy TargetPolicy) Option {
+	return func(d *Dispatcher) {
+		d.policy = policy
+	}
+}
+
+// WithHTTPClient sends deliveries with client instead of one that enforces
+// the target policy.
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// Dispatcher is a domain.IEventPublisher that queues a delivery for every
+// webhook an event matches, and sends queued deliveries while it runs.
+// Deliveries are stored, so those still pending when the process stops are
+// sent after it restarts.
+type Dispatcher struct {
+	webhooks    domain.IWebhookRepository
+	deliveries  domain.IWebhookDeliveryRepository
+	client      *http.Client
+	policy      TargetPolicy
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	wake        chan struct{}
+}
+
+func NewDispatcher(webhooks domain.IWebhookRepository, deliveries domain.IWebhookDeliveryRepository, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		webhooks:    webhooks,
+		deliveries:  deliveries,
+		maxAttempts: DefaultMaxAttempts,
+		backoff:     DefaultBackoff,
+		maxBackoff:  DefaultMaxBackoff,
+		wake:        make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.client == nil {
+		d.client = d.policy.Client(deliveryTimeout)
+	}
+	return d
+}
+
+// Publish queues a delivery of event to each matching webhook. Failures are
+// logged rather than returned: the change the event announces has already
+// been committed.
+func (d *Dispatcher) Publish(event domain.DeviceEvent) {
+	if err := d.Enqueue(context.Background(), event); err != nil {
+		log.Printf("webhook: queueing %s event of device %s: %v", event.Action, event.DeviceID, err)
+	}
+}
+
+// Enqueue queues a delivery of event to each matching webhook.
+func (d *Dispatcher) Enqueue(ctx context.Context, event domain.DeviceEvent) error {
+	webhooks, err := d.webhooks.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	queued := false
+	for _, webhook := range webhooks {
+		if !webhook.Matches(event) {
+			continue
+		}
+		delivery := domain.NewWebhookDelivery(uuid.NewString(), webhook, event, payload)
+		if err := d.deliveries.Save(ctx, delivery); err != nil {
+			return err
+		}
+		queued = true
+	}
+	if queued {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Run sends due deliveries until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.DeliverDue(ctx); err != nil {
+			log.Printf("webhook: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue claims the deliveries that are due, makes one attempt at each
+// and reports how many of them succeeded. Claims keep dispatchers sharing a
+// database from sending the same delivery.
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	until := now.Add(claimLease)
+	due, err := d.deliveries.ClaimDue(ctx, now, until, dueBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	succeeded := 0
+	for _, delivery := range due {
+		// An attempt must end before the claim does.
+		if ctx.Err() != nil || time.Now().Add(deliveryTimeout).After(until) {
+			break
+		}
+		if err := d.attempt(ctx, delivery); err != nil {
+			return succeeded, err
+		}
+		if delivery.Status == domain.DeliveryStatusSucceeded {
+			succeeded++
+		}
+	}
+	return succeeded, nil
+}
+
+// attempt sends delivery once and records the outcome. Only a failure to
+// record it is returned.
+func (d *Dispatcher) attempt(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	webhook, err := d.webhooks.FindByID(ctx, delivery.WebhookID)
+	if errors.Is(err, domain.ErrWebhookNotFound) {
+		// Deleted since the delivery was loaded, together with its log.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// The outcome is recorded even when ctx ends during the request, so
+	// that the attempt is not lost on shutdown.
+	record := context.WithoutCancel(ctx)
+	delivery.Attempts++
+	if !webhook.Active {
+		delivery.Status = domain.DeliveryStatusDead
+		delivery.LastError = "webhook is inactive"
+		return d.deliveries.Update(record, delivery)
+	}
+
+	status, sendErr := d.send(ctx, webhook, delivery)
+	delivery.ResponseStatus = status
+	switch {
+	case sendErr == nil:
+		now := time.Now()
+		delivery.Status = domain.DeliveryStatusSucceeded
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= d.maxAttempts:
+		delivery.Status = domain.DeliveryStatusDead
+		delivery.LastError = sendErr.Error()
+	default:
+		delivery.NextAttemptAt = time.Now().Add(d.delay(delivery.Attempts))
+		delivery.LastError = sendErr.Error()
+	}
+	return d.deliveries.Update(record, delivery)
+}
+
+// delay returns how long to wait after the given number of failed attempts.
+func (d *Dispatcher) delay(attempts int) time.Duration {
+	delay := d.backoff
+	for i := 1; i < attempts && delay < d.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.maxBackoff)
+}
+
+func (d *Dispatcher) send(ctx context.Context, webhook *domain.Webhook, delivery *domain.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "device-api-webhooks")
+	req.Header.Set(HeaderWebhookID, webhook.ID)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderEvent, string(delivery.EventType))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, now, payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
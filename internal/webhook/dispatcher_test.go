@@ -0,0 +1,33 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	payload := []byte(`{"type":"deleted"}`)
+	at := time.Unix(1700000000, 0)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(`1700000000.{"type":"deleted"}`))
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), Sign("secret", at, payload))
+
+	assert.NotEqual(t, Sign("secret", at, payload), Sign("other", at, payload))
+	assert.NotEqual(t, Sign("secret", at, payload), Sign("secret", at.Add(time.Second), payload))
+}
+
+func TestBackoffDoublesUpToMax(t *testing.T) {
+	d := NewDispatcher(nil, nil, WithBackoff(time.Second, 5*time.Second))
+
+	var delays []time.Duration
+	for attempts := 1; attempts <= 5; attempts++ {
+		delays = append(delays, d.delay(attempts))
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, delays)
+}
@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which some
+// clouds use for metadata services.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// TargetPolicy decides which addresses webhooks may be sent to. Loopback,
+// private, link-local (which holds cloud metadata services), shared,
+// multicast and unspecified addresses are refused unless they fall in one
+// of the Allowed networks; every other address is allowed.
+type TargetPolicy struct {
+	Allowed []netip.Prefix
+}
+
+// ParseAllowedNetworks reads a comma separated list of networks in CIDR
+// notation, such as "10.0.0.0/8,127.0.0.1/32". A bare address stands for
+// itself.
+func ParseAllowedNetworks(raw string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// CheckAddr returns domain.ErrWebhookTargetNotAllowed when addr is refused.
+func (p TargetPolicy) CheckAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	for _, prefix := range p.Allowed {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() || addr.IsUnspecified() || sharedAddressSpace.Contains(addr) {
+		return fmt.Errorf("%w: %s", domain.ErrWebhookTargetNotAllowed, addr)
+	}
+	return nil
+}
+
+// CheckURL checks every address the host of rawURL resolves to. A host that
+// does not resolve makes the URL invalid.
+func (p TargetPolicy) CheckURL(ctx context.Context, rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return domain.ErrInvalidWebhookURL
+	}
+	host := target.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return p.CheckAddr(addr)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidWebhookURL, err)
+	}
+	for _, addr := range addrs {
+		if err := p.CheckAddr(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Client returns an HTTP client that refuses to connect to addresses the
+// policy refuses. The check runs on every connection, so hosts that resolve
+// differently after the webhook was registered, and redirects, are covered.
+// Proxies are not used, as they would make the connections instead.
+func (p TargetPolicy) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			return p.CheckAddr(addrPort.Addr())
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
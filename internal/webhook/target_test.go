@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"context"
+	"device-api/internal/domain"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTargetPolicyRefusesInternalAddresses(t *testing.T) {
+	var policy TargetPolicy
+	for _, addr := range []string{"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.100.100.200", "fd00:ec2::254", "fe80::1", "0.0.0.0", "::ffff:127.0.0.1", "224.0.0.1"} {
+		assert.ErrorIs(t, policy.CheckAddr(netip.MustParseAddr(addr)), domain.ErrWebhookTargetNotAllowed, addr)
+	}
+	for _, addr := range []string{"93.184.216.34", "2606:4700::1111"} {
+		assert.NoError(t, policy.CheckAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestTargetPolicyAllowlist(t *testing.T) {
+	allowed, err := ParseAllowedNetworks("10.0.0.0/8, 127.0.0.1")
+	assert.NoError(t, err)
+	policy := TargetPolicy{Allowed: allowed}
+
+	assert.NoError(t, policy.CheckAddr(netip.MustParseAddr("10.20.30.40")))
+	assert.NoError(t, policy.CheckAddr(netip.MustParseAddr("127.0.0.1")))
+	assert.ErrorIs(t, policy.CheckAddr(netip.MustParseAddr("127.0.0.2")), domain.ErrWebhookTargetNotAllowed)
+
+	_, err = ParseAllowedNetworks("10.0.0.0/33")
+	assert.Error(t, err)
+}
+
+func TestTargetPolicyCheckURL(t *testing.T) {
+	var policy TargetPolicy
+	ctx := context.Background()
+
+	assert.ErrorIs(t, policy.CheckURL(ctx, "http://169.254.169.254/latest/meta-data"), domain.ErrWebhookTargetNotAllowed)
+	assert.ErrorIs(t, policy.CheckURL(ctx, "http://[::1]:8080/hook"), domain.ErrWebhookTargetNotAllowed)
+	assert.ErrorIs(t, policy.CheckURL(ctx, "http://localhost/hook"), domain.ErrWebhookTargetNotAllowed)
+	assert.ErrorIs(t, policy.CheckURL(ctx, "http://host.invalid/hook"), domain.ErrInvalidWebhookURL)
+	assert.NoError(t, policy.CheckURL(ctx, "https://93.184.216.34/hook"))
+}
+
+func TestTargetPolicyClientRefusesConnections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := TargetPolicy{}.Client(time.Second).Get(server.URL)
+	assert.ErrorIs(t, err, domain.ErrWebhookTargetNotAllowed)
+
+	loopback := TargetPolicy{Allowed: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}}
+	resp, err := loopback.Client(time.Second).Get(server.URL)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+	}
+}
@@ -1,37 +1,111 @@
 package integration_test
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"device-api/internal/domain"
+	"device-api/internal/events"
+	"device-api/internal/graphqlapi"
+	"device-api/internal/grpcapi"
+	"device-api/internal/grpcapi/devicev1"
 	"device-api/internal/handler"
+	"device-api/internal/migrations"
+	"device-api/internal/outbox"
 	"device-api/internal/repository"
 	"device-api/internal/service"
+	"device-api/internal/webhook"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-func setupTestRouter() (*gin.Engine, *repository.PostgresRepository) {
+func openTestDB() *gorm.DB {
 	// Use in-memory SQLite for integration testing
 	db, _ := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{
         Logger: logger.Default.LogMode(logger.Silent),
     })
-	db.AutoMigrate(&domain.Device{})
+	// Shared-cache SQLite reports "table is locked" instead of waiting when
+	// several connections write at once, so funnel everything through one.
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	migrator, err := migrations.New(db, migrations.SQLite())
+	if err != nil {
+		panic(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		panic(err)
+	}
+	return db
+}
+
+func setupTestRouter() (*gin.Engine, *repository.PostgresRepository) {
+	r, repo, _ := setupTestRouterWithWebhooks()
+	return r, repo
+}
+
+// setupTestRouterWithWebhooks also returns the webhook dispatcher, which is
+// not running: tests drive deliveries with DeliverDue. Failed deliveries are
+// retried right away and dead-lettered after the second attempt. Webhooks
+// may target loopback, where the test receivers listen.
+func setupTestRouterWithWebhooks() (*gin.Engine, *repository.PostgresRepository, *webhook.Dispatcher) {
+	db := openTestDB()
+	targets := webhook.TargetPolicy{Allowed: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}}
 
 	repo := repository.NewPostgresRepository(db)
-	svc := service.NewDeviceService(repo)
+	reservationRepo := repository.NewPostgresReservationRepository(db)
+	broker := events.NewBroker(events.DefaultBacklog)
+	webhookRepo := repository.NewPostgresWebhookRepository(db)
+	deliveryRepo := repository.NewPostgresWebhookDeliveryRepository(db)
+	dispatcher := webhook.NewDispatcher(webhookRepo, deliveryRepo, webhook.WithMaxAttempts(2), webhook.WithBackoff(0, 0), webhook.WithTargetPolicy(targets))
+	svc := service.NewDeviceService(
+		repo,
+		service.WithReservations(reservationRepo),
+		service.WithHistory(repository.NewPostgresDeviceHistoryRepository(db)),
+		service.WithIDGenerator(repository.NewSequenceGenerator(db, "devices", "DEV-", 6)),
+		service.WithUnitOfWork(repository.NewUnitOfWork(db)),
+		service.WithEvents(events.Fanout{broker, dispatcher}),
+	)
+	reservationSvc := service.NewReservationService(reservationRepo, repo, service.WithReservationUnitOfWork(repository.NewUnitOfWork(db)))
 	h := handler.NewDeviceHandler(svc)
+	rh := handler.NewReservationHandler(reservationSvc)
+	schema, err := graphqlapi.NewSchema(svc, reservationSvc, broker)
+	if err != nil {
+		panic(err)
+	}
 
 	r := gin.Default()
+	r.Use(handler.Deadlines(handler.DefaultTimeouts()))
 	handler.RegisterRoutes(r, h)
-	return r, repo
+	handler.RegisterReservationRoutes(r, rh)
+	handler.RegisterEventRoutes(r, handler.NewEventHandler(broker))
+	handler.RegisterWebhookRoutes(r, handler.NewWebhookHandler(service.NewWebhookService(webhookRepo, deliveryRepo, service.WithWebhookTargetPolicy(targets))))
+	graphqlapi.RegisterRoutes(r, graphqlapi.NewHandler(schema))
+	return r, repo, dispatcher
 }
 
 func TestCreateAndGetDevice(t *testing.T) {
@@ -69,3 +143,1345 @@ func TestDeviceLifecycle(t *testing.T) {
     r.ServeHTTP(w, req)
     assert.Equal(t, http.StatusCreated, w.Code)
 }
+
+func TestInvalidStateTransition(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	id := "transition-1"
+	reqBody, _ := json.Marshal(handler.CreateDeviceRequest{ID: id, Name: "Phone", Brand: "BrandA"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/devices", bytes.NewBuffer(reqBody))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", "/api/v1/devices/"+id, bytes.NewBufferString(`{"state":"banana"}`))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	assert.Equal(t, handler.ProblemContentType, w.Header().Get("Content-Type"))
+
+	var problem handler.Problem
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	assert.Equal(t, "invalid_device_state", problem.Code)
+	assert.ElementsMatch(t, []domain.DeviceState{domain.DeviceStateInUse, domain.DeviceStateInactive}, problem.AllowedStates)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/devices/"+id, nil)
+	r.ServeHTTP(w, req)
+	var device domain.Device
+	json.Unmarshal(w.Body.Bytes(), &device)
+	assert.Equal(t, domain.DeviceStateAvailable, device.State)
+}
+
+func TestCheckoutAndCheckin(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	id := "checkout-1"
+	reqBody, _ := json.Marshal(handler.CreateDeviceRequest{ID: id, Name: "Phone", Brand: "BrandA"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/devices", bytes.NewBuffer(reqBody))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/devices/"+id+"/checkout", bytes.NewBufferString(`{"assignee":"alice"}`))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var device domain.Device
+	json.Unmarshal(w.Body.Bytes(), &device)
+	assert.Equal(t, domain.DeviceStateInUse, device.State)
+	assert.Equal(t, "alice", device.Assignee)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/devices/"+id+"/checkout", bytes.NewBufferString(`{"assignee":"bob"}`))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/devices/"+id+"/checkin", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	device = domain.Device{}
+	json.Unmarshal(w.Body.Bytes(), &device)
+	assert.Equal(t, domain.DeviceStateAvailable, device.State)
+	assert.Empty(t, device.Assignee)
+}
+
+func TestReservations(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	id := "reservation-1"
+	reqBody, _ := json.Marshal(handler.CreateDeviceRequest{ID: id, Name: "Phone", Brand: "BrandA"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/devices", bytes.NewBuffer(reqBody))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	now := time.Now()
+	reserve := func(by string, start, end time.Time) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(handler.CreateReservationRequest{ReservedBy: by, StartsAt: start, EndsAt: end})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/devices/"+id+"/reservations", bytes.NewBuffer(body))
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w = reserve("alice", now.Add(-time.Minute), now.Add(time.Hour))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var reservation domain.Reservation
+	json.Unmarshal(w.Body.Bytes(), &reservation)
+
+	w = reserve("bob", now.Add(30*time.Minute), now.Add(2*time.Hour))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = reserve("bob", now.Add(time.Hour), now.Add(2*time.Hour))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/devices/"+id+"/reservations", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var reservations []domain.Reservation
+	json.Unmarshal(w.Body.Bytes(), &reservations)
+	assert.Len(t, reservations, 2)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/devices/"+id+"/checkout", bytes.NewBufferString(`{"assignee":"bob"}`))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/devices/"+id+"/reservations/"+reservation.ID, nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/devices/"+id+"/checkout", bytes.NewBufferString(`{"assignee":"bob"}`))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestConcurrentReservations(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	id := "reservation-2"
+	reqBody, _ := json.Marshal(handler.CreateDeviceRequest{ID: id, Name: "Phone", Brand: "BrandA"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/devices", bytes.NewBuffer(reqBody))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	start := time.Now().Add(time.Hour)
+	codes := make([]int, 8)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			offset := time.Duration(i) * time.Minute
+			body, _ := json.Marshal(handler.CreateReservationRequest{
+				ReservedBy: "user-" + strconv.Itoa(i),
+				StartsAt:   start.Add(offset),
+				EndsAt:     start.Add(offset + time.Hour),
+			})
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/devices/"+id+"/reservations", bytes.NewBuffer(body))
+			r.ServeHTTP(w, req)
+			codes[i] = w.Code
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, code := range codes {
+		if code == http.StatusCreated {
+			created++
+		} else {
+			assert.Equal(t, http.StatusConflict, code)
+		}
+	}
+	assert.Equal(t, 1, created, "overlapping reservations exclude each other")
+}
+
+func TestDeviceHistory(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	id := "history-1"
+	reqBody, _ := json.Marshal(handler.CreateDeviceRequest{ID: id, Name: "Phone", Brand: "BrandA"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/devices", bytes.NewBuffer(reqBody))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", "/api/v1/devices/"+id, bytes.NewBufferString(`{"state":"in-use"}`))
+	req.Header.Set(handler.ActorHeader, "alice")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", "/api/v1/devices/"+id, bytes.NewBufferString(`{"state":"available"}`))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/devices/"+id, nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/devices/"+id+"/history?limit=2&offset=1", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var page handler.HistoryPage
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Equal(t, int64(4), page.Total)
+	if assert.Len(t, page.Items, 2) {
+		assert.Equal(t, domain.HistoryActionStateChanged, page.Items[0].Action)
+		assert.Equal(t, domain.DeviceStateInUse, page.Items[0].OldValue.State)
+		assert.Equal(t, domain.HistoryActionStateChanged, page.Items[1].Action)
+		assert.Equal(t, "alice", page.Items[1].Actor)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/devices/unknown-device/history", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	r, repo := setupTestRouter()
+
+	id := "soft-delete-1"
+	reqBody, _ := json.Marshal(handler.CreateDeviceRequest{ID: id, Name: "Phone", Brand: "SoftDeleteBrand"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/devices", bytes.NewBuffer(reqBody))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/devices/"+id, nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/devices/"+id, nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	listBrand := func(query string) []*domain.Device {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/devices?brand=SoftDeleteBrand"+query, nil)
+		r.ServeHTTP(w, req)
+		var page handler.DeviceListResponse
+		json.Unmarshal(w.Body.Bytes(), &page)
+		return page.Items
+	}
+	assert.Empty(t, listBrand(""))
+	if deleted := listBrand("&include_deleted=true"); assert.Len(t, deleted, 1) {
+		assert.True(t, deleted[0].IsDeleted())
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/devices", bytes.NewBuffer(reqBody))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/devices/"+id+"/restore", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/devices/"+id+"/restore", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/devices/"+id, nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	purged, err := repo.PurgeDeletedBefore(context.Background(), time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	assert.Contains(t, purged, id)
+	assert.Empty(t, listBrand("&include_deleted=true"))
+}
+
+func TestOptimisticConcurrency(t *testing.T) {
+	r, repo := setupTestRouter()
+
+	id := "concurrency-1"
+	reqBody, _ := json.Marshal(handler.CreateDeviceRequest{ID: id, Name: "Phone", Brand: "BrandA"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/devices", bytes.NewBuffer(reqBody))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/devices/"+id, nil)
+	r.ServeHTTP(w, req)
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, `"1"`, etag)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", "/api/v1/devices/"+id, bytes.NewBufferString(`{"name":"Renamed"}`))
+	req.Header.Set("If-Match", etag)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PATCH", "/api/v1/devices/"+id, bytes.NewBufferString(`{"name":"Stale"}`))
+	req.Header.Set("If-Match", etag)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/devices/"+id, nil)
+	req.Header.Set("If-Match", etag)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	first, _ := repo.FindByID(context.Background(), id)
+	second, _ := repo.FindByID(context.Background(), id)
+	first.Name = "First"
+	second.Name = "Second"
+	assert.NoError(t, repo.Update(context.Background(), first))
+	assert.ErrorIs(t, repo.Update(context.Background(), second), domain.ErrVersionConflict)
+
+	stored, _ := repo.FindByID(context.Background(), id)
+	assert.Equal(t, "First", stored.Name)
+	assert.Equal(t, int64(3), stored.Version)
+}
+
+func TestConcurrentCreateSameID(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	const attempts = 50
+	body, _ := json.Marshal(handler.CreateDeviceRequest{ID: "concurrent-create-1", Name: "Phone", Brand: "BrandA"})
+
+	codes := make(chan int, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/devices", bytes.NewBuffer(body))
+			r.ServeHTTP(w, req)
+			codes <- w.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+	assert.Equal(t, map[int]int{http.StatusCreated: 1, http.StatusConflict: attempts - 1}, counts)
+}
+
+func TestCreateDeviceWithGeneratedID(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	create := func() domain.Device {
+		body, _ := json.Marshal(handler.CreateDeviceRequest{Name: "Phone", Brand: "BrandA"})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/devices", bytes.NewBuffer(body))
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var device domain.Device
+		json.Unmarshal(w.Body.Bytes(), &device)
+		return device
+	}
+
+	first, second := create(), create()
+	assert.Regexp(t, `^DEV-\d{6}$`, first.ID)
+	assert.Regexp(t, `^DEV-\d{6}$`, second.ID)
+	assert.Less(t, first.ID, second.ID)
+}
+
+func TestCreateDeviceWithReservedID(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	create := func(id string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(handler.CreateDeviceRequest{ID: id, Name: "Phone", Brand: "BrandA"})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/devices", bytes.NewBuffer(body))
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// The sequence would hand this ID out later.
+	w := create("DEV-999999")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var problem handler.Problem
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	assert.Equal(t, "device_id_reserved", problem.Code)
+
+	assert.Equal(t, http.StatusCreated, create("DEV-reserved-test").Code)
+}
+
+func TestListDevicesCombinedFilters(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	for _, d := range []handler.CreateDeviceRequest{
+		{ID: "filter-1", Name: "iPhone 15", Brand: "FilterApple"},
+		{ID: "filter-2", Name: "iPad Pro", Brand: "FilterApple"},
+		{ID: "filter-3", Name: "Pixel 8", Brand: "FilterGoogle"},
+		{ID: "filter-4", Name: "Galaxy", Brand: "FilterSamsung"},
+	} {
+		body, _ := json.Marshal(d)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/devices", bytes.NewBuffer(body))
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", "/api/v1/devices/filter-1", bytes.NewBufferString(`{"state":"in-use"}`))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	list := func(query string) []string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/devices?"+query, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var page handler.DeviceListResponse
+		json.Unmarshal(w.Body.Bytes(), &page)
+		var ids []string
+		for _, d := range page.Items {
+			ids = append(ids, d.ID)
+		}
+		return ids
+	}
+
+	assert.ElementsMatch(t, []string{"filter-2"}, list("brand=FilterApple&state=available"))
+	assert.ElementsMatch(t, []string{"filter-1", "filter-2", "filter-3"}, list("brand=FilterApple,FilterGoogle&name=i&state=available,in-use"))
+	assert.ElementsMatch(t, []string{"filter-1"}, list("brand=FilterApple&name=PHONE"))
+	assert.ElementsMatch(t, []string{"filter-1", "filter-2", "filter-3"}, list("brand=FilterApple&brand=FilterGoogle"))
+	assert.ElementsMatch(t, []string{"filter-3"}, list("brand=FilterApple,FilterGoogle,FilterSamsung&name=PIX"))
+	assert.Empty(t, list("brand=FilterApple&created_to="+time.Now().Add(-time.Hour).Format(time.RFC3339)))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/devices?state=banana", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestListDevicesPagination(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	names := []string{"Delta", "Alpha", "Charlie", "Alpha", "Bravo"}
+	for i, name := range names {
+		body, _ := json.Marshal(handler.CreateDeviceRequest{ID: fmt.Sprintf("page-%d", i), Name: name, Brand: "PageBrand"})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/devices", bytes.NewBuffer(body))
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	fetch := func(query string) handler.DeviceListResponse {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/devices?brand=PageBrand&"+query, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var page handler.DeviceListResponse
+		json.Unmarshal(w.Body.Bytes(), &page)
+		return page
+	}
+
+	var ids []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		page := fetch("sort=-name&limit=2&include_total=true&cursor=" + cursor)
+		if assert.NotNil(t, page.Total) {
+			assert.Equal(t, int64(len(names)), *page.Total)
+		}
+		for _, d := range page.Items {
+			ids = append(ids, d.ID)
+		}
+		if page.NextCursor == "" {
+			assert.Equal(t, 2, pages)
+			break
+		}
+		cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"page-0", "page-2", "page-4", "page-1", "page-3"}, ids)
+
+	page := fetch("sort=name&limit=2")
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/devices?brand=PageBrand&sort=-name&cursor="+page.NextCursor, nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/devices?sort=color", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestProblemDetails(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/devices/problem-missing", nil)
+	req.Header.Set(handler.RequestIDHeader, "req-123")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, handler.ProblemContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "req-123", w.Header().Get(handler.RequestIDHeader))
+
+	var problem handler.Problem
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	assert.Equal(t, "device_not_found", problem.Code)
+	assert.Equal(t, http.StatusNotFound, problem.Status)
+	assert.Equal(t, "/api/v1/devices/problem-missing", problem.Instance)
+	assert.Equal(t, "req-123", problem.RequestID)
+	assert.NotEmpty(t, problem.Type)
+	assert.NotEmpty(t, problem.Title)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/devices", bytes.NewBufferString(`{"name":"Phone"}`))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NotEmpty(t, w.Header().Get(handler.RequestIDHeader))
+
+	problem = handler.Problem{}
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	assert.Equal(t, "invalid_request", problem.Code)
+	assert.Equal(t, w.Header().Get(handler.RequestIDHeader), problem.RequestID)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/devices?state=banana", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	problem = handler.Problem{}
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	assert.Equal(t, "invalid_filter", problem.Code)
+}
+
+func TestCancelledRequests(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/api/v1/devices", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, handler.StatusClientClosedRequest, w.Code)
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequestWithContext(ctx, "GET", "/api/v1/devices/cancelled-1", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+
+	var problem handler.Problem
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	assert.Equal(t, "timeout", problem.Code)
+}
+
+func TestReplaceAndPatchDevice(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	id := "patch-1"
+	reqBody, _ := json.Marshal(handler.CreateDeviceRequest{ID: id, Name: "Phone", Brand: "BrandA"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/devices", bytes.NewBuffer(reqBody))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	send := func(method, contentType, body string) (*httptest.ResponseRecorder, handler.Problem) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/api/v1/devices/"+id, bytes.NewBufferString(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		r.ServeHTTP(w, req)
+		var problem handler.Problem
+		if w.Code >= 400 {
+			json.Unmarshal(w.Body.Bytes(), &problem)
+		}
+		return w, problem
+	}
+
+	// PUT replaces the whole device, so every field is required.
+	w, problem := send("PUT", "application/json", `{"name":"Phone 2"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_request", problem.Code)
+
+	w, _ = send("PUT", "application/json", `{"name":"Phone 2","brand":"BrandB","state":"in-use"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var device domain.Device
+	json.Unmarshal(w.Body.Bytes(), &device)
+	assert.Equal(t, "Phone 2", device.Name)
+	assert.Equal(t, domain.DeviceStateInUse, device.State)
+
+	// State and details change together, so a device in use can be
+	// released and renamed in one request.
+	w, _ = send("PATCH", handler.MergePatchContentType, `{"name":"Phone 3","state":"available"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &device)
+	assert.Equal(t, "Phone 3", device.Name)
+	assert.Equal(t, domain.DeviceStateAvailable, device.State)
+
+	// null removes a member in a merge patch, which clears a required field.
+	w, problem = send("PATCH", handler.MergePatchContentType, `{"brand":null}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "device_brand_required", problem.Code)
+
+	w, _ = send("PATCH", handler.JSONPatchContentType, `[{"op":"test","path":"/name","value":"Phone 3"},{"op":"replace","path":"/brand","value":"BrandC"}]`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &device)
+	assert.Equal(t, "BrandC", device.Brand)
+
+	w, problem = send("PATCH", handler.JSONPatchContentType, `[{"op":"test","path":"/name","value":"Other"},{"op":"replace","path":"/brand","value":"BrandD"}]`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "patch_test_failed", problem.Code)
+
+	w, problem = send("PATCH", handler.JSONPatchContentType, `[{"op":"add","path":"/id","value":"other"}]`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "immutable_field", problem.Code)
+
+	w, problem = send("PATCH", "text/plain", `name=x`)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Equal(t, "unsupported_media_type", problem.Code)
+
+	// None of the rejected patches were partially applied.
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/devices/"+id, nil)
+	r.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &device)
+	assert.Equal(t, "Phone 3", device.Name)
+	assert.Equal(t, "BrandC", device.Brand)
+}
+
+func TestUnitOfWorkRollsBack(t *testing.T) {
+	db := openTestDB()
+	repo := repository.NewPostgresRepository(db)
+	history := repository.NewPostgresDeviceHistoryRepository(db)
+
+	id := "uow-1"
+	device := domain.NewDevice(id, "Phone", "BrandA")
+	device.State = domain.DeviceStateInUse
+	assert.NoError(t, repo.Save(context.Background(), device))
+
+	errHistory := errors.New("history unavailable")
+	err := repository.NewUnitOfWork(db).Do(context.Background(), func(repos domain.Repositories) error {
+		device, err := repos.Devices.FindByID(context.Background(), id)
+		if err != nil {
+			return err
+		}
+		before := domain.SnapshotOf(device)
+		if err := device.Apply(domain.DeviceAttributes{Name: "Renamed", Brand: "BrandA", State: domain.DeviceStateAvailable}); err != nil {
+			return err
+		}
+		if err := repos.Devices.Update(context.Background(), device); err != nil {
+			return err
+		}
+		if err := repos.History.Append(context.Background(), domain.NewDeviceHistoryEntry(id, domain.HistoryActionUpdated, before, domain.SnapshotOf(device), "test")); err != nil {
+			return err
+		}
+		return errHistory
+	})
+	assert.ErrorIs(t, err, errHistory)
+
+	stored, err := repo.FindByID(context.Background(), id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Phone", stored.Name)
+	assert.Equal(t, domain.DeviceStateInUse, stored.State)
+	assert.Equal(t, int64(1), stored.Version)
+
+	_, total, err := history.FindByDevice(context.Background(), id, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+}
+
+// TestMemoryStorage wires the API to the memory repositories as
+// STORAGE_DRIVER=memory does.
+func TestMemoryStorage(t *testing.T) {
+	store := repository.NewMemoryStore()
+	devices := repository.NewMemoryRepository(store)
+	reservations := repository.NewMemoryReservationRepository(store)
+	uow := repository.NewMemoryUnitOfWork(store)
+	svc := service.NewDeviceService(
+		devices,
+		service.WithReservations(reservations),
+		service.WithHistory(repository.NewMemoryDeviceHistoryRepository(store)),
+		service.WithUnitOfWork(uow),
+	)
+	r := gin.Default()
+	handler.RegisterRoutes(r, handler.NewDeviceHandler(svc))
+	handler.RegisterReservationRoutes(r, handler.NewReservationHandler(service.NewReservationService(reservations, devices, service.WithReservationUnitOfWork(uow))))
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Atomic batches roll back the items that succeeded.
+	w := send("POST", "/api/v1/devices:batchCreate", `{"items":[{"id":"mem-1","name":"A","brand":"X"},{"id":"mem-2","name":"B"}]}`)
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/api/v1/devices/mem-1", "").Code)
+
+	w = send("POST", "/api/v1/devices:batchCreate", `{"items":[{"id":"mem-1","name":"A","brand":"X"},{"id":"mem-2","name":"B","brand":"X"}]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusOK, send("GET", "/api/v1/devices/mem-1", "").Code)
+
+	assert.Equal(t, http.StatusOK, send("PATCH", "/api/v1/devices/mem-1", `{"state":"in-use"}`).Code)
+	w = send("GET", "/api/v1/devices/mem-1/history", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var page handler.HistoryPage
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Equal(t, int64(2), page.Total)
+
+	start := time.Now().Add(time.Hour)
+	body, _ := json.Marshal(handler.CreateReservationRequest{ReservedBy: "alice", StartsAt: start, EndsAt: start.Add(time.Hour)})
+	assert.Equal(t, http.StatusCreated, send("POST", "/api/v1/devices/mem-2/reservations", string(body)).Code)
+	assert.Equal(t, http.StatusConflict, send("POST", "/api/v1/devices/mem-2/reservations", string(body)).Code)
+	assert.Equal(t, http.StatusConflict, send("POST", "/api/v1/devices/mem-2/checkout", `{"assignee":"bob"}`).Code)
+}
+
+func TestBatchEndpoints(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	post := func(path, body string) (*httptest.ResponseRecorder, handler.BatchResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+		r.ServeHTTP(w, req)
+		var resp handler.BatchResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+	exists := func(id string) bool {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/devices/"+id, nil)
+		r.ServeHTTP(w, req)
+		return w.Code == http.StatusOK
+	}
+
+	// The second item lacks a brand, so the atomic batch creates nothing.
+	w, resp := post("/api/v1/devices:batchCreate", `{"items":[{"id":"batch-1","name":"A","brand":"X"},{"id":"batch-2","name":"B"}]}`)
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Equal(t, "atomic", resp.Mode)
+	assert.Equal(t, 2, resp.Failed)
+	assert.Equal(t, "batch_aborted", resp.Results[0].Error.Code)
+	assert.Equal(t, http.StatusFailedDependency, resp.Results[0].Status)
+	assert.Equal(t, "device_brand_required", resp.Results[1].Error.Code)
+	assert.False(t, exists("batch-1"))
+
+	w, resp = post("/api/v1/devices:batchCreate", `{"mode":"best_effort","items":[{"id":"batch-1","name":"A","brand":"X"},{"id":"batch-2","name":"B"},{"name":"C","brand":"X"}]}`)
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Equal(t, 2, resp.Succeeded)
+	assert.Equal(t, http.StatusCreated, resp.Results[0].Status)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Results[1].Status)
+	assert.NotEmpty(t, resp.Results[2].ID)
+	assert.True(t, exists("batch-1"))
+	assert.True(t, exists(resp.Results[2].ID))
+	generated := resp.Results[2].ID
+
+	w, resp = post("/api/v1/devices:batchUpdate", `{"items":[{"id":"batch-1","state":"in-use"},{"id":"`+generated+`","name":"C2","version":1}]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, domain.DeviceStateInUse, resp.Results[0].Device.State)
+	assert.Equal(t, "C2", resp.Results[1].Device.Name)
+
+	// batch-1 is in use and cannot be deleted, which rolls back the other deletion.
+	w, resp = post("/api/v1/devices:batchDelete", `{"items":[{"id":"`+generated+`"},{"id":"batch-1"}]}`)
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Equal(t, "device_in_use", resp.Results[1].Error.Code)
+	assert.True(t, exists(generated))
+
+	w, resp = post("/api/v1/devices:batchDelete", `{"mode":"best_effort","items":[{"id":"`+generated+`"},{"id":"batch-1"}]}`)
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Equal(t, http.StatusNoContent, resp.Results[0].Status)
+	assert.False(t, exists(generated))
+
+	w, _ = post("/api/v1/devices:batchDelete", `{"mode":"sometimes","items":[]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestImportDevices(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	inventory := "Asset Tag,Name,Brand,State\n" +
+		"import-1,Pixel 8,Google,\n" +
+		"import-2,iPhone 15,Apple,in-use\n" +
+		"import-3,Galaxy,,\n" +
+		"import-4,Tab,Lenovo,banana\n"
+	send := func(query, contentType, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/devices:import?map=Asset%20Tag:id&"+query, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	exists := func(id string) bool {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/devices/"+id, nil)
+		r.ServeHTTP(w, req)
+		return w.Code == http.StatusOK
+	}
+
+	w := send("dry_run=true", "text/csv", inventory)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp handler.ImportResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.True(t, resp.DryRun)
+	assert.Equal(t, 4, resp.Total)
+	assert.Equal(t, 2, resp.Created)
+	assert.Equal(t, 2, resp.Rejected)
+	assert.Equal(t, 4, resp.Errors[0].Line)
+	assert.Equal(t, "device_brand_required", resp.Errors[0].Code)
+	assert.Equal(t, "invalid_device_state", resp.Errors[1].Code)
+	assert.False(t, exists("import-1"))
+
+	w = send("report=csv", "text/csv", inventory)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	assert.True(t, strings.HasPrefix(w.Body.String(), "line,id,name,brand,state,error\n4,import-3,Galaxy,,,brand is required\n"))
+	assert.True(t, exists("import-1"))
+	assert.True(t, exists("import-2"))
+
+	// Importing again updates the devices found by ID.
+	w = send("", "application/x-ndjson", `{"Asset Tag":"import-1","name":"Pixel 8 Pro","brand":"Google"}`+"\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+	resp = handler.ImportResponse{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, 1, resp.Updated)
+
+	w = send("", "text/csv", "id,name\nimport-9,Phone\n")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var problem handler.Problem
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	assert.Equal(t, "missing_import_column", problem.Code)
+}
+
+func TestExportDevices(t *testing.T) {
+	r, _ := setupTestRouter()
+
+	for _, device := range []handler.CreateDeviceRequest{
+		{ID: "export-1", Name: "Export Phone", Brand: "ExportBrand"},
+		{ID: "export-2", Name: "Export Tablet", Brand: "ExportBrand"},
+	} {
+		body, _ := json.Marshal(device)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/devices", bytes.NewBuffer(body))
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/devices/export?brand=ExportBrand&sort=-id", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Regexp(t, `^attachment; filename="devices-\d{8}\.csv"$`, w.Header().Get("Content-Disposition"))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[1], "export-2,Export Tablet,ExportBrand,available,"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/devices/export?format=ndjson&brand=ExportBrand&name=tablet", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var device domain.Device
+	assert.NoError(t, json.Unmarshal(bytes.TrimSpace(w.Body.Bytes()), &device))
+	assert.Equal(t, "export-2", device.ID)
+
+	// An export without matches is still a valid, empty file.
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/devices/export?format=xlsx&brand=NoSuchBrand", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("PK")))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/devices/export?format=pdf", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/devices/export?state=banana", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDeviceEventStream(t *testing.T) {
+	r, _ := setupTestRouter()
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/v1/devices/events?brand=EventBrand")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	for _, brand := range []string{"OtherBrand", "EventBrand"} {
+		body := fmt.Sprintf(`{"id":"events-%s","name":"Event Phone","brand":%q}`, brand, brand)
+		created, err := http.Post(server.URL+"/api/v1/devices", "application/json", strings.NewReader(body))
+		assert.NoError(t, err)
+		created.Body.Close()
+		assert.Equal(t, http.StatusCreated, created.StatusCode)
+	}
+	deleteReq, _ := http.NewRequest("DELETE", server.URL+"/api/v1/devices/events-EventBrand", nil)
+	deleted, err := http.DefaultClient.Do(deleteReq)
+	assert.NoError(t, err)
+	deleted.Body.Close()
+
+	// Only the events of the filtered brand arrive, the deletion included.
+	lines := bufio.NewScanner(resp.Body)
+	var received []string
+	var lastID string
+	for len(received) < 2 && lines.Scan() {
+		line := lines.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			lastID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			var event domain.DeviceEvent
+			assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+			assert.Equal(t, "events-EventBrand", event.DeviceID)
+			received = append(received, string(event.Action))
+		}
+	}
+	assert.Equal(t, []string{"created", "deleted"}, received)
+
+	// Resuming after the creation replays the deletion from the backlog.
+	req, _ := http.NewRequest("GET", server.URL+"/api/v1/devices/events?brand=EventBrand", nil)
+	id, _ := strconv.ParseUint(lastID, 10, 64)
+	req.Header.Set(handler.LastEventIDHeader, strconv.FormatUint(id-1, 10))
+	resumed, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resumed.Body.Close()
+	lines = bufio.NewScanner(resumed.Body)
+	assert.True(t, lines.Scan())
+	assert.Equal(t, "id: "+lastID, lines.Text())
+	assert.True(t, lines.Scan())
+	assert.Equal(t, "event: deleted", lines.Text())
+
+	bad, err := http.Get(server.URL + "/api/v1/devices/events?last_event_id=abc")
+	assert.NoError(t, err)
+	bad.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, bad.StatusCode)
+}
+
+func TestWebhooks(t *testing.T) {
+	r, _, dispatcher := setupTestRouterWithWebhooks()
+
+	var received []*http.Request
+	var bodies [][]byte
+	failing := true
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		received = append(received, req)
+		bodies = append(bodies, body)
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/api/v1/webhooks", `{"url":"ftp://example.com"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = do("POST", "/api/v1/webhooks", `{"url":"http://example.com","event_types":["exploded"]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	// Only loopback is allowed for the test receiver; metadata stays refused.
+	w = do("POST", "/api/v1/webhooks", `{"url":"http://169.254.169.254/latest/meta-data"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "webhook_target_not_allowed")
+
+	// Notify when a device becomes available, and of every deletion.
+	w = do("POST", "/api/v1/webhooks", fmt.Sprintf(`{"url":%q,"secret":"s3cret","event_types":["state_changed","checked_in","deleted"],"states":["available"]}`, receiver.URL))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created handler.CreateWebhookResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "s3cret", created.Secret)
+	assert.True(t, created.Active)
+	defer do("DELETE", "/api/v1/webhooks/"+created.ID, "")
+
+	w = do("GET", "/api/v1/webhooks/"+created.ID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "s3cret")
+
+	assert.Equal(t, http.StatusCreated, do("POST", "/api/v1/devices", `{"id":"webhook-1","name":"Hook Phone","brand":"Hook"}`).Code)
+	assert.Equal(t, http.StatusOK, do("PATCH", "/api/v1/devices/webhook-1", `{"state":"in-use"}`).Code)
+	assert.Equal(t, http.StatusOK, do("PATCH", "/api/v1/devices/webhook-1", `{"state":"available"}`).Code)
+
+	// Only the change back to available matches; it fails twice and is
+	// dead-lettered.
+	for range 2 {
+		sent, err := dispatcher.DeliverDue(context.Background())
+		assert.NoError(t, err)
+		assert.Zero(t, sent)
+	}
+	assert.Len(t, received, 2)
+
+	w = do("GET", "/api/v1/webhooks/dead-letters", "")
+	var dead []domain.WebhookDelivery
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &dead))
+	if assert.Len(t, dead, 1) {
+		assert.Equal(t, domain.HistoryActionStateChanged, dead[0].EventType)
+		assert.Equal(t, 2, dead[0].Attempts)
+		assert.Equal(t, http.StatusServiceUnavailable, dead[0].ResponseStatus)
+	}
+
+	failing = false
+	w = do("POST", "/api/v1/webhooks/"+created.ID+"/deliveries/"+dead[0].ID+"/redeliver", "")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/api/v1/devices/webhook-1", "").Code)
+	sent, err := dispatcher.DeliverDue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sent)
+
+	last := received[len(received)-1]
+	assert.Equal(t, "deleted", last.Header.Get(webhook.HeaderEvent))
+	timestamp, _ := strconv.ParseInt(last.Header.Get(webhook.HeaderTimestamp), 10, 64)
+	assert.Equal(t, webhook.Sign("s3cret", time.Unix(timestamp, 0), bodies[len(bodies)-1]), last.Header.Get(webhook.HeaderSignature))
+	var event domain.DeviceEvent
+	assert.NoError(t, json.Unmarshal(bodies[len(bodies)-1], &event))
+	assert.Equal(t, "webhook-1", event.DeviceID)
+
+	w = do("GET", "/api/v1/webhooks/"+created.ID+"/deliveries?status=succeeded", "")
+	var page handler.DeliveryPage
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, int64(2), page.Total)
+
+	w = do("PUT", "/api/v1/webhooks/"+created.ID, fmt.Sprintf(`{"url":%q,"active":false}`, receiver.URL))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"active":false`)
+
+	w = do("GET", "/api/v1/webhooks/unknown/deliveries", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestWebhookDispatchersSendEachDeliveryOnce(t *testing.T) {
+	db := openTestDB()
+	webhookRepo := repository.NewPostgresWebhookRepository(db)
+	deliveryRepo := repository.NewPostgresWebhookDeliveryRepository(db)
+
+	var mu sync.Mutex
+	sends := map[string]int{}
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		sends[req.Header.Get(webhook.HeaderDelivery)]++
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+
+	hook, err := domain.NewWebhook("dispatchers-hook", "secret", domain.WebhookSubscription{URL: receiver.URL, Active: true})
+	assert.NoError(t, err)
+	assert.NoError(t, webhookRepo.Save(context.Background(), hook))
+	defer webhookRepo.Delete(context.Background(), hook.ID)
+
+	// Two replicas share the delivery table.
+	targets := webhook.WithTargetPolicy(webhook.TargetPolicy{Allowed: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}})
+	dispatchers := []*webhook.Dispatcher{
+		webhook.NewDispatcher(webhookRepo, deliveryRepo, targets),
+		webhook.NewDispatcher(webhookRepo, deliveryRepo, targets),
+	}
+	const events = 20
+	for i := range events {
+		event := domain.DeviceEvent{Action: domain.HistoryActionCreated, DeviceID: fmt.Sprintf("dispatchers-%d", i), OccurredAt: time.Now()}
+		assert.NoError(t, dispatchers[0].Enqueue(context.Background(), event))
+	}
+
+	var wg sync.WaitGroup
+	for _, dispatcher := range dispatchers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := dispatcher.DeliverDue(context.Background())
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, sends, events)
+	for id, count := range sends {
+		assert.Equal(t, 1, count, id)
+	}
+}
+
+func TestOutboxRelay(t *testing.T) {
+	db := openTestDB()
+	outboxRepo := repository.NewPostgresOutboxRepository(db)
+	svc := service.NewDeviceService(
+		repository.NewPostgresRepository(db),
+		service.WithUnitOfWork(repository.NewUnitOfWork(db)),
+		service.WithOutbox(outboxRepo),
+	)
+
+	_, err := svc.CreateDevice(context.Background(), "outbox-1", "Outbox Phone", "Outbox")
+	assert.NoError(t, err)
+	_, err = svc.UpdateDeviceState(context.Background(), "outbox-1", domain.DeviceStateInUse)
+	assert.NoError(t, err)
+
+	// The failed batch is rolled back together with the events of the item
+	// that succeeded.
+	_, err = svc.CreateDevices(context.Background(), []service.DeviceCreate{
+		{ID: "outbox-2", Name: "Outbox Tablet", Brand: "Outbox"},
+		{ID: "outbox-1", Name: "Duplicate", Brand: "Outbox"},
+	}, service.BatchAtomic)
+	assert.NoError(t, err)
+
+	pending, err := outboxRepo.FindPending(context.Background(), 10, nil)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	var log bytes.Buffer
+	relayed, err := outbox.NewRelay(outboxRepo, []outbox.Sink{outbox.NewWriterSink(&log)}).Drain(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, relayed)
+
+	var actions []domain.HistoryAction
+	for _, line := range strings.Split(strings.TrimSpace(log.String()), "\n") {
+		var event domain.DeviceEvent
+		assert.NoError(t, json.Unmarshal([]byte(line), &event))
+		assert.Equal(t, "outbox-1", event.DeviceID)
+		actions = append(actions, event.Action)
+	}
+	assert.Equal(t, []domain.HistoryAction{domain.HistoryActionCreated, domain.HistoryActionStateChanged}, actions)
+
+	pending, err = outboxRepo.FindPending(context.Background(), 10, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// A message that cannot be decoded is dead-lettered and stays in the
+	// outbox, without holding back the events after it.
+	assert.NoError(t, outboxRepo.Append(context.Background(), &domain.OutboxMessage{DeviceID: "outbox-1", Payload: "not json"}))
+	_, err = svc.UpdateDeviceState(context.Background(), "outbox-1", domain.DeviceStateAvailable)
+	assert.NoError(t, err)
+	log.Reset()
+	relay := outbox.NewRelay(outboxRepo, []outbox.Sink{outbox.NewWriterSink(&log)},
+		outbox.WithMaxAttempts(1),
+		outbox.WithUnitOfWork(repository.NewUnitOfWork(db)),
+	)
+	relayed, err = relay.Drain(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 1, relayed)
+	assert.Contains(t, log.String(), `"state_changed"`)
+
+	pending, err = outboxRepo.FindPending(context.Background(), 10, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+	var deadLetters []domain.OutboxMessage
+	assert.NoError(t, db.Where("dead_lettered_at IS NOT NULL").Find(&deadLetters).Error)
+	if assert.Len(t, deadLetters, 1) {
+		assert.Equal(t, 1, deadLetters[0].Attempts)
+		assert.NotEmpty(t, deadLetters[0].LastError)
+	}
+}
+
+func TestGRPCAPI(t *testing.T) {
+	db := openTestDB()
+	broker := events.NewBroker(events.DefaultBacklog)
+	svc := service.NewDeviceService(
+		repository.NewPostgresRepository(db),
+		service.WithHistory(repository.NewPostgresDeviceHistoryRepository(db)),
+		service.WithUnitOfWork(repository.NewUnitOfWork(db)),
+		service.WithEvents(broker),
+	)
+
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	grpcapi.NewServer(svc, broker).Register(g)
+	go g.Serve(lis)
+	defer g.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer conn.Close()
+	client := devicev1.NewDeviceServiceClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), grpcapi.ActorMetadataKey, "alice")
+
+	watch, err := client.WatchDevices(ctx, &devicev1.WatchDevicesRequest{DeviceIds: []string{"grpc-1"}})
+	assert.NoError(t, err)
+	// Headers arrive once the subscription is in place.
+	_, err = watch.Header()
+	assert.NoError(t, err)
+	_, err = client.CreateDevice(ctx, &devicev1.CreateDeviceRequest{Id: "grpc-1", Name: "Rpc Phone", Brand: "Rpc"})
+	assert.NoError(t, err)
+	event, err := watch.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, devicev1.EventType_EVENT_TYPE_CREATED, event.Type)
+	assert.Equal(t, "alice", event.Actor)
+
+	_, err = client.CreateDevice(ctx, &devicev1.CreateDeviceRequest{Id: "grpc-1", Name: "Rpc Phone", Brand: "Rpc"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	inUse := devicev1.DeviceState_DEVICE_STATE_IN_USE
+	device, err := client.UpdateDevice(ctx, &devicev1.UpdateDeviceRequest{Id: "grpc-1", State: &inUse})
+	assert.NoError(t, err)
+	assert.Equal(t, inUse, device.State)
+	assert.Equal(t, "Rpc Phone", device.Name)
+	event, err = watch.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, devicev1.EventType_EVENT_TYPE_STATE_CHANGED, event.Type)
+	assert.Equal(t, devicev1.DeviceState_DEVICE_STATE_AVAILABLE, event.Previous.State)
+
+	_, err = client.DeleteDevice(ctx, &devicev1.DeleteDeviceRequest{Id: "grpc-1"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	stale := int64(1)
+	_, err = client.ReplaceDevice(ctx, &devicev1.ReplaceDeviceRequest{Id: "grpc-1", Name: "Rpc Phone", Brand: "Rpc", State: devicev1.DeviceState_DEVICE_STATE_AVAILABLE, IfMatchVersion: &stale})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	list, err := client.ListDevices(ctx, &devicev1.ListDevicesRequest{Brands: []string{"Rpc"}, IncludeTotal: true})
+	assert.NoError(t, err)
+	assert.Len(t, list.Devices, 1)
+	assert.Equal(t, int64(1), list.GetTotal())
+
+	history, err := client.GetDeviceHistory(ctx, &devicev1.GetDeviceHistoryRequest{Id: "grpc-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), history.Total)
+
+	_, err = client.GetDevice(ctx, &devicev1.GetDeviceRequest{Id: "grpc-missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGraphQLAPI(t *testing.T) {
+	r, _ := setupTestRouter()
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	graphQL := func(query string, variables map[string]interface{}) (map[string]interface{}, []map[string]interface{}) {
+		body, _ := json.Marshal(graphqlapi.Request{Query: query, Variables: variables})
+		req, _ := http.NewRequest("POST", server.URL+"/graphql", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(graphqlapi.ActorHeader, "bob")
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var result struct {
+			Data   map[string]interface{}   `json:"data"`
+			Errors []map[string]interface{} `json:"errors"`
+		}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		return result.Data, result.Errors
+	}
+	errorCode := func(errs []map[string]interface{}) interface{} {
+		if assert.Len(t, errs, 1) {
+			return errs[0]["extensions"].(map[string]interface{})["code"]
+		}
+		return nil
+	}
+
+	data, errs := graphQL(`mutation { createDevice(id: "gql-1", name: "Graph Phone", brand: "Gql") { id state version } }`, nil)
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"id": "gql-1", "state": "AVAILABLE", "version": float64(1)}, data["createDevice"])
+	_, errs = graphQL(`mutation { createDevice(id: "gql-1", name: "Graph Phone", brand: "Gql") { id } }`, nil)
+	assert.Equal(t, "DEVICE_ALREADY_EXISTS", errorCode(errs))
+
+	rename := `mutation($name: String) { updateDevice(id: "gql-1", name: $name) { name brand } }`
+	data, errs = graphQL(rename, map[string]interface{}{"name": "Graph Phone 2"})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"name": "Graph Phone 2", "brand": "Gql"}, data["updateDevice"])
+	_, errs = graphQL(`mutation { updateDeviceState(id: "gql-1", state: IN_USE) { state } }`, nil)
+	assert.Empty(t, errs)
+	_, errs = graphQL(rename, map[string]interface{}{"name": "Graph Phone 3"})
+	assert.Equal(t, "DEVICE_IN_USE", errorCode(errs))
+
+	// Resuming after the creation replays the state change but not the
+	// rename; the replayed event shows the subscription is in place.
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/graphql", nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	var msg map[string]interface{}
+	assert.NoError(t, conn.WriteJSON(map[string]interface{}{"type": "connection_init"}))
+	assert.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "connection_ack", msg["type"])
+	assert.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"id":   "1",
+		"type": "subscribe",
+		"payload": map[string]interface{}{
+			"query": `subscription { deviceStateChanged(ids: ["gql-1"], afterEventId: "1") { type actor previous { state } device { state } } }`,
+		},
+	}))
+	nextEvent := func() map[string]interface{} {
+		var msg struct {
+			Type    string `json:"type"`
+			Payload struct {
+				Data map[string]interface{} `json:"data"`
+			} `json:"payload"`
+		}
+		assert.NoError(t, conn.ReadJSON(&msg))
+		assert.Equal(t, "next", msg.Type)
+		event, _ := msg.Payload.Data["deviceStateChanged"].(map[string]interface{})
+		return event
+	}
+	assert.Equal(t, map[string]interface{}{
+		"type":     "STATE_CHANGED",
+		"actor":    "bob",
+		"previous": map[string]interface{}{"state": "AVAILABLE"},
+		"device":   map[string]interface{}{"state": "IN_USE"},
+	}, nextEvent())
+
+	_, errs = graphQL(`mutation { updateDeviceState(id: "gql-1", state: INACTIVE) { state } }`, nil)
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"state": "INACTIVE"}, nextEvent()["device"])
+
+	_, errs = graphQL(`mutation { updateDeviceState(id: "gql-1", state: IN_USE) { state } }`, nil)
+	if assert.Equal(t, "INVALID_DEVICE_STATE", errorCode(errs)) {
+		assert.Equal(t, []interface{}{"available"}, errs[0]["extensions"].(map[string]interface{})["allowedStates"])
+	}
+	assert.NoError(t, conn.WriteJSON(map[string]interface{}{"id": "1", "type": "complete"}))
+
+	data, errs = graphQL(`{ device(id: "gql-1") { name state history(limit: 2) { action actor } reservations { id } } }`, nil)
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{
+		"name":  "Graph Phone 2",
+		"state": "INACTIVE",
+		"history": []interface{}{
+			map[string]interface{}{"action": "STATE_CHANGED", "actor": "bob"},
+			map[string]interface{}{"action": "STATE_CHANGED", "actor": "bob"},
+		},
+		"reservations": []interface{}{},
+	}, data["device"])
+
+	data, errs = graphQL(`query($states: [DeviceState!]) { devices(brands: ["Gql"], states: $states, includeTotal: true) { items { id } nextCursor total } }`,
+		map[string]interface{}{"states": []string{"INACTIVE"}})
+	assert.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{"items": []interface{}{map[string]interface{}{"id": "gql-1"}}, "nextCursor": nil, "total": float64(1)}, data["devices"])
+	_, errs = graphQL(`{ devices(sort: "color") { total } }`, nil)
+	assert.Equal(t, "INVALID_SORT", errorCode(errs))
+
+	data, errs = graphQL(`mutation { deleteDevice(id: "gql-1") }`, nil)
+	assert.Empty(t, errs)
+	assert.Equal(t, "gql-1", data["deleteDevice"])
+	data, errs = graphQL(`{ device(id: "gql-1") { id } }`, nil)
+	assert.Empty(t, errs)
+	assert.Nil(t, data["device"])
+	data, _ = graphQL(`{ device(id: "gql-1", includeDeleted: true) { deletedAt } }`, nil)
+	assert.NotNil(t, data["device"].(map[string]interface{})["deletedAt"])
+
+	resp, err := http.Get(server.URL + "/graphql?query=" + url.QueryEscape(`mutation { deleteDevice(id: "gql-1") }`))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	resp, err = http.Post(server.URL+"/graphql", "application/json", strings.NewReader(`{"query": "subscription { deviceStateChanged { id } }"}`))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}